@@ -0,0 +1,138 @@
+package analyzer
+
+import "strings"
+
+// checkAlterTable verifica las acciones de un ALTER TABLE contra las
+// columnas actuales de la tabla. Las acciones se aplican en orden sobre una
+// copia de las columnas, de modo que "ADD a INT, DROP a" es válido.
+func (info *SemanticInfo) checkAlterTable(stmt *AlterTableStmt) {
+	table := stmt.Table.Name
+	current, ok := info.columnsOf(table)
+	if !ok {
+		// La tabla no existe: ya se informó al verificar las tablas o se
+		// indicó IF EXISTS
+		return
+	}
+	columns := make(map[string]ColumnSchema, len(current))
+	for name, column := range current {
+		columns[name] = column
+	}
+
+	for _, action := range stmt.Actions {
+		name := action.Column.Name
+		column, exists := columns[name]
+		missing := func() {
+			info.addError(action.Column.Span, "la columna '%s' no existe en la tabla '%s'", action.Column.String(), table.String())
+		}
+
+		switch action.Kind {
+		case "ADD COLUMN":
+			if exists {
+				if !action.IfExists {
+					info.addError(action.Column.Span, "la columna '%s' ya existe en la tabla '%s'", action.Column.String(), table.String())
+				}
+				continue
+			}
+			def := action.Definition
+			columns[name] = ColumnSchema{Name: name, Type: catalogTypeName(def.Type), Nullable: !columnNotNull(def), Length: typeLength(def.Type)}
+			if requiresValue(def) {
+				info.addWarning(action.Span, "la columna '%s' es NOT NULL y no tiene DEFAULT: el ALTER TABLE fallará si '%s' ya tiene filas",
+					action.Column.String(), table.String())
+			}
+
+		case "DROP COLUMN":
+			if !exists {
+				if !action.IfExists {
+					missing()
+				}
+				continue
+			}
+			delete(columns, name)
+
+		case "RENAME COLUMN":
+			if !exists {
+				missing()
+				continue
+			}
+			if _, taken := columns[action.NewName.Name]; taken {
+				info.addError(action.NewName.Span, "la columna '%s' ya existe en la tabla '%s'", action.NewName.String(), table.String())
+				continue
+			}
+			delete(columns, name)
+			column.Name = action.NewName.Name
+			columns[column.Name] = column
+
+		case "ALTER COLUMN TYPE":
+			if !exists {
+				missing()
+				continue
+			}
+			info.checkTypeChange(action, column.Type)
+			column.Type = catalogTypeName(action.Type)
+			column.Length = typeLength(action.Type)
+			columns[name] = column
+
+		case "SET DEFAULT", "DROP DEFAULT", "SET NOT NULL", "DROP NOT NULL":
+			if !exists {
+				missing()
+			}
+
+		case "ADD CONSTRAINT":
+			for _, column := range action.Constraint.Columns {
+				if _, ok := columns[column.Name]; !ok {
+					info.addError(column.Span, "la columna '%s' del constraint no existe en la tabla '%s'", column.String(), table.String())
+				}
+			}
+
+		case "RENAME TO":
+			renamed := QualifiedName{Parts: append(table.Parts[:len(table.Parts)-1:len(table.Parts)-1], action.NewName), Span: action.NewName.Span}
+			if info.tableExists(renamed) {
+				info.addError(action.NewName.Span, "ya existe una tabla llamada '%s'", renamed.String())
+			}
+		}
+	}
+}
+
+// requiresValue indica si una columna nueva necesita un valor en las filas
+// existentes: es NOT NULL (o PRIMARY KEY) y no tiene DEFAULT ni es SERIAL.
+func requiresValue(column *ColumnDef) bool {
+	if column.Type.Name == "SERIAL" || column.Type.Name == "BIGSERIAL" {
+		return false
+	}
+	for _, constraint := range column.Constraints {
+		if constraint.Kind == "DEFAULT" {
+			return false
+		}
+	}
+	return columnNotNull(column)
+}
+
+// columnNotNull indica si la definición de la columna impide NULL.
+func columnNotNull(column *ColumnDef) bool {
+	for _, constraint := range column.Constraints {
+		if constraint.Kind == "NOT NULL" || constraint.Kind == "PRIMARY KEY" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTypeChange verifica que PostgreSQL pueda convertir los valores de la
+// columna al nuevo tipo sin USING: dentro de la misma categoría o hacia
+// texto. Si la conversión depende del tipo exacto no se informa nada.
+func (info *SemanticInfo) checkTypeChange(action *AlterAction, current string) {
+	if action.Using != nil {
+		return
+	}
+	from := info.catalog.TypeCategory(current)
+	to := typeCategory(action.Type.Name)
+	if action.Type.ArrayDims > 0 {
+		to = typeArray
+	}
+	if from == typeUnknown || to == typeUnknown || from == to || to == typeText {
+		return
+	}
+	column, target := action.Column.String(), action.Type.String()
+	info.addError(action.Span, "no se puede convertir la columna '%s' de %s a %s sin USING, por ejemplo: USING %s::%s",
+		column, strings.ToLower(current), target, column, strings.ToLower(target))
+}
@@ -0,0 +1,1513 @@
+package analyzer
+
+import "strings"
+
+// Node es cualquier nodo del árbol de sintaxis abstracta que produce
+// SyntacticAnalysis.
+type Node interface {
+	// Location devuelve el rango de la query que ocupa el nodo.
+	Location() Span
+	// Tree convierte el nodo al árbol genérico que consume el frontend.
+	Tree() SyntaxNode
+	children() []Node
+}
+
+// Statement es una sentencia SQL completa.
+type Statement interface {
+	Node
+	statementNode()
+}
+
+// Expr es una expresión: columnas, literales, operaciones, funciones...
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Query es una sentencia que devuelve filas: un SELECT o una operación de
+// conjuntos entre consultas. Es lo que puede aparecer como subconsulta.
+type Query interface {
+	Statement
+	queryNode()
+}
+
+// TableExpr es un elemento de FROM: una tabla o un JOIN entre dos elementos.
+type TableExpr interface {
+	Node
+	tableExprNode()
+}
+
+// Walk recorre el árbol en profundidad llamando a fn con cada nodo. Si fn
+// devuelve false no se visitan los hijos de ese nodo.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	for _, child := range node.children() {
+		Walk(child, fn)
+	}
+}
+
+// SyntaxTree devuelve el árbol genérico de un nodo con los rangos de cada
+// nivel ya calculados.
+func SyntaxTree(node Node) *SyntaxNode {
+	tree := node.Tree()
+	fillSpans(&tree)
+	return &tree
+}
+
+// exprNodes convierte una lista de expresiones en nodos para children().
+func exprNodes(exprs []Expr) []Node {
+	nodes := make([]Node, 0, len(exprs))
+	for _, expr := range exprs {
+		nodes = append(nodes, expr)
+	}
+	return nodes
+}
+
+// treeList agrupa bajo un nodo del tipo indicado el árbol de cada nodo.
+func treeList[T Node](nodeType string, nodes []T) SyntaxNode {
+	list := SyntaxNode{Type: nodeType}
+	for _, node := range nodes {
+		list.Children = append(list.Children, node.Tree())
+	}
+	return list
+}
+
+// Sentencias
+
+// SelectStmt es una consulta SELECT, con su cláusula WITH opcional.
+type SelectStmt struct {
+	With     *WithClause
+	Distinct bool
+	Columns  []*SelectItem
+	From     []TableExpr
+	Where    Expr
+	GroupBy  []Expr
+	Having   Expr
+	Windows  []*WindowSpec
+	ResultClauses
+	Span
+}
+
+// ResultClauses son las cláusulas que ordenan y limitan el resultado de una
+// consulta. LIMIT ALL, que no limita, es un Literal NULL con valor "ALL".
+type ResultClauses struct {
+	OrderBy []*OrderItem
+	Limit   Expr
+	Offset  Expr
+}
+
+// SetOperation combina dos consultas con UNION, INTERSECT o EXCEPT. El
+// ORDER BY, LIMIT y OFFSET finales se aplican al resultado combinado.
+type SetOperation struct {
+	With  *WithClause
+	Op    string
+	All   bool
+	Left  Query
+	Right Query
+	ResultClauses
+	Span
+}
+
+// SelectItem es una expresión de la lista de columnas de un SELECT con su
+// alias opcional (Alias.Name vacío si no tiene).
+type SelectItem struct {
+	Expr  Expr
+	Alias Identifier
+	Span
+}
+
+// TableRef es una referencia a una tabla existente, con alias opcional en
+// FROM, UPDATE y DELETE. Si el nombre corresponde a una CTE visible, CTE
+// apunta a su definición y la tabla no tiene que existir en la base.
+type TableRef struct {
+	Name  QualifiedName
+	Alias Identifier
+	CTE   *CommonTableExpr
+	Span
+}
+
+// VisibleName devuelve el nombre con el que la tabla se referencia en el
+// resto de la sentencia: su alias o, si no tiene, su nombre.
+func (t *TableRef) VisibleName() string {
+	if t.Alias.Name != "" {
+		return t.Alias.Name
+	}
+	return t.Name.Name()
+}
+
+// JoinExpr es un JOIN entre dos elementos de FROM. Kind es "INNER", "LEFT",
+// "RIGHT", "FULL" o "CROSS"; la condición es On, Using o ninguna (CROSS y
+// NATURAL).
+type JoinExpr struct {
+	Kind    string
+	Natural bool
+	Left    TableExpr
+	Right   TableExpr
+	On      Expr
+	Using   []Identifier
+	Span
+}
+
+// WithClause es la cláusula WITH [RECURSIVE] de una consulta.
+type WithClause struct {
+	Recursive bool
+	CTEs      []*CommonTableExpr
+	Span
+}
+
+// CommonTableExpr es una consulta con nombre de WITH:
+// nombre [(columnas)] AS [[NOT] MATERIALIZED] (SELECT ...).
+type CommonTableExpr struct {
+	Name         Identifier
+	Columns      []Identifier
+	Materialized string
+	Query        Query
+	Span
+}
+
+// DerivedTable es una subconsulta en FROM. Siempre tiene alias y puede
+// renombrar sus columnas: (SELECT ...) AS d (a, b).
+type DerivedTable struct {
+	Lateral       bool
+	Query         Query
+	Alias         Identifier
+	ColumnAliases []Identifier
+	Span
+}
+
+// WindowSpec es la definición de una ventana: la de OVER (...) o la de una
+// ventana con nombre de la cláusula WINDOW (Name). Base es la ventana con
+// nombre en la que se apoya, como en "OVER w" u "OVER (w ORDER BY x)".
+type WindowSpec struct {
+	Name        Identifier
+	Base        Identifier
+	PartitionBy []Expr
+	OrderBy     []*OrderItem
+	Frame       *WindowFrame
+	Span
+}
+
+// WindowFrame es el marco de una ventana: ROWS, RANGE o GROUPS seguido de
+// un límite o de BETWEEN inicio AND fin (End es nil si no hay BETWEEN), con
+// un EXCLUDE opcional.
+type WindowFrame struct {
+	Units   string
+	Start   *FrameBound
+	End     *FrameBound
+	Exclude string
+	Span
+}
+
+// FrameBound es un límite del marco de una ventana. Kind es "UNBOUNDED
+// PRECEDING", "PRECEDING", "CURRENT ROW", "FOLLOWING" o "UNBOUNDED
+// FOLLOWING"; Offset es el desplazamiento de "n PRECEDING" y "n FOLLOWING".
+type FrameBound struct {
+	Kind   string
+	Offset Expr
+	Span
+}
+
+// OrderItem es un criterio de ORDER BY.
+type OrderItem struct {
+	Expr      Expr
+	Direction string
+	Span
+}
+
+// InsertStmt es un INSERT INTO. Las filas vienen de VALUES (Values), de
+// una consulta (Query) o de DEFAULT VALUES; un valor DEFAULT dentro de
+// VALUES es un Literal de tipo "DEFAULT".
+type InsertStmt struct {
+	Table         *TableRef
+	Columns       []Identifier
+	Values        [][]Expr
+	Query         Query
+	DefaultValues bool
+	OnConflict    *OnConflict
+	Returning     []*SelectItem
+	Span
+}
+
+// OnConflict es la cláusula ON CONFLICT de un INSERT. El conflicto se
+// identifica por columnas (Target, con un WHERE opcional para índices
+// parciales) o por el nombre de una restricción. Action es "NOTHING" o
+// "UPDATE"; en este caso Set y Where describen la actualización.
+type OnConflict struct {
+	Target      []Identifier
+	TargetWhere Expr
+	Constraint  Identifier
+	Action      string
+	Set         []*Assignment
+	Where       Expr
+	Span
+}
+
+// UpdateStmt es un UPDATE ... SET ... [FROM] [WHERE] [RETURNING].
+type UpdateStmt struct {
+	Table     *TableRef
+	Set       []*Assignment
+	From      []TableExpr
+	Where     Expr
+	Returning []*SelectItem
+	Span
+}
+
+// Assignment es una asignación de un SET. La forma simple "columna = valor"
+// usa Column y Value; la de tupla "(a, b) = (1, 2)" usa Columns y Values, o
+// Value si los valores vienen de una subconsulta. DEFAULT es un Literal de
+// tipo "DEFAULT".
+type Assignment struct {
+	Column  Identifier
+	Columns []Identifier
+	Value   Expr
+	Values  []Expr
+	Span
+}
+
+// Targets devuelve las columnas que asigna.
+func (a *Assignment) Targets() []Identifier {
+	if len(a.Columns) > 0 {
+		return a.Columns
+	}
+	return []Identifier{a.Column}
+}
+
+// DeleteStmt es un DELETE FROM ... [USING] [WHERE] [RETURNING].
+type DeleteStmt struct {
+	Table     *TableRef
+	Using     []TableExpr
+	Where     Expr
+	Returning []*SelectItem
+	Span
+}
+
+// CreateTableStmt es un CREATE TABLE.
+type CreateTableStmt struct {
+	IfNotExists bool
+	Name        QualifiedName
+	Columns     []*ColumnDef
+	Constraints []*TableConstraint
+	Span
+}
+
+// ColumnDef es la definición de una columna dentro de CREATE TABLE.
+type ColumnDef struct {
+	Name        Identifier
+	Type        *DataType
+	Constraints []*ColumnConstraint
+	Span
+}
+
+// DataType es un tipo de dato con sus parámetros, como VARCHAR(50) o
+// DECIMAL(10,2). Name está en mayúsculas; ArrayDims es la cantidad de
+// dimensiones de un arreglo como TEXT[] (0 si no es arreglo).
+type DataType struct {
+	Name      string
+	Params    []string
+	ArrayDims int
+	Span
+}
+
+// ColumnConstraint es una restricción escrita junto a una columna. Kind es
+// "NOT NULL", "NULL", "PRIMARY KEY", "UNIQUE", "DEFAULT", "REFERENCES" o
+// "CHECK"; según el caso se usa Default, References o Check.
+type ColumnConstraint struct {
+	Kind       string
+	Default    Expr
+	References *Reference
+	Check      Expr
+	Span
+}
+
+// TableConstraint es una restricción de tabla. Kind es "PRIMARY KEY",
+// "FOREIGN KEY", "UNIQUE" o "CHECK".
+type TableConstraint struct {
+	Name       string
+	Kind       string
+	Columns    []Identifier
+	References *Reference
+	Check      Expr
+	Span
+}
+
+// Reference es el destino de una clave foránea.
+type Reference struct {
+	Table   *TableRef
+	Columns []Identifier
+	// OnDelete y OnUpdate son las acciones de ON DELETE y ON UPDATE
+	// (CASCADE, SET NULL...), vacías si no se indican.
+	OnDelete string
+	OnUpdate string
+	Span
+}
+
+// CreateDatabaseStmt es un CREATE DATABASE.
+type CreateDatabaseStmt struct {
+	Name Identifier
+	Span
+}
+
+// CreateIndexStmt es un CREATE [UNIQUE] INDEX ... ON tabla (columnas).
+// Method es el de USING (vacío si no se indica) y Expressions son los
+// elementos del índice que no son una columna, como lower(nombre).
+type CreateIndexStmt struct {
+	Unique      bool
+	IfNotExists bool
+	Name        Identifier
+	Table       *TableRef
+	Method      string
+	Columns     []Identifier
+	Expressions []Expr
+	Span
+}
+
+// AlterTableStmt es un ALTER TABLE con una o más acciones.
+type AlterTableStmt struct {
+	IfExists bool
+	Table    *TableRef
+	Actions  []*AlterAction
+	Span
+}
+
+// AlterAction es una acción de ALTER TABLE. Kind es "ADD COLUMN", "DROP
+// COLUMN", "ALTER COLUMN TYPE", "SET DEFAULT", "DROP DEFAULT", "SET NOT
+// NULL", "DROP NOT NULL", "RENAME COLUMN", "ADD CONSTRAINT", "DROP
+// CONSTRAINT", "RENAME CONSTRAINT" o "RENAME TO". Column es la columna (o
+// el constraint) afectada; el resto de los campos se usa según el caso.
+// IfExists corresponde a DROP ... IF EXISTS y ADD COLUMN IF NOT EXISTS.
+type AlterAction struct {
+	Kind       string
+	Column     Identifier
+	IfExists   bool
+	Definition *ColumnDef
+	Type       *DataType
+	Using      Expr
+	Default    Expr
+	Constraint *TableConstraint
+	NewName    Identifier
+	Behavior   string
+	Span
+}
+
+// DropStmt es un DROP de uno o más objetos del mismo tipo. ObjectType es
+// "TABLE", "VIEW", "MATERIALIZED VIEW", "INDEX", "SEQUENCE", "SCHEMA" o
+// "DATABASE"; Behavior es "CASCADE", "RESTRICT" o vacío (RESTRICT).
+type DropStmt struct {
+	ObjectType   string
+	IfExists     bool
+	Concurrently bool
+	Names        []QualifiedName
+	Behavior     string
+	Span
+}
+
+// TransactionStmt es una sentencia de control de transacciones. Kind es
+// "BEGIN", "COMMIT", "ROLLBACK", "SAVEPOINT", "RELEASE" o "ROLLBACK TO";
+// los tres últimos indican el savepoint en Savepoint. Modes son los modos
+// de un BEGIN: "ISOLATION LEVEL ...", "READ ONLY", "READ WRITE",
+// "DEFERRABLE" o "NOT DEFERRABLE".
+type TransactionStmt struct {
+	Kind      string
+	Savepoint Identifier
+	Modes     []string
+	Span
+}
+
+func (*TableRef) tableExprNode()     {}
+func (*JoinExpr) tableExprNode()     {}
+func (*DerivedTable) tableExprNode() {}
+
+func (*SelectStmt) queryNode()   {}
+func (*SetOperation) queryNode() {}
+
+func (*SelectStmt) statementNode()         {}
+func (*SetOperation) statementNode()       {}
+func (*InsertStmt) statementNode()         {}
+func (*UpdateStmt) statementNode()         {}
+func (*DeleteStmt) statementNode()         {}
+func (*CreateTableStmt) statementNode()    {}
+func (*CreateDatabaseStmt) statementNode() {}
+func (*CreateIndexStmt) statementNode()    {}
+func (*DropStmt) statementNode()           {}
+func (*TransactionStmt) statementNode()    {}
+func (*AlterTableStmt) statementNode()     {}
+
+// Expresiones
+
+// Literal es un valor constante. Kind es "NUMERO", "CADENA", "BOOLEANO" o
+// "NULL"; Value conserva el texto original y Text el valor decodificado de
+// las cadenas.
+type Literal struct {
+	Kind  string
+	Value string
+	Text  string
+	Span
+}
+
+// ColumnRef es una referencia a una columna (o a todas con '*' / 'tabla.*').
+type ColumnRef struct {
+	Name QualifiedName
+	Span
+}
+
+// FuncCall es una llamada a función. Star indica un argumento '*' como en
+// COUNT(*) y NoParens una función especial sin paréntesis como
+// CURRENT_TIMESTAMP. Distinct, OrderBy y Filter solo tienen sentido en
+// funciones de agregado: COUNT(DISTINCT x), STRING_AGG(x, ',' ORDER BY y),
+// SUM(x) FILTER (WHERE ...).
+type FuncCall struct {
+	Name     QualifiedName
+	Args     []Expr
+	Star     bool
+	NoParens bool
+	Distinct bool
+	OrderBy  []*OrderItem
+	Filter   Expr
+	Over     *WindowSpec
+	Span
+}
+
+// BinaryExpr es una operación con dos operandos (aritmética, comparación,
+// AND, OR).
+type BinaryExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+	Span
+}
+
+// UnaryExpr es una operación con un operando (NOT, signo).
+type UnaryExpr struct {
+	Op      string
+	Operand Expr
+	Span
+}
+
+// InExpr es "expr [NOT] IN (a, b, ...)" o "expr [NOT] IN (SELECT ...)".
+type InExpr struct {
+	Expr     Expr
+	Not      bool
+	List     []Expr
+	Subquery Query
+	Span
+}
+
+// SubqueryExpr es una subconsulta escalar: (SELECT ...).
+type SubqueryExpr struct {
+	Query Query
+	Span
+}
+
+// ExistsExpr es "EXISTS (SELECT ...)".
+type ExistsExpr struct {
+	Query Query
+	Span
+}
+
+// QuantifiedExpr es una comparación con ANY, SOME o ALL contra una
+// subconsulta (Query) o un arreglo (Array).
+type QuantifiedExpr struct {
+	Op         string
+	Quantifier string
+	Left       Expr
+	Query      Query
+	Array      Expr
+	Span
+}
+
+// BetweenExpr es "expr [NOT] BETWEEN low AND high".
+type BetweenExpr struct {
+	Expr Expr
+	Not  bool
+	Low  Expr
+	High Expr
+	Span
+}
+
+// LikeExpr es "expr [NOT] LIKE|ILIKE patrón [ESCAPE carácter]".
+type LikeExpr struct {
+	Expr    Expr
+	Not     bool
+	Op      string
+	Pattern Expr
+	Escape  Expr
+	Span
+}
+
+// IsExpr es "expr IS [NOT] NULL|TRUE|FALSE|UNKNOWN".
+type IsExpr struct {
+	Expr  Expr
+	Not   bool
+	Value string
+	Span
+}
+
+// CastExpr es una conversión de tipo: "expr::tipo" o "CAST(expr AS tipo)".
+type CastExpr struct {
+	Expr Expr
+	Type *DataType
+	Span
+}
+
+// CaseExpr es una expresión CASE. En la forma simple Operand es el valor que
+// se compara con cada WHEN; en la forma con búsqueda es nil y cada WHEN es
+// una condición. Else es nil si no hay ELSE.
+type CaseExpr struct {
+	Operand Expr
+	Whens   []*WhenClause
+	Else    Expr
+	Span
+}
+
+// WhenClause es una rama "WHEN condición THEN resultado" de un CASE.
+type WhenClause struct {
+	Cond   Expr
+	Result Expr
+	Span
+}
+
+func (*Literal) exprNode()        {}
+func (*ColumnRef) exprNode()      {}
+func (*FuncCall) exprNode()       {}
+func (*BinaryExpr) exprNode()     {}
+func (*UnaryExpr) exprNode()      {}
+func (*InExpr) exprNode()         {}
+func (*BetweenExpr) exprNode()    {}
+func (*LikeExpr) exprNode()       {}
+func (*IsExpr) exprNode()         {}
+func (*CastExpr) exprNode()       {}
+func (*CaseExpr) exprNode()       {}
+func (*SubqueryExpr) exprNode()   {}
+func (*ExistsExpr) exprNode()     {}
+func (*QuantifiedExpr) exprNode() {}
+
+// IsStar indica si la referencia es '*' o 'tabla.*'.
+func (c *ColumnRef) IsStar() bool {
+	return c.Name.Name() == "*"
+}
+
+// children
+
+func (s *SelectStmt) children() []Node {
+	var nodes []Node
+	if s.With != nil {
+		nodes = append(nodes, s.With)
+	}
+	for _, item := range s.Columns {
+		nodes = append(nodes, item)
+	}
+	for _, from := range s.From {
+		nodes = append(nodes, from)
+	}
+	if s.Where != nil {
+		nodes = append(nodes, s.Where)
+	}
+	nodes = append(nodes, exprNodes(s.GroupBy)...)
+	if s.Having != nil {
+		nodes = append(nodes, s.Having)
+	}
+	for _, window := range s.Windows {
+		nodes = append(nodes, window)
+	}
+	return append(nodes, s.ResultClauses.children()...)
+}
+
+func (s *SetOperation) children() []Node {
+	var nodes []Node
+	if s.With != nil {
+		nodes = append(nodes, s.With)
+	}
+	nodes = append(nodes, s.Left, s.Right)
+	return append(nodes, s.ResultClauses.children()...)
+}
+
+func (r *ResultClauses) children() []Node {
+	var nodes []Node
+	for _, item := range r.OrderBy {
+		nodes = append(nodes, item)
+	}
+	if r.Limit != nil {
+		nodes = append(nodes, r.Limit)
+	}
+	if r.Offset != nil {
+		nodes = append(nodes, r.Offset)
+	}
+	return nodes
+}
+
+func (s *SelectItem) children() []Node { return []Node{s.Expr} }
+func (t *TableRef) children() []Node   { return nil }
+func (o *OrderItem) children() []Node  { return []Node{o.Expr} }
+
+func (w *WindowSpec) children() []Node {
+	nodes := exprNodes(w.PartitionBy)
+	for _, item := range w.OrderBy {
+		nodes = append(nodes, item)
+	}
+	if w.Frame != nil {
+		nodes = append(nodes, w.Frame)
+	}
+	return nodes
+}
+
+func (f *WindowFrame) children() []Node {
+	nodes := []Node{f.Start}
+	if f.End != nil {
+		nodes = append(nodes, f.End)
+	}
+	return nodes
+}
+
+func (b *FrameBound) children() []Node {
+	if b.Offset != nil {
+		return []Node{b.Offset}
+	}
+	return nil
+}
+
+func (d *DerivedTable) children() []Node { return []Node{d.Query} }
+
+func (w *WithClause) children() []Node {
+	nodes := make([]Node, 0, len(w.CTEs))
+	for _, cte := range w.CTEs {
+		nodes = append(nodes, cte)
+	}
+	return nodes
+}
+
+func (c *CommonTableExpr) children() []Node { return []Node{c.Query} }
+
+func (j *JoinExpr) children() []Node {
+	nodes := []Node{j.Left, j.Right}
+	if j.On != nil {
+		nodes = append(nodes, j.On)
+	}
+	return nodes
+}
+
+func (s *InsertStmt) children() []Node {
+	nodes := []Node{s.Table}
+	for _, row := range s.Values {
+		nodes = append(nodes, exprNodes(row)...)
+	}
+	if s.Query != nil {
+		nodes = append(nodes, s.Query)
+	}
+	if s.OnConflict != nil {
+		nodes = append(nodes, s.OnConflict)
+	}
+	for _, item := range s.Returning {
+		nodes = append(nodes, item)
+	}
+	return nodes
+}
+
+func (c *OnConflict) children() []Node {
+	var nodes []Node
+	if c.TargetWhere != nil {
+		nodes = append(nodes, c.TargetWhere)
+	}
+	for _, assignment := range c.Set {
+		nodes = append(nodes, assignment)
+	}
+	if c.Where != nil {
+		nodes = append(nodes, c.Where)
+	}
+	return nodes
+}
+
+func (s *UpdateStmt) children() []Node {
+	nodes := []Node{s.Table}
+	for _, assignment := range s.Set {
+		nodes = append(nodes, assignment)
+	}
+	for _, from := range s.From {
+		nodes = append(nodes, from)
+	}
+	if s.Where != nil {
+		nodes = append(nodes, s.Where)
+	}
+	for _, item := range s.Returning {
+		nodes = append(nodes, item)
+	}
+	return nodes
+}
+
+func (a *Assignment) children() []Node {
+	nodes := exprNodes(a.Values)
+	if a.Value != nil {
+		nodes = append(nodes, a.Value)
+	}
+	return nodes
+}
+
+func (s *DeleteStmt) children() []Node {
+	nodes := []Node{s.Table}
+	for _, using := range s.Using {
+		nodes = append(nodes, using)
+	}
+	if s.Where != nil {
+		nodes = append(nodes, s.Where)
+	}
+	for _, item := range s.Returning {
+		nodes = append(nodes, item)
+	}
+	return nodes
+}
+
+func (s *CreateTableStmt) children() []Node {
+	var nodes []Node
+	for _, column := range s.Columns {
+		nodes = append(nodes, column)
+	}
+	for _, constraint := range s.Constraints {
+		nodes = append(nodes, constraint)
+	}
+	return nodes
+}
+
+func (c *ColumnDef) children() []Node {
+	nodes := []Node{c.Type}
+	for _, constraint := range c.Constraints {
+		nodes = append(nodes, constraint)
+	}
+	return nodes
+}
+
+func (d *DataType) children() []Node { return nil }
+
+func (c *ColumnConstraint) children() []Node {
+	var nodes []Node
+	if c.Default != nil {
+		nodes = append(nodes, c.Default)
+	}
+	if c.References != nil {
+		nodes = append(nodes, c.References)
+	}
+	if c.Check != nil {
+		nodes = append(nodes, c.Check)
+	}
+	return nodes
+}
+
+func (c *TableConstraint) children() []Node {
+	var nodes []Node
+	if c.References != nil {
+		nodes = append(nodes, c.References)
+	}
+	if c.Check != nil {
+		nodes = append(nodes, c.Check)
+	}
+	return nodes
+}
+
+func (r *Reference) children() []Node          { return []Node{r.Table} }
+func (s *CreateDatabaseStmt) children() []Node { return nil }
+func (s *CreateIndexStmt) children() []Node {
+	return append([]Node{s.Table}, exprNodes(s.Expressions)...)
+}
+func (s *DropStmt) children() []Node        { return nil }
+func (s *TransactionStmt) children() []Node { return nil }
+
+func (s *AlterTableStmt) children() []Node {
+	nodes := []Node{s.Table}
+	for _, action := range s.Actions {
+		nodes = append(nodes, action)
+	}
+	return nodes
+}
+
+func (a *AlterAction) children() []Node {
+	var nodes []Node
+	if a.Definition != nil {
+		nodes = append(nodes, a.Definition)
+	}
+	if a.Type != nil {
+		nodes = append(nodes, a.Type)
+	}
+	if a.Constraint != nil {
+		nodes = append(nodes, a.Constraint)
+	}
+	if a.Using != nil {
+		nodes = append(nodes, a.Using)
+	}
+	if a.Default != nil {
+		nodes = append(nodes, a.Default)
+	}
+	return nodes
+}
+
+func (l *Literal) children() []Node   { return nil }
+func (c *ColumnRef) children() []Node { return nil }
+func (f *FuncCall) children() []Node {
+	nodes := exprNodes(f.Args)
+	for _, item := range f.OrderBy {
+		nodes = append(nodes, item)
+	}
+	if f.Filter != nil {
+		nodes = append(nodes, f.Filter)
+	}
+	if f.Over != nil {
+		nodes = append(nodes, f.Over)
+	}
+	return nodes
+}
+
+func (b *BinaryExpr) children() []Node { return []Node{b.Left, b.Right} }
+func (u *UnaryExpr) children() []Node  { return []Node{u.Operand} }
+
+func (e *InExpr) children() []Node {
+	if e.Subquery != nil {
+		return []Node{e.Expr, e.Subquery}
+	}
+	return append([]Node{e.Expr}, exprNodes(e.List)...)
+}
+
+func (e *SubqueryExpr) children() []Node { return []Node{e.Query} }
+func (e *ExistsExpr) children() []Node   { return []Node{e.Query} }
+
+func (e *QuantifiedExpr) children() []Node {
+	if e.Query != nil {
+		return []Node{e.Left, e.Query}
+	}
+	return []Node{e.Left, e.Array}
+}
+
+func (e *BetweenExpr) children() []Node { return []Node{e.Expr, e.Low, e.High} }
+
+func (e *LikeExpr) children() []Node {
+	nodes := []Node{e.Expr, e.Pattern}
+	if e.Escape != nil {
+		nodes = append(nodes, e.Escape)
+	}
+	return nodes
+}
+
+func (e *IsExpr) children() []Node   { return []Node{e.Expr} }
+func (e *CastExpr) children() []Node { return []Node{e.Expr, e.Type} }
+
+func (e *CaseExpr) children() []Node {
+	var nodes []Node
+	if e.Operand != nil {
+		nodes = append(nodes, e.Operand)
+	}
+	for _, when := range e.Whens {
+		nodes = append(nodes, when)
+	}
+	if e.Else != nil {
+		nodes = append(nodes, e.Else)
+	}
+	return nodes
+}
+
+func (w *WhenClause) children() []Node { return []Node{w.Cond, w.Result} }
+
+// Tree: conversión al árbol genérico. Se conservan los tipos de nodo que ya
+// usaba el frontend (SELECT_STATEMENT, COLUMNS, TABLE, WHERE_CLAUSE...).
+
+func (s *SelectStmt) Tree() SyntaxNode {
+	root := SyntaxNode{Type: "SELECT_STATEMENT", Span: s.Span}
+	if s.With != nil {
+		root.Children = append(root.Children, s.With.Tree())
+	}
+
+	columns := treeList("COLUMNS", s.Columns)
+	if s.Distinct {
+		columns.Type = "DISTINCT_COLUMNS"
+	}
+	root.Children = append(root.Children, columns)
+
+	for _, from := range s.From {
+		root.Children = append(root.Children, from.Tree())
+	}
+	if s.Where != nil {
+		root.Children = append(root.Children, clauseTree("WHERE_CLAUSE", s.Where))
+	}
+	having := SyntaxNode{}
+	if s.Having != nil {
+		having = clauseTree("HAVING_CLAUSE", s.Having)
+	}
+	if len(s.GroupBy) > 0 {
+		group := treeList("GROUP_BY_CLAUSE", s.GroupBy)
+		if s.Having != nil {
+			group.Children = append(group.Children, having)
+		}
+		root.Children = append(root.Children, group)
+	} else if s.Having != nil {
+		root.Children = append(root.Children, having)
+	}
+	if len(s.Windows) > 0 {
+		root.Children = append(root.Children, treeList("WINDOW_CLAUSE", s.Windows))
+	}
+	root.Children = append(root.Children, s.ResultClauses.trees()...)
+	return root
+}
+
+func (s *SetOperation) Tree() SyntaxNode {
+	op := s.Op
+	if s.All {
+		op += " ALL"
+	}
+	root := SyntaxNode{Type: "SET_OPERATION", Value: op, Span: s.Span}
+	if s.With != nil {
+		root.Children = append(root.Children, s.With.Tree())
+	}
+	root.Children = append(root.Children, s.Left.Tree(), s.Right.Tree())
+	root.Children = append(root.Children, s.ResultClauses.trees()...)
+	return root
+}
+
+// trees devuelve los nodos ORDER_BY_CLAUSE, LIMIT y OFFSET presentes.
+func (r *ResultClauses) trees() []SyntaxNode {
+	var nodes []SyntaxNode
+	if len(r.OrderBy) > 0 {
+		nodes = append(nodes, treeList("ORDER_BY_CLAUSE", r.OrderBy))
+	}
+	if r.Limit != nil {
+		nodes = append(nodes, clauseTree("LIMIT", r.Limit))
+	}
+	if r.Offset != nil {
+		nodes = append(nodes, clauseTree("OFFSET", r.Offset))
+	}
+	return nodes
+}
+
+// clauseTree envuelve el árbol de una expresión en el nodo de su cláusula.
+func clauseTree(nodeType string, expr Expr) SyntaxNode {
+	return SyntaxNode{Type: nodeType, Children: []SyntaxNode{expr.Tree()}}
+}
+
+func (s *SelectItem) Tree() SyntaxNode { return withAlias(s.Expr.Tree(), s.Alias) }
+func (t *TableRef) Tree() SyntaxNode   { return withAlias(nameNode("TABLE", t.Name), t.Alias) }
+
+// withAlias agrega al nodo un hijo ALIAS si el alias no está vacío.
+func withAlias(node SyntaxNode, alias Identifier) SyntaxNode {
+	if alias.Name != "" {
+		node.Children = append(node.Children, SyntaxNode{Type: "ALIAS", Value: alias.String(), Span: alias.Span})
+	}
+	return node
+}
+
+func (j *JoinExpr) Tree() SyntaxNode {
+	join := j.Kind + " JOIN"
+	if j.Natural {
+		join = "NATURAL " + join
+	}
+	node := SyntaxNode{Type: "JOIN", Value: join, Span: j.Span,
+		Children: []SyntaxNode{j.Left.Tree(), j.Right.Tree()}}
+	if j.On != nil {
+		node.Children = append(node.Children, clauseTree("JOIN_CONDITION", j.On))
+	}
+	if len(j.Using) > 0 {
+		node.Children = append(node.Children, identifierList("USING", "COLUMN", j.Using))
+	}
+	return node
+}
+
+func (w *WithClause) Tree() SyntaxNode {
+	node := treeList("WITH_CLAUSE", w.CTEs)
+	node.Span = w.Span
+	if w.Recursive {
+		node.Value = "RECURSIVE"
+	}
+	return node
+}
+
+func (c *CommonTableExpr) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "CTE", Value: c.Name.String(), Span: c.Span}
+	if len(c.Columns) > 0 {
+		node.Children = append(node.Children, identifierList("COLUMNS", "COLUMN", c.Columns))
+	}
+	if c.Materialized != "" {
+		node.Children = append(node.Children, SyntaxNode{Type: "MATERIALIZATION", Value: c.Materialized})
+	}
+	node.Children = append(node.Children, c.Query.Tree())
+	return node
+}
+
+func (d *DerivedTable) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "DERIVED_TABLE", Span: d.Span, Children: []SyntaxNode{d.Query.Tree()}}
+	if d.Lateral {
+		node.Value = "LATERAL"
+	}
+	node = withAlias(node, d.Alias)
+	if len(d.ColumnAliases) > 0 {
+		node.Children = append(node.Children, identifierList("COLUMN_ALIASES", "COLUMN", d.ColumnAliases))
+	}
+	return node
+}
+
+func (o *OrderItem) Tree() SyntaxNode {
+	item := SyntaxNode{Type: "ORDER_ITEM", Span: o.Span, Children: []SyntaxNode{o.Expr.Tree()}}
+	if o.Direction != "" {
+		item.Children = append(item.Children, SyntaxNode{Type: "DIRECTION", Value: o.Direction})
+	}
+	return item
+}
+
+// Tree devuelve un nodo WINDOW con el nombre de la ventana; en OVER el nodo
+// se renombra y su valor es la ventana base, si la hay.
+func (w *WindowSpec) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "WINDOW", Span: w.Span}
+	switch {
+	case w.Name.Name != "":
+		node.Value = w.Name.String()
+		if w.Base.Name != "" {
+			node.Children = append(node.Children, SyntaxNode{Type: "BASE_WINDOW", Value: w.Base.String(), Span: w.Base.Span})
+		}
+	case w.Base.Name != "":
+		node.Value = w.Base.String()
+	}
+	if len(w.PartitionBy) > 0 {
+		node.Children = append(node.Children, treeList("PARTITION_BY", w.PartitionBy))
+	}
+	if len(w.OrderBy) > 0 {
+		node.Children = append(node.Children, treeList("ORDER_BY_CLAUSE", w.OrderBy))
+	}
+	if w.Frame != nil {
+		node.Children = append(node.Children, w.Frame.Tree())
+	}
+	return node
+}
+
+func (f *WindowFrame) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "FRAME", Value: f.Units, Span: f.Span, Children: []SyntaxNode{f.Start.Tree()}}
+	if f.End != nil {
+		end := f.End.Tree()
+		end.Type = "FRAME_END"
+		node.Children = append(node.Children, end)
+	}
+	if f.Exclude != "" {
+		node.Children = append(node.Children, SyntaxNode{Type: "EXCLUDE", Value: f.Exclude})
+	}
+	return node
+}
+
+func (b *FrameBound) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "FRAME_START", Value: b.Kind, Span: b.Span}
+	if b.Offset != nil {
+		node.Children = append(node.Children, b.Offset.Tree())
+	}
+	return node
+}
+
+func (s *InsertStmt) Tree() SyntaxNode {
+	root := SyntaxNode{Type: "INSERT_STATEMENT", Span: s.Span}
+	root.Children = append(root.Children, s.Table.Tree())
+	if len(s.Columns) > 0 {
+		root.Children = append(root.Children, identifierList("COLUMNS", "COLUMN", s.Columns))
+	}
+	switch {
+	case s.DefaultValues:
+		root.Children = append(root.Children, SyntaxNode{Type: "DEFAULT_VALUES", Value: "true"})
+	case s.Query != nil:
+		root.Children = append(root.Children, s.Query.Tree())
+	default:
+		values := SyntaxNode{Type: "VALUES"}
+		for _, row := range s.Values {
+			values.Children = append(values.Children, treeList("VALUE_SET", row))
+		}
+		root.Children = append(root.Children, values)
+	}
+	if s.OnConflict != nil {
+		root.Children = append(root.Children, s.OnConflict.Tree())
+	}
+	if len(s.Returning) > 0 {
+		root.Children = append(root.Children, treeList("RETURNING_CLAUSE", s.Returning))
+	}
+	return root
+}
+
+func (c *OnConflict) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "ON_CONFLICT", Value: "DO " + c.Action, Span: c.Span}
+	switch {
+	case c.Constraint.Name != "":
+		node.Children = append(node.Children, SyntaxNode{Type: "CONSTRAINT", Value: c.Constraint.String(), Span: c.Constraint.Span})
+	case len(c.Target) > 0:
+		target := identifierList("CONFLICT_TARGET", "COLUMN", c.Target)
+		if c.TargetWhere != nil {
+			target.Children = append(target.Children, clauseTree("WHERE_CLAUSE", c.TargetWhere))
+		}
+		node.Children = append(node.Children, target)
+	}
+	if len(c.Set) > 0 {
+		node.Children = append(node.Children, treeList("SET_CLAUSE", c.Set))
+	}
+	if c.Where != nil {
+		node.Children = append(node.Children, clauseTree("WHERE_CLAUSE", c.Where))
+	}
+	return node
+}
+
+// identifierList agrupa una lista de nombres simples bajo un nodo.
+func identifierList(listType, itemType string, ids []Identifier) SyntaxNode {
+	list := SyntaxNode{Type: listType}
+	for _, id := range ids {
+		list.Children = append(list.Children, SyntaxNode{Type: itemType, Value: id.String(), Span: id.Span})
+	}
+	return list
+}
+
+func (s *UpdateStmt) Tree() SyntaxNode {
+	root := SyntaxNode{Type: "UPDATE_STATEMENT", Span: s.Span}
+	root.Children = append(root.Children, s.Table.Tree(), treeList("SET_CLAUSE", s.Set))
+	if len(s.From) > 0 {
+		root.Children = append(root.Children, treeList("FROM_CLAUSE", s.From))
+	}
+	if s.Where != nil {
+		root.Children = append(root.Children, clauseTree("WHERE_CLAUSE", s.Where))
+	}
+	if len(s.Returning) > 0 {
+		root.Children = append(root.Children, treeList("RETURNING_CLAUSE", s.Returning))
+	}
+	return root
+}
+
+func (a *Assignment) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "ASSIGNMENT", Span: a.Span}
+	if len(a.Columns) > 0 {
+		node.Children = append(node.Children, identifierList("COLUMNS", "COLUMN", a.Columns))
+	} else {
+		node.Children = append(node.Children, SyntaxNode{Type: "COLUMN", Value: a.Column.String(), Span: a.Column.Span})
+	}
+	if a.Value != nil {
+		node.Children = append(node.Children, a.Value.Tree())
+	} else {
+		node.Children = append(node.Children, treeList("VALUE_SET", a.Values))
+	}
+	return node
+}
+
+func (s *DeleteStmt) Tree() SyntaxNode {
+	root := SyntaxNode{Type: "DELETE_STATEMENT", Span: s.Span}
+	root.Children = append(root.Children, s.Table.Tree())
+	if len(s.Using) > 0 {
+		root.Children = append(root.Children, treeList("USING_CLAUSE", s.Using))
+	}
+	if s.Where != nil {
+		root.Children = append(root.Children, clauseTree("WHERE_CLAUSE", s.Where))
+	}
+	if len(s.Returning) > 0 {
+		root.Children = append(root.Children, treeList("RETURNING_CLAUSE", s.Returning))
+	}
+	return root
+}
+
+func (s *CreateTableStmt) Tree() SyntaxNode {
+	root := SyntaxNode{Type: "CREATE_STATEMENT", Span: s.Span}
+	table := nameNode("TABLE", s.Name)
+	if s.IfNotExists {
+		table.Children = append(table.Children, SyntaxNode{Type: "IF_NOT_EXISTS", Value: "true"})
+	}
+	columns := treeList("COLUMNS", s.Columns)
+	for _, constraint := range s.Constraints {
+		columns.Children = append(columns.Children, constraint.Tree())
+	}
+	root.Children = append(root.Children, table, columns)
+	return root
+}
+
+func (c *ColumnDef) Tree() SyntaxNode {
+	column := SyntaxNode{Type: "COLUMN_DEFINITION", Value: c.Name.String(), Span: c.Span}
+	column.Children = append(column.Children, c.Type.Tree())
+	for _, constraint := range c.Constraints {
+		column.Children = append(column.Children, constraint.Tree())
+	}
+	return column
+}
+
+func (d *DataType) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "DATA_TYPE", Value: d.Name + strings.Repeat("[]", d.ArrayDims), Span: d.Span}
+	for _, param := range d.Params {
+		node.Children = append(node.Children, SyntaxNode{Type: "SIZE", Value: param})
+	}
+	return node
+}
+
+// String devuelve el tipo como se escribiría en SQL, por ejemplo
+// DECIMAL(10,2).
+func (d *DataType) String() string {
+	name := d.Name
+	if len(d.Params) > 0 {
+		name += "(" + strings.Join(d.Params, ",") + ")"
+	}
+	return name + strings.Repeat("[]", d.ArrayDims)
+}
+
+func (c *ColumnConstraint) Tree() SyntaxNode {
+	switch c.Kind {
+	case "DEFAULT":
+		return SyntaxNode{Type: "DEFAULT", Span: c.Span, Children: []SyntaxNode{c.Default.Tree()}}
+	case "REFERENCES":
+		return c.References.Tree()
+	case "CHECK":
+		return SyntaxNode{Type: "CONSTRAINT", Value: "CHECK", Span: c.Span,
+			Children: []SyntaxNode{clauseTree("CHECK_CONDITION", c.Check)}}
+	default:
+		return SyntaxNode{Type: "CONSTRAINT", Value: c.Kind, Span: c.Span}
+	}
+}
+
+func (c *TableConstraint) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "TABLE_CONSTRAINT", Value: c.Name, Span: c.Span}
+	switch c.Kind {
+	case "PRIMARY KEY":
+		node.Children = append(node.Children, identifierList("PRIMARY_KEY", "COLUMN", c.Columns))
+	case "UNIQUE":
+		node.Children = append(node.Children, identifierList("UNIQUE", "COLUMN", c.Columns))
+	case "FOREIGN KEY":
+		fk := identifierList("FOREIGN_KEY", "COLUMN", c.Columns)
+		fk.Children = append(fk.Children, c.References.Tree())
+		node.Children = append(node.Children, fk)
+	case "CHECK":
+		node.Children = append(node.Children, clauseTree("CHECK_CONDITION", c.Check))
+	}
+	return node
+}
+
+func (r *Reference) Tree() SyntaxNode {
+	node := nameNode("REFERENCES", r.Table.Name)
+	node.Span = r.Span
+	for _, column := range r.Columns {
+		node.Children = append(node.Children, SyntaxNode{Type: "REF_COLUMN", Value: column.String(), Span: column.Span})
+	}
+	if r.OnDelete != "" {
+		node.Children = append(node.Children, SyntaxNode{Type: "ON_DELETE", Value: r.OnDelete})
+	}
+	if r.OnUpdate != "" {
+		node.Children = append(node.Children, SyntaxNode{Type: "ON_UPDATE", Value: r.OnUpdate})
+	}
+	return node
+}
+
+func (s *CreateDatabaseStmt) Tree() SyntaxNode {
+	return SyntaxNode{Type: "CREATE_STATEMENT", Span: s.Span, Children: []SyntaxNode{
+		{Type: "DATABASE", Value: s.Name.String(), Span: s.Name.Span},
+	}}
+}
+
+func (s *CreateIndexStmt) Tree() SyntaxNode {
+	index := SyntaxNode{Type: "INDEX", Value: s.Name.String(), Span: s.Name.Span}
+	if s.Unique {
+		index.Children = append(index.Children, SyntaxNode{Type: "UNIQUE", Value: "true"})
+	}
+	if s.IfNotExists {
+		index.Children = append(index.Children, SyntaxNode{Type: "IF_NOT_EXISTS", Value: "true"})
+	}
+	index.Children = append(index.Children, s.Table.Tree())
+	if s.Method != "" {
+		index.Children = append(index.Children, SyntaxNode{Type: "USING", Value: s.Method})
+	}
+	columns := identifierList("COLUMNS", "COLUMN", s.Columns)
+	for _, expr := range s.Expressions {
+		columns.Children = append(columns.Children, clauseTree("EXPRESSION", expr))
+	}
+	index.Children = append(index.Children, columns)
+	return SyntaxNode{Type: "CREATE_STATEMENT", Span: s.Span, Children: []SyntaxNode{index}}
+}
+
+func (s *AlterTableStmt) Tree() SyntaxNode {
+	table := nameNode("TABLE", s.Table.Name)
+	if s.IfExists {
+		table.Children = append(table.Children, SyntaxNode{Type: "IF_EXISTS", Value: "true"})
+	}
+	root := SyntaxNode{Type: "ALTER_STATEMENT", Span: s.Span, Children: []SyntaxNode{table}}
+	for _, action := range s.Actions {
+		root.Children = append(root.Children, action.Tree())
+	}
+	return root
+}
+
+func (a *AlterAction) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "ALTER_ACTION", Value: a.Kind, Span: a.Span}
+	switch {
+	case a.Definition != nil:
+		node.Children = append(node.Children, a.Definition.Tree())
+	case a.Constraint != nil:
+		node.Children = append(node.Children, a.Constraint.Tree())
+	case a.Kind == "DROP CONSTRAINT" || a.Kind == "RENAME CONSTRAINT":
+		node.Children = append(node.Children, SyntaxNode{Type: "CONSTRAINT", Value: a.Column.String(), Span: a.Column.Span})
+	case a.Column.Name != "":
+		node.Children = append(node.Children, SyntaxNode{Type: "COLUMN", Value: a.Column.String(), Span: a.Column.Span})
+	}
+	if a.IfExists {
+		node.Children = append(node.Children, SyntaxNode{Type: "IF_EXISTS", Value: "true"})
+	}
+	if a.Type != nil {
+		node.Children = append(node.Children, a.Type.Tree())
+	}
+	if a.Using != nil {
+		node.Children = append(node.Children, clauseTree("USING", a.Using))
+	}
+	if a.Default != nil {
+		node.Children = append(node.Children, clauseTree("DEFAULT", a.Default))
+	}
+	if a.NewName.Name != "" {
+		node.Children = append(node.Children, SyntaxNode{Type: "NEW_NAME", Value: a.NewName.String(), Span: a.NewName.Span})
+	}
+	if a.Behavior != "" {
+		node.Children = append(node.Children, SyntaxNode{Type: "BEHAVIOR", Value: a.Behavior})
+	}
+	return node
+}
+
+func (s *DropStmt) Tree() SyntaxNode {
+	root := SyntaxNode{Type: "DROP_STATEMENT", Span: s.Span}
+	if s.IfExists {
+		root.Children = append(root.Children, SyntaxNode{Type: "IF_EXISTS", Value: "true"})
+	}
+	if s.Concurrently {
+		root.Children = append(root.Children, SyntaxNode{Type: "CONCURRENTLY", Value: "true"})
+	}
+	nodeType := strings.ReplaceAll(s.ObjectType, " ", "_")
+	for _, name := range s.Names {
+		root.Children = append(root.Children, nameNode(nodeType, name))
+	}
+	if s.Behavior != "" {
+		root.Children = append(root.Children, SyntaxNode{Type: "BEHAVIOR", Value: s.Behavior})
+	}
+	return root
+}
+
+func (s *TransactionStmt) Tree() SyntaxNode {
+	root := SyntaxNode{Type: "TRANSACTION_STATEMENT", Value: s.Kind, Span: s.Span}
+	if s.Savepoint.Name != "" {
+		root.Children = append(root.Children, SyntaxNode{Type: "SAVEPOINT", Value: s.Savepoint.String(), Span: s.Savepoint.Span})
+	}
+	for _, mode := range s.Modes {
+		root.Children = append(root.Children, SyntaxNode{Type: "TRANSACTION_MODE", Value: mode})
+	}
+	return root
+}
+
+func (l *Literal) Tree() SyntaxNode {
+	return SyntaxNode{Type: "VALUE", Value: l.Value, Span: l.Span}
+}
+
+func (c *ColumnRef) Tree() SyntaxNode { return nameNode("COLUMN", c.Name) }
+
+func (f *FuncCall) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "FUNCTION", Value: f.Name.String(), Span: f.Span}
+	if f.Distinct {
+		node.Children = append(node.Children, SyntaxNode{Type: "DISTINCT"})
+	}
+	if f.Star {
+		node.Children = append(node.Children, SyntaxNode{Type: "COLUMN", Value: "*"})
+	}
+	for _, arg := range f.Args {
+		node.Children = append(node.Children, arg.Tree())
+	}
+	if len(f.OrderBy) > 0 {
+		node.Children = append(node.Children, treeList("ORDER_BY_CLAUSE", f.OrderBy))
+	}
+	if f.Filter != nil {
+		node.Children = append(node.Children, clauseTree("FILTER", f.Filter))
+	}
+	if f.Over != nil {
+		over := f.Over.Tree()
+		over.Type = "OVER"
+		node.Children = append(node.Children, over)
+	}
+	return node
+}
+
+func (b *BinaryExpr) Tree() SyntaxNode {
+	return SyntaxNode{Type: "BINARY_EXPR", Value: b.Op, Span: b.Span,
+		Children: []SyntaxNode{b.Left.Tree(), b.Right.Tree()}}
+}
+
+func (u *UnaryExpr) Tree() SyntaxNode {
+	return SyntaxNode{Type: "UNARY_EXPR", Value: u.Op, Span: u.Span,
+		Children: []SyntaxNode{u.Operand.Tree()}}
+}
+
+// negated antepone NOT al operador cuando corresponde ("NOT IN", "NOT LIKE").
+func negated(op string, not bool) string {
+	if not {
+		return "NOT " + op
+	}
+	return op
+}
+
+func (e *InExpr) Tree() SyntaxNode {
+	list := SyntaxNode{Type: "IN_LIST"}
+	for _, item := range e.List {
+		list.Children = append(list.Children, item.Tree())
+	}
+	if e.Subquery != nil {
+		list = subqueryTree(e.Subquery)
+	}
+	return SyntaxNode{Type: "IN_EXPR", Value: negated("IN", e.Not), Span: e.Span,
+		Children: []SyntaxNode{e.Expr.Tree(), list}}
+}
+
+func (e *BetweenExpr) Tree() SyntaxNode {
+	return SyntaxNode{Type: "BETWEEN_EXPR", Value: negated("BETWEEN", e.Not), Span: e.Span,
+		Children: []SyntaxNode{e.Expr.Tree(), e.Low.Tree(), e.High.Tree()}}
+}
+
+func (e *LikeExpr) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "LIKE_EXPR", Value: negated(e.Op, e.Not), Span: e.Span,
+		Children: []SyntaxNode{e.Expr.Tree(), e.Pattern.Tree()}}
+	if e.Escape != nil {
+		node.Children = append(node.Children, clauseTree("ESCAPE", e.Escape))
+	}
+	return node
+}
+
+func (e *IsExpr) Tree() SyntaxNode {
+	return SyntaxNode{Type: "IS_EXPR", Value: "IS " + negated(e.Value, e.Not), Span: e.Span,
+		Children: []SyntaxNode{e.Expr.Tree()}}
+}
+
+func (e *CaseExpr) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "CASE_EXPR", Span: e.Span}
+	if e.Operand != nil {
+		node.Children = append(node.Children, clauseTree("CASE_OPERAND", e.Operand))
+	}
+	for _, when := range e.Whens {
+		node.Children = append(node.Children, when.Tree())
+	}
+	if e.Else != nil {
+		node.Children = append(node.Children, clauseTree("ELSE", e.Else))
+	}
+	return node
+}
+
+func (w *WhenClause) Tree() SyntaxNode {
+	return SyntaxNode{Type: "WHEN", Span: w.Span,
+		Children: []SyntaxNode{w.Cond.Tree(), clauseTree("THEN", w.Result)}}
+}
+
+func (e *CastExpr) Tree() SyntaxNode {
+	return SyntaxNode{Type: "CAST_EXPR", Value: e.Type.String(), Span: e.Span,
+		Children: []SyntaxNode{e.Expr.Tree(), e.Type.Tree()}}
+}
+
+// subqueryTree envuelve el árbol de una subconsulta en un nodo SUBQUERY.
+func subqueryTree(query Query) SyntaxNode {
+	return SyntaxNode{Type: "SUBQUERY", Span: query.Location(), Children: []SyntaxNode{query.Tree()}}
+}
+
+func (e *SubqueryExpr) Tree() SyntaxNode {
+	node := subqueryTree(e.Query)
+	node.Span = e.Span
+	return node
+}
+
+func (e *ExistsExpr) Tree() SyntaxNode {
+	return SyntaxNode{Type: "EXISTS", Span: e.Span, Children: []SyntaxNode{e.Query.Tree()}}
+}
+
+func (e *QuantifiedExpr) Tree() SyntaxNode {
+	node := SyntaxNode{Type: "QUANTIFIED_EXPR", Value: e.Op + " " + e.Quantifier, Span: e.Span,
+		Children: []SyntaxNode{e.Left.Tree()}}
+	if e.Query != nil {
+		node.Children = append(node.Children, subqueryTree(e.Query))
+	} else {
+		node.Children = append(node.Children, e.Array.Tree())
+	}
+	return node
+}
@@ -0,0 +1,123 @@
+package analyzer
+
+import "strings"
+
+// Catalog da acceso a los objetos de la base contra los que el análisis
+// semántico verifica una sentencia: tablas con sus columnas y constraints,
+// otros objetos (vistas, índices, secuencias, esquemas), funciones y tipos.
+// PostgresCatalog los consulta en una base real y MemoryCatalog los guarda
+// en memoria, para analizar sin conexión.
+type Catalog interface {
+	// Table devuelve la estructura de una tabla o vista. Un nombre sin
+	// esquema se busca en los esquemas del search_path.
+	Table(name QualifiedName) (*TableSchema, bool)
+	// ObjectExists indica si existe un objeto del tipo indicado: TABLE,
+	// VIEW, MATERIALIZED VIEW, INDEX, SEQUENCE o SCHEMA.
+	ObjectExists(objectType string, name QualifiedName) bool
+	// Dependents devuelve los objetos que dependen de uno que se elimina y
+	// que DROP ... CASCADE eliminaría también.
+	Dependents(objectType string, name QualifiedName) []DependentObject
+	// Function busca una función por su nombre.
+	Function(name QualifiedName) (FunctionInfo, bool)
+	// TypeCategory devuelve la categoría de un tipo tal como lo nombra el
+	// catálogo (integer, character varying...), o "" si no se conoce.
+	TypeCategory(typeName string) string
+}
+
+// TableSchema es la estructura de una tabla: sus columnas, en el orden en
+// que están definidas, y sus constraints.
+type TableSchema struct {
+	Name        string
+	Columns     []ColumnSchema
+	Constraints []ConstraintSchema
+}
+
+// ColumnSchema describe una columna de una tabla: su tipo tal como lo
+// informa information_schema (integer, character varying...) y si admite
+// NULL. Length es el largo máximo de un character varying(n) o
+// character(n), o 0 si el tipo no lo limita.
+type ColumnSchema struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Length   int
+}
+
+// ConstraintSchema es un constraint de una tabla. Kind es "PRIMARY KEY",
+// "UNIQUE", "FOREIGN KEY" o "CHECK"; References y RefColumns son el destino
+// de una clave foránea.
+type ConstraintSchema struct {
+	Name       string
+	Kind       string
+	Columns    []string
+	References string
+	RefColumns []string
+}
+
+// Column busca una columna de la tabla por su nombre.
+func (t *TableSchema) Column(name string) (ColumnSchema, bool) {
+	for _, column := range t.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return ColumnSchema{}, false
+}
+
+// columnMap devuelve las columnas de la tabla por nombre.
+func (t *TableSchema) columnMap() map[string]ColumnSchema {
+	columns := make(map[string]ColumnSchema, len(t.Columns))
+	for _, column := range t.Columns {
+		columns[column.Name] = column
+	}
+	return columns
+}
+
+// hasUniqueKey indica si la tabla tiene una PRIMARY KEY o un UNIQUE formado
+// exactamente por esas columnas, en cualquier orden.
+func (t *TableSchema) hasUniqueKey(columns []string) bool {
+	for _, constraint := range t.Constraints {
+		if constraint.Kind != "PRIMARY KEY" && constraint.Kind != "UNIQUE" {
+			continue
+		}
+		if len(constraint.Columns) != len(columns) {
+			continue
+		}
+		matches := true
+		for _, column := range columns {
+			matches = matches && containsName(constraint.Columns, column)
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultCatalog es el catálogo que usan SemanticAnalysis y
+// ScriptStatement.Semantic. Vacío hasta que la aplicación lo reemplace, por
+// ejemplo por un PostgresCatalog.
+var DefaultCatalog Catalog = NewMemoryCatalog()
+
+// catalogKey es la clave de un objeto en un catálogo en memoria: su nombre
+// sin comillas, calificado con el esquema salvo que sea public.
+func catalogKey(name QualifiedName) string {
+	if schema := name.Qualifier(); schema != "" && schema != "public" {
+		return schema + "." + name.Name()
+	}
+	return name.Name()
+}
+
+// keyName convierte una clave de catálogo en el nombre del objeto.
+func keyName(key string) QualifiedName {
+	var name QualifiedName
+	for _, part := range strings.SplitN(key, ".", 2) {
+		name.Parts = append(name.Parts, Identifier{Name: part})
+	}
+	return name
+}
+
+// nameKey es catalogKey para un nombre escrito como texto ("ventas.t").
+func nameKey(name string) string {
+	return strings.TrimPrefix(name, "public.")
+}
@@ -0,0 +1,714 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LoadCatalogDir arma un catálogo en memoria con los scripts .sql de dir
+// (por ejemplo, las migraciones del esquema), aplicándolos en orden
+// alfabético de nombre de archivo. Un error indica el archivo y la posición
+// de la sentencia que no se pudo analizar o aplicar. Los avisos, con el
+// archivo al inicio, son las sentencias que se omitieron (ver ApplyScript).
+func LoadCatalogDir(dir string) (*MemoryCatalog, []string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(files)
+
+	catalog := NewMemoryCatalog()
+	var warnings []string
+	for _, file := range files {
+		script, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		skipped, err := catalog.ApplyScript(string(script))
+		for _, warning := range skipped {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", file, warning))
+		}
+		if err != nil {
+			return nil, warnings, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	return catalog, warnings, nil
+}
+
+// ApplyScript aplica al catálogo las sentencias de un script, en orden. Se
+// detiene en la primera que no se puede aplicar o que, sin poder
+// analizarse, crea, modifica o elimina una tabla. Las demás sentencias que
+// no se pueden analizar (CREATE FUNCTION, CREATE EXTENSION, GRANT...) no
+// cambian las tablas: se omiten y se devuelven como avisos. De las vistas,
+// secuencias y esquemas que crean se registra el nombre, para que un DROP
+// posterior los encuentre.
+func (c *MemoryCatalog) ApplyScript(script string) ([]*AnalysisError, error) {
+	var skipped []*AnalysisError
+	for _, statement := range SplitScript(script) {
+		stmt, err := statement.Syntactic()
+		if err == nil {
+			if err := c.Apply(stmt); err != nil {
+				return skipped, err
+			}
+			continue
+		}
+		if changesTables(statement) {
+			return skipped, err
+		}
+		reason := err.Error()
+		var analysisErr *AnalysisError
+		if errors.As(err, &analysisErr) {
+			reason = analysisErr.Message
+		}
+		if objectType, name, ok := c.recordObject(statement); ok {
+			skipped = append(skipped, newAnalysisError(statement.Span, "se registró %s '%s' sin analizar su definición (%s)",
+				objectLabels[objectType], name, reason))
+		} else {
+			skipped = append(skipped, newAnalysisError(statement.Span, "se omitió una sentencia que no modifica tablas (%s)", reason))
+		}
+	}
+	return skipped, nil
+}
+
+// tableStatements son los comienzos de las sentencias que cambian la
+// estructura de las tablas; sin ellas el catálogo no reflejaría el esquema.
+var tableStatements = [][]string{
+	{"CREATE", "TABLE"}, {"CREATE", "TEMP", "TABLE"}, {"CREATE", "TEMPORARY", "TABLE"},
+	{"CREATE", "UNLOGGED", "TABLE"}, {"ALTER", "TABLE"}, {"DROP", "TABLE"},
+}
+
+// changesTables indica si la sentencia crea, modifica o elimina una tabla.
+func changesTables(statement ScriptStatement) bool {
+	p, ok := statementParser(statement)
+	if !ok {
+		return false
+	}
+	for _, words := range tableStatements {
+		if p.isKeyword(words...) {
+			return true
+		}
+	}
+	return false
+}
+
+// statementParser prepara un parser con los tokens de la sentencia, si se
+// puede analizar léxicamente.
+func statementParser(statement ScriptStatement) (*parser, bool) {
+	tokens, err := statement.Lexical()
+	if err != nil {
+		return nil, false
+	}
+	return &parser{tokens: withoutComments(tokens)}, true
+}
+
+// createdObjects son los objetos cuya creación se registra aunque la
+// sentencia no se pueda analizar.
+var createdObjects = [][]string{
+	{"MATERIALIZED", "VIEW"}, {"VIEW"}, {"SEQUENCE"}, {"SCHEMA"},
+}
+
+// recordObject registra la vista, secuencia o esquema que crea una
+// sentencia que no se pudo analizar: CREATE [OR REPLACE] [TEMP] tipo [IF
+// NOT EXISTS] nombre [(columnas)]. Una vista depende de las tablas de su
+// consulta y, si se puede analizar, tiene sus columnas.
+func (c *MemoryCatalog) recordObject(statement ScriptStatement) (string, string, bool) {
+	p, ok := statementParser(statement)
+	if !ok || !p.acceptKeyword("CREATE") {
+		return "", "", false
+	}
+	p.acceptKeyword("OR", "REPLACE")
+	if !p.acceptKeyword("TEMP") {
+		p.acceptKeyword("TEMPORARY")
+	}
+	var objectType string
+	for _, words := range createdObjects {
+		if p.acceptKeyword(words...) {
+			objectType = strings.Join(words, " ")
+			break
+		}
+	}
+	if objectType == "" {
+		return "", "", false
+	}
+	p.acceptKeyword("IF", "NOT", "EXISTS")
+	name, err := p.parseQualifiedName("nombre", false)
+	if err != nil {
+		return "", "", false
+	}
+
+	var dependsOn []string
+	var columns []ColumnSchema
+	if strings.HasSuffix(objectType, "VIEW") {
+		var aliases []Identifier
+		if p.accept("(") {
+			for !p.atEnd() && !p.accept(")") {
+				if alias, err := p.parseIdentifier("columna"); err == nil {
+					aliases = append(aliases, alias)
+				}
+				p.accept(",")
+			}
+		}
+		// La consulta empieza después del primer AS
+		for !p.atEnd() && !p.acceptKeyword("AS") {
+			p.next()
+		}
+		if query, err := p.parseQuery(); err == nil {
+			for _, table := range extractTables(query) {
+				dependsOn = append(dependsOn, catalogKey(table))
+			}
+			columns = c.viewColumns(query, aliases)
+		}
+	}
+	key := catalogKey(name)
+	c.AddObject(objectType, key, dependsOn...)
+	if columns != nil {
+		object := c.objects[nameKey(key)]
+		object.columns = columns
+		c.objects[nameKey(key)] = object
+	}
+	delete(c.dropped, key)
+	return objectType, name.String(), true
+}
+
+// viewColumns deduce las columnas de la consulta de una vista, con sus
+// tipos, renombradas con la lista de columnas de CREATE VIEW. Un '*' se
+// reemplaza por las columnas de las tablas de FROM. Devuelve nil si no se
+// pueden conocer, por ejemplo con un '*' sobre una subconsulta.
+func (c *MemoryCatalog) viewColumns(query Query, aliases []Identifier) []ColumnSchema {
+	first := query
+	for {
+		set, ok := first.(*SetOperation)
+		if !ok {
+			break
+		}
+		first = set.Left
+	}
+	selectStmt, ok := first.(*SelectStmt)
+	if !ok {
+		return nil
+	}
+
+	info := analyzeSemantics(query, c)
+	var entries []scopeEntry
+	for _, item := range selectStmt.From {
+		collectEntries(item, &entries)
+	}
+	columns := []ColumnSchema{}
+	for _, item := range selectStmt.Columns {
+		ref, isRef := item.Expr.(*ColumnRef)
+		if !isRef || !ref.IsStar() {
+			t := info.typeOf(item.Expr)
+			columns = append(columns, ColumnSchema{Name: itemName(item), Type: t.name, Nullable: true, Length: t.length})
+			continue
+		}
+		for _, entry := range entries {
+			if entry.table == nil || entry.table.CTE != nil {
+				return nil
+			}
+			if qualifier := ref.Name.Qualifier(); qualifier != "" && qualifier != entry.table.VisibleName() {
+				continue
+			}
+			table, known := info.tableOf(entry.table.Name)
+			if !known {
+				return nil
+			}
+			columns = append(columns, table.schema.Columns...)
+		}
+	}
+
+	for i, alias := range aliases {
+		if i < len(columns) {
+			columns[i].Name = alias.Name
+		}
+	}
+	return columns
+}
+
+// Apply aplica al catálogo el efecto de una sentencia CREATE TABLE, CREATE
+// INDEX, ALTER TABLE o DROP, con los mismos errores que daría PostgreSQL al
+// ejecutarla (la tabla ya existe, la columna no existe...). Las demás
+// sentencias no cambian el esquema y se ignoran. Si hay un error el
+// catálogo queda como estaba.
+func (c *MemoryCatalog) Apply(stmt Statement) error {
+	switch s := stmt.(type) {
+	case *CreateTableStmt:
+		return c.applyCreateTable(s)
+	case *CreateIndexStmt:
+		return c.applyCreateIndex(s)
+	case *AlterTableStmt:
+		return c.applyAlterTable(s)
+	case *DropStmt:
+		return c.applyDrop(s)
+	}
+	return nil
+}
+
+func (c *MemoryCatalog) applyCreateTable(stmt *CreateTableStmt) error {
+	key := catalogKey(stmt.Name)
+	if _, exists := c.table(key); exists {
+		if stmt.IfNotExists {
+			return nil
+		}
+		return newAnalysisError(stmt.Name.Span, "la tabla '%s' ya existe", stmt.Name.String())
+	}
+
+	table := &TableSchema{Name: key}
+	for _, def := range stmt.Columns {
+		if _, exists := table.Column(def.Name.Name); exists {
+			return newAnalysisError(def.Name.Span, "la columna '%s' está definida más de una vez", def.Name.String())
+		}
+		table.Columns = append(table.Columns, ColumnSchema{
+			Name:     def.Name.Name,
+			Type:     catalogTypeName(def.Type),
+			Nullable: !columnNotNull(def),
+			Length:   typeLength(def.Type),
+		})
+		for _, constraint := range def.Constraints {
+			if schema, ok := columnConstraintSchema(key, def.Name.Name, constraint); ok {
+				table.Constraints = append(table.Constraints, schema)
+			}
+		}
+	}
+	for _, constraint := range stmt.Constraints {
+		schema, err := c.tableConstraintSchema(table, constraint)
+		if err != nil {
+			return err
+		}
+		table.Constraints = append(table.Constraints, schema)
+		if schema.Kind == "PRIMARY KEY" {
+			table.setNotNull(schema.Columns)
+		}
+	}
+	if err := c.checkReferences(table, stmt.Name.Span); err != nil {
+		return err
+	}
+
+	c.tables[key] = table
+	delete(c.dropped, key)
+	return nil
+}
+
+// columnConstraintSchema convierte un constraint de columna en uno del
+// catálogo, con el nombre que le daría PostgreSQL. NOT NULL y DEFAULT no
+// son constraints del catálogo.
+func columnConstraintSchema(table, column string, constraint *ColumnConstraint) (ConstraintSchema, bool) {
+	base := strings.ReplaceAll(table, ".", "_") + "_"
+	switch constraint.Kind {
+	case "PRIMARY KEY":
+		return ConstraintSchema{Name: base + "pkey", Kind: "PRIMARY KEY", Columns: []string{column}}, true
+	case "UNIQUE":
+		return ConstraintSchema{Name: base + column + "_key", Kind: "UNIQUE", Columns: []string{column}}, true
+	case "CHECK":
+		return ConstraintSchema{Name: base + column + "_check", Kind: "CHECK", Columns: []string{column}}, true
+	case "REFERENCES":
+		return ConstraintSchema{
+			Name:       base + column + "_fkey",
+			Kind:       "FOREIGN KEY",
+			Columns:    []string{column},
+			References: catalogKey(constraint.References.Table.Name),
+			RefColumns: identifierNames(constraint.References.Columns),
+		}, true
+	}
+	return ConstraintSchema{}, false
+}
+
+// tableConstraintSchema convierte un constraint de tabla en uno del
+// catálogo, verificando que sus columnas existan.
+func (c *MemoryCatalog) tableConstraintSchema(table *TableSchema, constraint *TableConstraint) (ConstraintSchema, error) {
+	schema := ConstraintSchema{Name: constraint.Name, Kind: constraint.Kind, Columns: identifierNames(constraint.Columns)}
+	for _, column := range constraint.Columns {
+		if _, ok := table.Column(column.Name); !ok {
+			return schema, newAnalysisError(column.Span, "la columna '%s' del constraint no existe en la tabla '%s'", column.String(), table.Name)
+		}
+	}
+	if constraint.References != nil {
+		schema.References = catalogKey(constraint.References.Table.Name)
+		schema.RefColumns = identifierNames(constraint.References.Columns)
+	}
+
+	if schema.Name == "" {
+		base := strings.ReplaceAll(table.Name, ".", "_")
+		switch constraint.Kind {
+		case "PRIMARY KEY":
+			schema.Name = base + "_pkey"
+		case "UNIQUE":
+			schema.Name = base + "_" + strings.Join(schema.Columns, "_") + "_key"
+		case "FOREIGN KEY":
+			schema.Name = base + "_" + strings.Join(schema.Columns, "_") + "_fkey"
+		default:
+			schema.Name = base + "_check"
+		}
+	}
+	for _, existing := range table.Constraints {
+		if existing.Name == schema.Name {
+			return schema, newAnalysisError(constraint.Span, "ya existe un constraint llamado '%s' en la tabla '%s'", schema.Name, table.Name)
+		}
+	}
+	return schema, nil
+}
+
+// checkReferences verifica que las tablas y columnas referenciadas por las
+// claves foráneas de la tabla existan. Una tabla puede referenciarse a sí
+// misma.
+func (c *MemoryCatalog) checkReferences(table *TableSchema, span Span) error {
+	for _, constraint := range table.Constraints {
+		if constraint.Kind != "FOREIGN KEY" {
+			continue
+		}
+		target := table
+		if constraint.References != table.Name {
+			var ok bool
+			if target, ok = c.table(nameKey(constraint.References)); !ok {
+				return newAnalysisError(span, "la tabla '%s' referenciada por '%s' no existe", constraint.References, constraint.Name)
+			}
+		}
+		for _, column := range constraint.RefColumns {
+			if _, ok := target.Column(column); !ok {
+				return newAnalysisError(span, "la columna '%s' referenciada por '%s' no existe en la tabla '%s'", column, constraint.Name, target.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCatalog) applyCreateIndex(stmt *CreateIndexStmt) error {
+	tableName := stmt.Table.Name
+	table, ok := c.table(catalogKey(tableName))
+	if !ok {
+		return newAnalysisError(tableName.Span, "la tabla '%s' no existe", tableName.String())
+	}
+	columns := stmt.Columns
+	for _, expr := range stmt.Expressions {
+		Walk(expr, func(node Node) bool {
+			if ref, ok := node.(*ColumnRef); ok && !ref.IsStar() {
+				columns = append(columns, ref.Name.Parts[len(ref.Name.Parts)-1])
+			}
+			return true
+		})
+	}
+	for _, column := range columns {
+		if _, ok := table.Column(column.Name); !ok {
+			return newAnalysisError(column.Span, "la columna '%s' del índice no existe en la tabla '%s'", column.String(), tableName.String())
+		}
+	}
+
+	// El índice se crea en el esquema de la tabla
+	name := stmt.Name.Name
+	if schema := tableName.Qualifier(); schema != "" && schema != "public" {
+		name = schema + "." + name
+	}
+	if c.relationExists(name) {
+		if stmt.IfNotExists {
+			return nil
+		}
+		return newAnalysisError(stmt.Name.Span, "ya existe una relación llamada '%s'", stmt.Name.String())
+	}
+	c.AddObject("INDEX", name, table.Name)
+	delete(c.dropped, name)
+
+	// Un índice único sobre columnas sirve como UNIQUE, por ejemplo para
+	// ON CONFLICT, como lo informa PostgresCatalog
+	if stmt.Unique && len(stmt.Expressions) == 0 {
+		constraints := append([]ConstraintSchema(nil), table.Constraints...)
+		constraints = append(constraints, ConstraintSchema{Name: stmt.Name.Name, Kind: "UNIQUE", Columns: identifierNames(stmt.Columns)})
+		c.tables[catalogKey(tableName)] = &TableSchema{Name: table.Name, Columns: table.Columns, Constraints: constraints}
+	}
+	return nil
+}
+
+// applyAlterTable aplica las acciones sobre una copia de la tabla, que
+// reemplaza a la original solo si todas se pudieron aplicar.
+func (c *MemoryCatalog) applyAlterTable(stmt *AlterTableStmt) error {
+	tableName := stmt.Table.Name
+	key := catalogKey(tableName)
+	original, ok := c.table(key)
+	if !ok {
+		if stmt.IfExists {
+			return nil
+		}
+		return newAnalysisError(tableName.Span, "la tabla '%s' no existe", tableName.String())
+	}
+	table := &TableSchema{
+		Name:        original.Name,
+		Columns:     append([]ColumnSchema(nil), original.Columns...),
+		Constraints: append([]ConstraintSchema(nil), original.Constraints...),
+	}
+	renamed := key
+
+	for _, action := range stmt.Actions {
+		index := -1
+		for i, column := range table.Columns {
+			if column.Name == action.Column.Name {
+				index = i
+			}
+		}
+		missing := newAnalysisError(action.Column.Span, "la columna '%s' no existe en la tabla '%s'", action.Column.String(), tableName.String())
+
+		switch action.Kind {
+		case "ADD COLUMN":
+			if index >= 0 {
+				if action.IfExists {
+					continue
+				}
+				return newAnalysisError(action.Column.Span, "la columna '%s' ya existe en la tabla '%s'", action.Column.String(), tableName.String())
+			}
+			def := action.Definition
+			table.Columns = append(table.Columns, ColumnSchema{
+				Name: def.Name.Name, Type: catalogTypeName(def.Type), Nullable: !columnNotNull(def), Length: typeLength(def.Type),
+			})
+			for _, constraint := range def.Constraints {
+				if schema, ok := columnConstraintSchema(table.Name, def.Name.Name, constraint); ok {
+					table.Constraints = append(table.Constraints, schema)
+				}
+			}
+
+		case "DROP COLUMN":
+			if index < 0 {
+				if action.IfExists {
+					continue
+				}
+				return missing
+			}
+			table.Columns = append(table.Columns[:index:index], table.Columns[index+1:]...)
+			// Los constraints que usan la columna se eliminan con ella
+			var kept []ConstraintSchema
+			for _, constraint := range table.Constraints {
+				if !containsName(constraint.Columns, action.Column.Name) {
+					kept = append(kept, constraint)
+				}
+			}
+			table.Constraints = kept
+
+		case "RENAME COLUMN":
+			if index < 0 {
+				return missing
+			}
+			if _, taken := table.Column(action.NewName.Name); taken {
+				return newAnalysisError(action.NewName.Span, "la columna '%s' ya existe en la tabla '%s'", action.NewName.String(), tableName.String())
+			}
+			table.Columns[index].Name = action.NewName.Name
+			for i, constraint := range table.Constraints {
+				columns := append([]string(nil), constraint.Columns...)
+				for j, column := range columns {
+					if column == action.Column.Name {
+						columns[j] = action.NewName.Name
+					}
+				}
+				table.Constraints[i].Columns = columns
+			}
+
+		case "ALTER COLUMN TYPE":
+			if index < 0 {
+				return missing
+			}
+			table.Columns[index].Type = catalogTypeName(action.Type)
+			table.Columns[index].Length = typeLength(action.Type)
+
+		case "SET NOT NULL", "DROP NOT NULL", "SET DEFAULT", "DROP DEFAULT":
+			if index < 0 {
+				return missing
+			}
+			switch action.Kind {
+			case "SET NOT NULL":
+				table.Columns[index].Nullable = false
+			case "DROP NOT NULL":
+				table.Columns[index].Nullable = true
+			}
+
+		case "ADD CONSTRAINT":
+			schema, err := c.tableConstraintSchema(table, action.Constraint)
+			if err != nil {
+				return err
+			}
+			table.Constraints = append(table.Constraints, schema)
+			if schema.Kind == "PRIMARY KEY" {
+				table.setNotNull(schema.Columns)
+			}
+
+		case "DROP CONSTRAINT":
+			var kept []ConstraintSchema
+			for _, constraint := range table.Constraints {
+				if constraint.Name != action.Column.Name {
+					kept = append(kept, constraint)
+				}
+			}
+			if len(kept) == len(table.Constraints) && !action.IfExists {
+				return newAnalysisError(action.Column.Span, "la tabla '%s' no tiene un constraint llamado '%s'", tableName.String(), action.Column.String())
+			}
+			table.Constraints = kept
+
+		case "RENAME CONSTRAINT":
+			found := false
+			for i, constraint := range table.Constraints {
+				if constraint.Name == action.Column.Name {
+					table.Constraints[i].Name = action.NewName.Name
+					found = true
+				}
+			}
+			if !found {
+				return newAnalysisError(action.Column.Span, "la tabla '%s' no tiene un constraint llamado '%s'", tableName.String(), action.Column.String())
+			}
+
+		case "RENAME TO":
+			renamed = action.NewName.Name
+			if schema := tableName.Qualifier(); schema != "" && schema != "public" {
+				renamed = schema + "." + renamed
+			}
+			if c.relationExists(renamed) {
+				return newAnalysisError(action.NewName.Span, "ya existe una tabla llamada '%s'", action.NewName.String())
+			}
+			table.Name = renamed
+		}
+	}
+	if err := c.checkReferences(table, stmt.Span); err != nil {
+		return err
+	}
+
+	if renamed != key {
+		references := c.Dependents("TABLE", keyName(key))
+		c.remove(key)
+		c.renameReferences(key, renamed, references)
+		// Una clave foránea de la tabla hacia sí misma también cambia
+		for i, constraint := range table.Constraints {
+			if nameKey(constraint.References) == key {
+				table.Constraints[i].References = renamed
+			}
+		}
+	}
+	c.tables[renamed] = table
+	delete(c.dropped, renamed)
+	return nil
+}
+
+// renameReferences actualiza las claves foráneas y dependencias que
+// apuntan a una tabla renombrada. references son las claves foráneas que
+// la referenciaban, buscadas antes de renombrarla.
+func (c *MemoryCatalog) renameReferences(from, to string, references []DependentObject) {
+	for _, reference := range references {
+		key := nameKey(reference.Table)
+		table, ok := c.table(key)
+		if !ok {
+			continue
+		}
+		constraints := append([]ConstraintSchema(nil), table.Constraints...)
+		for i, constraint := range constraints {
+			if constraint.Name == reference.Name {
+				constraints[i].References = to
+			}
+		}
+		c.tables[key] = &TableSchema{Name: table.Name, Columns: table.Columns, Constraints: constraints}
+	}
+	for key, object := range c.objects {
+		for i, dependency := range object.dependsOn {
+			if dependency == from {
+				object.dependsOn = append([]string(nil), object.dependsOn...)
+				object.dependsOn[i] = to
+				c.objects[key] = object
+			}
+		}
+	}
+}
+
+// applyDrop elimina los objetos. Sin CASCADE, un objeto del que dependen
+// otros no se puede eliminar; con CASCADE se eliminan también las vistas e
+// índices que dependen de él y las claves foráneas que lo referencian.
+func (c *MemoryCatalog) applyDrop(stmt *DropStmt) error {
+	if stmt.ObjectType == "DATABASE" {
+		return nil
+	}
+	names := map[string]bool{}
+	for _, name := range stmt.Names {
+		names[catalogKey(name)] = true
+	}
+
+	for _, name := range stmt.Names {
+		if !c.ObjectExists(stmt.ObjectType, name) {
+			if stmt.IfExists {
+				delete(names, catalogKey(name))
+				continue
+			}
+			return newAnalysisError(name.Span, "%s '%s' no existe", capitalize(objectLabels[stmt.ObjectType]), name.String())
+		}
+		if stmt.Behavior == "CASCADE" {
+			continue
+		}
+		var dependents []string
+		for _, dependent := range c.Dependents(stmt.ObjectType, name) {
+			if names[nameKey(dependent.Name)] || dependent.Kind == "FOREIGN KEY" && names[nameKey(dependent.Table)] {
+				continue
+			}
+			dependents = append(dependents, dependent.String())
+		}
+		if len(dependents) > 0 {
+			return newAnalysisError(name.Span, "hay objetos que dependen de %s '%s' (%s): use CASCADE para eliminarlos también",
+				objectLabels[stmt.ObjectType], name.String(), strings.Join(dependents, ", "))
+		}
+	}
+
+	for _, name := range stmt.Names {
+		if names[catalogKey(name)] {
+			c.drop(stmt.ObjectType, catalogKey(name))
+		}
+	}
+	return nil
+}
+
+// drop elimina un objeto y lo que depende de él: las vistas, los índices y
+// las claves foráneas que lo referencian o, si es un esquema, lo que
+// contiene.
+func (c *MemoryCatalog) drop(objectType, key string) {
+	for _, dependent := range c.Dependents(objectType, keyName(key)) {
+		if dependent.Kind == "FOREIGN KEY" {
+			c.dropConstraint(nameKey(dependent.Table), dependent.Name)
+		} else {
+			c.drop(dependent.Kind, nameKey(dependent.Name))
+		}
+	}
+	for other, object := range c.objects {
+		if object.objectType == "INDEX" && containsName(object.dependsOn, key) {
+			c.remove(other)
+		}
+	}
+	if object, ok := c.objects[key]; ok && objectType == "INDEX" && len(object.dependsOn) > 0 {
+		// El UNIQUE de un índice único desaparece con él
+		c.dropConstraint(object.dependsOn[0], keyName(key).Name())
+	}
+	c.remove(key)
+}
+
+// dropConstraint elimina un constraint de una tabla.
+func (c *MemoryCatalog) dropConstraint(tableKey, name string) {
+	table, ok := c.table(tableKey)
+	if !ok {
+		return
+	}
+	var kept []ConstraintSchema
+	for _, constraint := range table.Constraints {
+		if constraint.Name != name {
+			kept = append(kept, constraint)
+		}
+	}
+	c.tables[tableKey] = &TableSchema{Name: table.Name, Columns: table.Columns, Constraints: kept}
+}
+
+// setNotNull marca las columnas como NOT NULL, como hace una PRIMARY KEY.
+func (t *TableSchema) setNotNull(columns []string) {
+	for i, column := range t.Columns {
+		if containsName(columns, column.Name) {
+			t.Columns[i].Nullable = false
+		}
+	}
+}
+
+// identifierNames devuelve los nombres de una lista de identificadores.
+func identifierNames(ids []Identifier) []string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = id.Name
+	}
+	return names
+}
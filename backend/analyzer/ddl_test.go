@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeMigrations escribe los archivos de un directorio de migraciones.
+func writeMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadCatalogDir(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"001_init.sql": `
+			CREATE EXTENSION IF NOT EXISTS pgcrypto;
+			CREATE SCHEMA ventas;
+			CREATE SEQUENCE numero_factura;
+			CREATE TABLE clientes (
+			    id SERIAL PRIMARY KEY,
+			    nombre varchar NOT NULL,
+			    pais char,
+			    alta timestamptz DEFAULT now()
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS clientes_nombre ON clientes USING btree (nombre);
+			CREATE INDEX clientes_lower ON clientes (lower(nombre));`,
+		"002_facturas.sql": `
+			CREATE TABLE facturas (
+			    id INT8 PRIMARY KEY,
+			    cliente_id INTEGER REFERENCES clientes (id) ON DELETE CASCADE ON UPDATE NO ACTION,
+			    total DOUBLE PRECISION,
+			    emitida TIMESTAMP WITH TIME ZONE,
+			    nota character varying
+			);
+			CREATE FUNCTION total_cliente(integer) RETURNS numeric AS $$
+			    SELECT sum(total) FROM facturas WHERE cliente_id = $1;
+			$$ LANGUAGE sql;
+			CREATE VIEW resumen (cliente, facturado) AS
+			    SELECT c.nombre, sum(f.total) FROM clientes c JOIN facturas f ON f.cliente_id = c.id GROUP BY c.nombre;
+			GRANT SELECT ON facturas TO lectura;
+			ALTER TABLE facturas ALTER COLUMN nota TYPE varchar;`,
+	})
+	catalog, warnings, err := LoadCatalogDir(dir)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(warnings) != 6 {
+		t.Errorf("%d avisos, se esperaban 6: %q", len(warnings), warnings)
+	}
+	for _, warning := range warnings {
+		if !strings.HasPrefix(warning, dir) {
+			t.Errorf("el aviso %q no indica el archivo", warning)
+		}
+	}
+
+	for _, test := range []semanticTest{
+		{query: "SELECT nombre, pais, alta FROM clientes", valid: true},
+		{query: "SELECT cliente, facturado FROM resumen", valid: true},
+		{query: "SELECT nombre FROM resumen", error: "'nombre' no existe"},
+		{query: "INSERT INTO clientes (nombre, pais) VALUES ('un nombre largo sin límite', 'A')", valid: true},
+		{query: "INSERT INTO clientes (nombre, pais) VALUES ('Ana', 'AR')", error: "es de tipo character(1)"},
+		{query: "INSERT INTO clientes (nombre) VALUES ('Ana') ON CONFLICT (nombre) DO NOTHING", valid: true},
+		{query: "SELECT * FROM facturas WHERE emitida > '2024-01-01 10:00-03'", valid: true},
+		{query: "DROP VIEW resumen", valid: true},
+		{query: "DROP SEQUENCE numero_factura", valid: true},
+		{query: "DROP SCHEMA ventas", valid: true},
+		{query: "DROP TABLE clientes", error: "facturas"},
+	} {
+		t.Run(test.query, func(t *testing.T) {
+			info, err := SemanticAnalysisWithCatalog(test.query, catalog)
+			if err != nil {
+				t.Fatalf("error sintáctico: %v", err)
+			}
+			checkSemanticInfo(t, info, test.valid, test.error)
+		})
+	}
+}
+
+func TestLoadCatalogDirErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		error string
+	}{
+		{
+			name:  "tabla que no se puede analizar",
+			files: map[string]string{"001.sql": "CREATE TABLE t (id INTEGER, x FOO);"},
+			error: "001.sql",
+		},
+		{
+			name:  "ALTER de una tabla que no existe",
+			files: map[string]string{"001.sql": "ALTER TABLE t ADD COLUMN x INTEGER;"},
+			error: "no existe",
+		},
+		{
+			name: "migraciones aplicadas en orden",
+			files: map[string]string{
+				"001.sql": "CREATE TABLE t (id INTEGER);",
+				"002.sql": "DROP TABLE t;",
+				"003.sql": "ALTER TABLE t ADD COLUMN x INTEGER;",
+			},
+			error: "003.sql",
+		},
+		{
+			name:  "tabla duplicada",
+			files: map[string]string{"001.sql": "CREATE TABLE t (id INTEGER); CREATE TABLE t (id INTEGER);"},
+			error: "ya existe",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := LoadCatalogDir(writeMigrations(t, test.files))
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if !strings.Contains(err.Error(), test.error) {
+				t.Errorf("error %q, se esperaba que contenga %q", err, test.error)
+			}
+		})
+	}
+}
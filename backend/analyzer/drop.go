@@ -0,0 +1,85 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DependentObject es un objeto que depende de otro que la sentencia elimina
+// y que DROP ... CASCADE eliminaría también. Table es la tabla que define
+// una clave foránea.
+type DependentObject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Table     string `json:"table,omitempty"`
+	DependsOn string `json:"dependsOn"`
+}
+
+// String describe el objeto para los mensajes: "la vista 'v'", "la clave
+// foránea 'fk' de 'pedidos'".
+func (d DependentObject) String() string {
+	if d.Table != "" {
+		return fmt.Sprintf("%s '%s' de '%s'", objectLabels[d.Kind], d.Name, d.Table)
+	}
+	return fmt.Sprintf("%s '%s'", objectLabels[d.Kind], d.Name)
+}
+
+// objectLabels nombra cada tipo de objeto en los mensajes.
+var objectLabels = map[string]string{
+	"TABLE": "la tabla", "VIEW": "la vista", "MATERIALIZED VIEW": "la vista materializada",
+	"INDEX": "el índice", "SEQUENCE": "la secuencia", "SCHEMA": "el esquema",
+	"FOREIGN KEY": "la clave foránea",
+}
+
+// checkDrop verifica que los objetos de un DROP existan y busca los objetos
+// que dependen de ellos: con CASCADE se listan en Dependents y se avisa que
+// también se eliminarán; sin CASCADE el DROP fallaría y se informa un error.
+func (info *SemanticInfo) checkDrop(stmt *DropStmt) {
+	if stmt.ObjectType == "DATABASE" {
+		return
+	}
+	dropped := map[string]bool{}
+	for _, name := range stmt.Names {
+		dropped[name.String()] = true
+	}
+
+	label := objectLabels[stmt.ObjectType]
+	for _, name := range stmt.Names {
+		if !info.catalog.ObjectExists(stmt.ObjectType, name) {
+			switch {
+			case stmt.IfExists:
+				info.addWarning(name.Span, "%s '%s' no existe y se omitirá (IF EXISTS)", capitalize(label), name.String())
+			case stmt.ObjectType != "TABLE":
+				// Las tablas inexistentes ya se informan al verificar las tablas
+				info.addError(name.Span, "%s '%s' no existe", capitalize(label), name.String())
+			}
+			continue
+		}
+
+		var dependents []string
+		for _, dependent := range info.catalog.Dependents(stmt.ObjectType, name) {
+			if dependent.Kind != "FOREIGN KEY" && dropped[dependent.Name] {
+				continue
+			}
+			if dependent.Kind == "FOREIGN KEY" && dropped[dependent.Table] {
+				continue
+			}
+			info.Dependents = append(info.Dependents, dependent)
+			dependents = append(dependents, dependent.String())
+		}
+		if len(dependents) == 0 {
+			continue
+		}
+		if stmt.Behavior == "CASCADE" {
+			info.addWarning(name.Span, "DROP %s CASCADE de '%s' también eliminará %s",
+				stmt.ObjectType, name.String(), strings.Join(dependents, ", "))
+		} else {
+			info.addError(name.Span, "hay objetos que dependen de %s '%s' (%s): use CASCADE para eliminarlos también",
+				label, name.String(), strings.Join(dependents, ", "))
+		}
+	}
+}
+
+func capitalize(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
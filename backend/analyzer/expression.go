@@ -0,0 +1,601 @@
+package analyzer
+
+import "strings"
+
+// Análisis de expresiones por precedencia de operadores ("precedence
+// climbing"). Los niveles siguen a PostgreSQL, de menor a mayor:
+//
+//	OR
+//	AND
+//	NOT (prefijo)
+//	IS [NOT] NULL | TRUE | FALSE | UNKNOWN | DISTINCT FROM
+//	< > = <= >= <> !=
+//	[NOT] IN, [NOT] BETWEEN, [NOT] LIKE | ILIKE
+//	|| y demás operadores
+//	+ -
+//	* / %
+//	^
+//	signo (prefijo)
+//	:: (conversión de tipo)
+const (
+	precOr = iota + 1
+	precAnd
+	precNot
+	precIs
+	precComparison
+	precLike
+	precOther
+	precAdditive
+	precMultiplicative
+	precExponent
+	precUnary
+)
+
+// binaryPrecedence es la precedencia de los operadores binarios simbólicos.
+var binaryPrecedence = map[string]int{
+	"=": precComparison, "<>": precComparison, "!=": precComparison,
+	"<": precComparison, ">": precComparison, "<=": precComparison, ">=": precComparison,
+	"||": precOther,
+	"+":  precAdditive, "-": precAdditive,
+	"*": precMultiplicative, "/": precMultiplicative, "%": precMultiplicative,
+	"^": precExponent,
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseBinary(precOr)
+}
+
+// parseBinary lee una expresión cuyos operadores tengan al menos la
+// precedencia minPrec.
+func (p *parser) parseBinary(minPrec int) (Expr, error) {
+	left, err := p.parsePrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, prec := p.peekOperator()
+		if op == "" || prec < minPrec {
+			return left, nil
+		}
+
+		switch op {
+		case "IS":
+			left, err = p.parseIs(left)
+		case "IN", "NOT IN":
+			left, err = p.parseIn(left, op == "NOT IN")
+		case "BETWEEN", "NOT BETWEEN":
+			left, err = p.parseBetween(left, op == "NOT BETWEEN")
+		case "LIKE", "ILIKE", "NOT LIKE", "NOT ILIKE":
+			left, err = p.parseLike(left, op)
+		default:
+			p.next()
+			if prec == precComparison && p.isQuantifier() {
+				left, err = p.parseQuantified(op, left)
+				break
+			}
+			var right Expr
+			if right, err = p.parseOperand(op, prec+1); err != nil {
+				return nil, err
+			}
+			if prec == precComparison {
+				// Las comparaciones no son asociativas: a = b = c es un error
+				if next, nextPrec := p.peekOperator(); nextPrec == precComparison {
+					return nil, p.errorf("no se pueden encadenar comparaciones: '%s' después de '%s'", next, op)
+				}
+			}
+			left = newBinary(op, left, right)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// peekOperator identifica el operador binario o posfijo en la posición
+// actual y su precedencia. Devuelve "" si no hay ninguno.
+func (p *parser) peekOperator() (string, int) {
+	tok := p.peek()
+	if tok.Type == "OPERADOR" {
+		if prec, ok := binaryPrecedence[tok.Value]; ok {
+			return tok.Value, prec
+		}
+		return "", 0
+	}
+
+	switch {
+	case isWord(tok, "OR"):
+		return "OR", precOr
+	case isWord(tok, "AND"):
+		return "AND", precAnd
+	case isWord(tok, "IS"):
+		return "IS", precIs
+	case isWord(tok, "IN"), isWord(tok, "BETWEEN"), isWord(tok, "LIKE"), isWord(tok, "ILIKE"):
+		return strings.ToUpper(tok.Value), precLike
+	case isWord(tok, "NOT"):
+		// NOT solo es operador binario delante de IN, BETWEEN o LIKE
+		for _, word := range []string{"IN", "BETWEEN", "LIKE", "ILIKE"} {
+			if isWord(p.peekAt(1), word) {
+				return "NOT " + word, precLike
+			}
+		}
+	}
+	return "", 0
+}
+
+// parseOperand lee el operando derecho de op con un error que nombra al
+// operador cuando el operando falta.
+func (p *parser) parseOperand(op string, minPrec int) (Expr, error) {
+	if p.atEnd() || p.is(")") || p.is(",") || p.is(";") {
+		return nil, p.errorf("se esperaba una expresión después de '%s', se encontró '%s'", op, p.current())
+	}
+	return p.parseBinary(minPrec)
+}
+
+// parsePrefix lee los operadores prefijos NOT, + y - y su operando.
+func (p *parser) parsePrefix() (Expr, error) {
+	start := p.pos
+
+	var op string
+	var prec int
+	switch {
+	case p.isKeyword("NOT"):
+		op, prec = "NOT", precNot
+	case p.is("-") || p.is("+"):
+		op, prec = p.peek().Value, precUnary
+	default:
+		return p.parsePostfix()
+	}
+
+	p.next()
+	operand, err := p.parseOperand(op, prec)
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryExpr{Op: op, Operand: operand, Span: p.spanFrom(start)}, nil
+}
+
+// parsePostfix lee una expresión primaria seguida de conversiones "::tipo".
+func (p *parser) parsePostfix() (Expr, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("::") {
+		dataType, err := p.parseCastType("'::'")
+		if err != nil {
+			return nil, err
+		}
+		expr = &CastExpr{Expr: expr, Type: dataType, Span: expr.Location().cover(dataType.Span)}
+	}
+	return expr, nil
+}
+
+// parseCastType lee el tipo de destino de una conversión, que debe ser uno
+// de los tipos aceptados en CREATE TABLE.
+func (p *parser) parseCastType(after string) (*DataType, error) {
+	if !p.isIdentifier() && p.peek().Type != "PALABRA_CLAVE" {
+		return nil, p.errorf("se esperaba un tipo de dato después de %s, se encontró '%s'", after, p.current())
+	}
+	return p.parseDataType()
+}
+
+// parseCase lee una expresión CASE simple (CASE expr WHEN valor THEN ...) o
+// con búsqueda (CASE WHEN condición THEN ...), con ELSE opcional.
+func (p *parser) parseCase() (Expr, error) {
+	start := p.pos
+	p.next() // CASE
+	expr := &CaseExpr{}
+	if !p.isKeyword("WHEN") && !p.isKeyword("END") {
+		operand, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		expr.Operand = operand
+	}
+	if !p.isKeyword("WHEN") {
+		return nil, p.errorf("se esperaba WHEN en CASE, se encontró '%s'", p.current())
+	}
+
+	for p.isKeyword("WHEN") {
+		whenStart := p.pos
+		p.next()
+		cond, err := p.parseCondition("WHEN")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("THEN", "después de la condición de WHEN"); err != nil {
+			return nil, err
+		}
+		if p.isKeyword("WHEN") || p.isKeyword("ELSE") || p.isKeyword("END") {
+			return nil, p.errorf("se esperaba un resultado después de THEN, se encontró '%s'", p.current())
+		}
+		result, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		expr.Whens = append(expr.Whens, &WhenClause{Cond: cond, Result: result, Span: p.spanFrom(whenStart)})
+	}
+
+	if p.acceptKeyword("ELSE") {
+		if p.isKeyword("END") {
+			return nil, p.errorf("se esperaba un resultado después de ELSE, se encontró 'END'")
+		}
+		result, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		expr.Else = result
+	}
+	if err := p.expectKeyword("END", "para cerrar CASE"); err != nil {
+		return nil, err
+	}
+	expr.Span = p.spanFrom(start)
+	return expr, nil
+}
+
+// parseIs lee "IS [NOT] NULL|TRUE|FALSE|UNKNOWN" o
+// "IS [NOT] DISTINCT FROM expr".
+func (p *parser) parseIs(left Expr) (Expr, error) {
+	p.next() // IS
+	not := p.acceptKeyword("NOT")
+
+	if p.acceptKeyword("DISTINCT") {
+		if err := p.expectKeyword("FROM", "después de IS DISTINCT"); err != nil {
+			return nil, err
+		}
+		op := negated("DISTINCT FROM", not)
+		right, err := p.parseOperand("IS "+op, precIs+1)
+		if err != nil {
+			return nil, err
+		}
+		return newBinary("IS "+op, left, right), nil
+	}
+
+	for _, value := range []string{"NULL", "TRUE", "FALSE", "UNKNOWN"} {
+		if p.acceptKeyword(value) {
+			return &IsExpr{Expr: left, Not: not, Value: value, Span: left.Location().cover(p.tokens[p.pos-1].Span)}, nil
+		}
+	}
+	return nil, p.errorf("se esperaba NULL, TRUE, FALSE, UNKNOWN o DISTINCT FROM después de IS, se encontró '%s'", p.current())
+}
+
+// parseIn lee "[NOT] IN (a, b, ...)".
+func (p *parser) parseIn(left Expr, not bool) (Expr, error) {
+	op := negated("IN", not)
+	if not {
+		p.next()
+	}
+	p.next() // IN
+
+	if p.isSubquery() {
+		query, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		return &InExpr{Expr: left, Not: not, Subquery: query, Span: left.Location().cover(p.tokens[p.pos-1].Span)}, nil
+	}
+
+	if err := p.expect("(", "después de "+op); err != nil {
+		return nil, err
+	}
+	if p.is(")") {
+		return nil, p.errorf("la lista de %s no puede estar vacía", op)
+	}
+	list, err := p.parseExprList(op)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")", "para cerrar la lista de "+op); err != nil {
+		return nil, err
+	}
+	return &InExpr{Expr: left, Not: not, List: list, Span: left.Location().cover(p.tokens[p.pos-1].Span)}, nil
+}
+
+// isQuantifier indica si después de un operador de comparación sigue
+// ANY, SOME o ALL con paréntesis.
+func (p *parser) isQuantifier() bool {
+	return (p.isKeyword("ANY") || p.isKeyword("SOME") || p.isKeyword("ALL")) &&
+		p.peekAt(1).Type == "DELIMITADOR" && p.peekAt(1).Value == "("
+}
+
+// parseQuantified lee "ANY|SOME|ALL (subconsulta)" o "ANY|SOME|ALL
+// (arreglo)" a la derecha de una comparación ya consumida.
+func (p *parser) parseQuantified(op string, left Expr) (Expr, error) {
+	expr := &QuantifiedExpr{Op: op, Quantifier: strings.ToUpper(p.next().Value), Left: left}
+	var err error
+	if p.isSubquery() {
+		if expr.Query, err = p.parseSubquery(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.next() // (
+		if expr.Array, err = p.parseOperand(op+" "+expr.Quantifier, precOr); err != nil {
+			return nil, err
+		}
+		if err := p.expect(")", "para cerrar "+expr.Quantifier); err != nil {
+			return nil, err
+		}
+	}
+	expr.Span = left.Location().cover(p.tokens[p.pos-1].Span)
+	return expr, nil
+}
+
+// parseBetween lee "[NOT] BETWEEN low AND high". Los límites no pueden
+// contener AND ni OR sin paréntesis.
+func (p *parser) parseBetween(left Expr, not bool) (Expr, error) {
+	op := negated("BETWEEN", not)
+	if not {
+		p.next()
+	}
+	p.next() // BETWEEN
+
+	low, err := p.parseOperand(op, precLike+1)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("AND", "en "+op); err != nil {
+		return nil, err
+	}
+	high, err := p.parseOperand(op+" ... AND", precLike+1)
+	if err != nil {
+		return nil, err
+	}
+	return &BetweenExpr{Expr: left, Not: not, Low: low, High: high, Span: left.Location().cover(high.Location())}, nil
+}
+
+// parseLike lee "[NOT] LIKE|ILIKE patrón [ESCAPE carácter]".
+func (p *parser) parseLike(left Expr, op string) (Expr, error) {
+	not := strings.HasPrefix(op, "NOT ")
+	if not {
+		p.next()
+	}
+	p.next() // LIKE / ILIKE
+
+	like := &LikeExpr{Expr: left, Not: not, Op: strings.TrimPrefix(op, "NOT ")}
+	var err error
+	if like.Pattern, err = p.parseOperand(op, precLike+1); err != nil {
+		return nil, err
+	}
+	like.Span = left.Location().cover(like.Pattern.Location())
+
+	if p.acceptKeyword("ESCAPE") {
+		if like.Escape, err = p.parseOperand("ESCAPE", precLike+1); err != nil {
+			return nil, err
+		}
+		like.Span = like.Span.cover(like.Escape.Location())
+	}
+	return like, nil
+}
+
+// niladicFunctions son las funciones de SQL que se escriben sin paréntesis.
+var niladicFunctions = map[string]bool{
+	"CURRENT_DATE": true, "CURRENT_TIME": true, "CURRENT_TIMESTAMP": true,
+	"LOCALTIME": true, "LOCALTIMESTAMP": true,
+	"CURRENT_USER": true, "SESSION_USER": true, "USER": true,
+}
+
+// keywordFunctions son palabras clave que también son nombres de función,
+// como LEFT(texto, n).
+var keywordFunctions = map[string]bool{"LEFT": true, "RIGHT": true}
+
+// parsePrimary lee un literal, una columna, una llamada a función o una
+// expresión entre paréntesis.
+func (p *parser) parsePrimary() (Expr, error) {
+	start := p.pos
+	tok := p.peek()
+
+	switch {
+	case tok.Type == "NUMERO":
+		p.next()
+		return &Literal{Kind: "NUMERO", Value: tok.Value, Span: tok.Span}, nil
+
+	case tok.Type == "CADENA":
+		p.next()
+		return &Literal{Kind: "CADENA", Value: tok.Value, Text: tok.Literal, Span: tok.Span}, nil
+
+	case isWord(tok, "NULL"):
+		p.next()
+		return &Literal{Kind: "NULL", Value: strings.ToUpper(tok.Value), Span: tok.Span}, nil
+
+	case isWord(tok, "TRUE") || isWord(tok, "FALSE"):
+		p.next()
+		return &Literal{Kind: "BOOLEANO", Value: strings.ToUpper(tok.Value), Span: tok.Span}, nil
+
+	case p.isTypedLiteral():
+		// DATE '2024-01-31' equivale a CAST('2024-01-31' AS DATE)
+		dataType, err := p.parseDataType()
+		if err != nil {
+			return nil, err
+		}
+		value := p.next()
+		literal := &Literal{Kind: "CADENA", Value: value.Value, Text: value.Literal, Span: value.Span}
+		return &CastExpr{Expr: literal, Type: dataType, Span: p.spanFrom(start)}, nil
+
+	case p.isSubquery():
+		query, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		return &SubqueryExpr{Query: query, Span: p.spanFrom(start)}, nil
+
+	case isWord(tok, "EXISTS"):
+		p.next()
+		if !p.isSubquery() {
+			return nil, p.errorf("se esperaba una subconsulta entre paréntesis después de EXISTS")
+		}
+		query, err := p.parseSubquery()
+		if err != nil {
+			return nil, err
+		}
+		return &ExistsExpr{Query: query, Span: p.spanFrom(start)}, nil
+
+	case p.is("("):
+		p.next()
+		if p.is(")") {
+			return nil, p.errorf("se esperaba una expresión entre los paréntesis")
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")", "para cerrar la expresión"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+
+	case isWord(tok, "CASE"):
+		return p.parseCase()
+
+	case isWord(tok, "CAST") && p.peekAt(1).Value == "(":
+		p.pos += 2
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("AS", "en CAST antes del tipo de dato"); err != nil {
+			return nil, err
+		}
+		dataType, err := p.parseCastType("AS")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")", "para cerrar CAST"); err != nil {
+			return nil, err
+		}
+		return &CastExpr{Expr: expr, Type: dataType, Span: p.spanFrom(start)}, nil
+
+	case tok.Type == "PALABRA_CLAVE" && keywordFunctions[strings.ToUpper(tok.Value)] && p.peekAt(1).Value == "(":
+		p.next()
+		return p.parseFuncCall(QualifiedName{Parts: []Identifier{identifierFromToken(tok)}, Span: tok.Span}, start)
+
+	case tok.Type == "IDENTIFICADOR":
+		name, err := p.parseQualifiedName("una expresión", true)
+		if err != nil {
+			return nil, err
+		}
+		if name.Name() != "*" && p.is("(") {
+			return p.parseFuncCall(name, start)
+		}
+		if len(name.Parts) == 1 && !name.Parts[0].Quoted && niladicFunctions[strings.ToUpper(name.Name())] {
+			return &FuncCall{Name: name, NoParens: true, Span: name.Span}, nil
+		}
+		return &ColumnRef{Name: name, Span: name.Span}, nil
+	}
+
+	if p.atEnd() {
+		return nil, p.errorf("se esperaba una expresión al final de la query")
+	}
+	return nil, p.errorf("se esperaba una expresión, se encontró '%s'", tok.Value)
+}
+
+// isTypedLiteral indica si en la posición actual hay un literal con tipo:
+// un nombre de tipo seguido de una cadena, como TIMESTAMP '2024-01-01 10:00'.
+func (p *parser) isTypedLiteral() bool {
+	tok := p.peek()
+	if tok.Type != "IDENTIFICADOR" && tok.Type != "PALABRA_CLAVE" || strings.HasPrefix(tok.Value, `"`) {
+		return false
+	}
+	start := p.pos
+	defer func() { p.pos = start }()
+	_, err := p.parseDataType()
+	return err == nil && p.peek().Type == "CADENA"
+}
+
+// parseFuncCall lee los argumentos de una llamada a función; el nombre ya
+// fue leído.
+func (p *parser) parseFuncCall(name QualifiedName, start int) (Expr, error) {
+	call := &FuncCall{Name: name}
+	p.next() // (
+
+	switch {
+	case len(name.Parts) == 1 && strings.EqualFold(name.Name(), "EXTRACT"):
+		if err := p.parseExtract(call); err != nil {
+			return nil, err
+		}
+	case p.accept("*"):
+		call.Star = true
+	case !p.is(")"):
+		call.Distinct = p.acceptKeyword("DISTINCT")
+		if !call.Distinct {
+			p.acceptKeyword("ALL")
+		}
+		args, err := p.parseExprList("los argumentos de " + name.String())
+		if err != nil {
+			return nil, err
+		}
+		call.Args = args
+		if p.acceptKeyword("ORDER") {
+			if err := p.expectKeyword("BY", "después de ORDER"); err != nil {
+				return nil, err
+			}
+			if call.OrderBy, err = p.parseOrderBy(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := p.expect(")", "para cerrar la función "+name.String()); err != nil {
+		return nil, err
+	}
+
+	// FILTER (WHERE condición) de los agregados
+	if p.isKeyword("FILTER") && p.peekAt(1).Value == "(" {
+		p.pos += 2
+		if err := p.expectKeyword("WHERE", "en FILTER"); err != nil {
+			return nil, err
+		}
+		filter, err := p.parseCondition("FILTER")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")", "para cerrar FILTER"); err != nil {
+			return nil, err
+		}
+		call.Filter = filter
+	}
+
+	// OVER nombre u OVER (definición) de las funciones de ventana
+	if p.isKeyword("OVER") && (p.peekAt(1).Value == "(" || p.peekAt(1).Type == "IDENTIFICADOR") {
+		p.next()
+		if p.isIdentifier() {
+			base := identifierFromToken(p.next())
+			call.Over = &WindowSpec{Base: base, Span: base.Span}
+		} else {
+			over, err := p.parseWindowSpec()
+			if err != nil {
+				return nil, err
+			}
+			call.Over = over
+		}
+	}
+
+	call.Span = p.spanFrom(start)
+	return call, nil
+}
+
+// parseExtract lee los argumentos de EXTRACT(campo FROM expr), que se
+// representan como DATE_PART('campo', expr).
+func (p *parser) parseExtract(call *FuncCall) error {
+	if !p.isIdentifier() && p.peek().Type != "CADENA" {
+		return p.errorf("se esperaba el campo a extraer (YEAR, MONTH, DAY...), se encontró '%s'", p.current())
+	}
+	tok := p.next()
+	field := strings.ToLower(tok.Value)
+	if tok.Type == "CADENA" {
+		field = tok.Literal
+	}
+	if err := p.expectKeyword("FROM", "después del campo de EXTRACT"); err != nil {
+		return err
+	}
+	source, err := p.parseOperand("EXTRACT(... FROM", precOr)
+	if err != nil {
+		return err
+	}
+	call.Args = []Expr{&Literal{Kind: "CADENA", Value: "'" + field + "'", Text: field, Span: tok.Span}, source}
+	return nil
+}
+
+func newBinary(op string, left, right Expr) *BinaryExpr {
+	return &BinaryExpr{Op: op, Left: left, Right: right, Span: left.Location().cover(right.Location())}
+}
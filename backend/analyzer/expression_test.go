@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// render escribe una expresión con paréntesis explícitos alrededor de cada
+// operación, para comprobar la precedencia con que se agrupó.
+func render(expr Expr) string {
+	not := func(negated bool) string {
+		if negated {
+			return "NOT "
+		}
+		return ""
+	}
+	switch e := expr.(type) {
+	case *Literal:
+		return e.Value
+	case *ColumnRef:
+		return e.Name.String()
+	case *BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", render(e.Left), e.Op, render(e.Right))
+	case *UnaryExpr:
+		return fmt.Sprintf("(%s %s)", e.Op, render(e.Operand))
+	case *IsExpr:
+		return fmt.Sprintf("(%s IS %s%s)", render(e.Expr), not(e.Not), e.Value)
+	case *BetweenExpr:
+		return fmt.Sprintf("(%s %sBETWEEN %s AND %s)", render(e.Expr), not(e.Not), render(e.Low), render(e.High))
+	case *LikeExpr:
+		return fmt.Sprintf("(%s %s%s %s)", render(e.Expr), not(e.Not), e.Op, render(e.Pattern))
+	case *InExpr:
+		items := make([]string, len(e.List))
+		for i, item := range e.List {
+			items[i] = render(item)
+		}
+		return fmt.Sprintf("(%s %sIN (%s))", render(e.Expr), not(e.Not), strings.Join(items, ", "))
+	case *CastExpr:
+		return fmt.Sprintf("(%s::%s)", render(e.Expr), e.Type.Name)
+	case *FuncCall:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = render(arg)
+		}
+		return fmt.Sprintf("%s(%s)", e.Name.String(), strings.Join(args, ", "))
+	}
+	return fmt.Sprintf("<%T>", expr)
+}
+
+// parseWhere devuelve la condición WHERE de "SELECT * FROM t WHERE cond".
+func parseWhere(t *testing.T, cond string) (Expr, error) {
+	t.Helper()
+	stmt, err := SyntacticAnalysis("SELECT * FROM t WHERE " + cond)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.(*SelectStmt).Where, nil
+}
+
+func TestExpressionPrecedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"a + b * c", "(a + (b * c))"},
+		{"(a + b) * c", "((a + b) * c)"},
+		{"a - b - c", "((a - b) - c)"},
+		{"a / b % c", "((a / b) % c)"},
+		{"a ^ b ^ c", "((a ^ b) ^ c)"},
+		{"-a * b", "((- a) * b)"},
+		{"a = 1 OR b = 2 AND c = 3", "((a = 1) OR ((b = 2) AND (c = 3)))"},
+		{"NOT a = 1 AND b", "((NOT (a = 1)) AND b)"},
+		{"a + 1 > b * 2", "((a + 1) > (b * 2))"},
+		{"a || b = c", "((a || b) = c)"},
+		{"a IS NOT NULL AND b IS TRUE", "((a IS NOT NULL) AND (b IS TRUE))"},
+		{"a BETWEEN 1 AND 2 AND b", "((a BETWEEN 1 AND 2) AND b)"},
+		{"a NOT BETWEEN b + 1 AND c", "(a NOT BETWEEN (b + 1) AND c)"},
+		{"a LIKE 'x%' OR b NOT ILIKE 'y'", "((a LIKE 'x%') OR (b NOT ILIKE 'y'))"},
+		{"a IN (1, 2) AND b NOT IN (3)", "((a IN (1, 2)) AND (b NOT IN (3)))"},
+		{"a::integer + 1 = 2", "(((a::INTEGER) + 1) = 2)"},
+		{"lower(a) = 'x' AND coalesce(b, 0) > 1", "((lower(a) = 'x') AND (coalesce(b, 0) > 1))"},
+		{"t.a <> u.b", "(t.a <> u.b)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := parseWhere(t, test.expr)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := render(expr); got != test.want {
+				t.Errorf("se agrupó como %s, se esperaba %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expr  string
+		error string
+	}{
+		{"a +", "se esperaba"},
+		{"a = = 1", "se esperaba una expresión"},
+		{"(a = 1", ")"},
+		{"a BETWEEN 1", "AND"},
+		{"()", "se esperaba una expresión entre los paréntesis"},
+		{"CAST(a integer)", "AS"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := parseWhere(t, test.expr)
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if !strings.Contains(err.Error(), test.error) {
+				t.Errorf("error %q, se esperaba que contenga %q", err, test.error)
+			}
+		})
+	}
+}
+
+func TestTypedLiterals(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"a = DATE '2024-01-31'", "(a = ('2024-01-31'::DATE))"},
+		{"a > TIMESTAMP '2024-01-01 10:00'", "(a > ('2024-01-01 10:00'::TIMESTAMP))"},
+		{"a < now() - interval '1 day'", "(a < (now() - ('1 day'::INTERVAL)))"},
+		{"a = TIMESTAMP WITH TIME ZONE '2024-01-01 10:00+02'", "(a = ('2024-01-01 10:00+02'::TIMESTAMPTZ))"},
+		{"date = 1", "(date = 1)"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			expr, err := parseWhere(t, test.expr)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := render(expr); got != test.want {
+				t.Errorf("se leyó como %s, se esperaba %s", got, test.want)
+			}
+		})
+	}
+}
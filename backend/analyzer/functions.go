@@ -0,0 +1,310 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FunctionInfo describe una función incorporada de PostgreSQL. MaxArgs -1
+// indica una cantidad variable de argumentos. Returns es el nombre del tipo
+// que devuelve, o "" si devuelve el tipo de su primer argumento (MAX,
+// COALESCE...). Window indica una función que solo puede usarse con OVER.
+type FunctionInfo struct {
+	Name      string
+	MinArgs   int
+	MaxArgs   int
+	Returns   string
+	Aggregate bool
+	Window    bool
+}
+
+// variadic marca funciones sin límite de argumentos.
+const variadic = -1
+
+// builtinFunctions es el catálogo de funciones conocidas, por nombre en
+// minúsculas.
+var builtinFunctions = map[string]FunctionInfo{}
+
+func init() {
+	for _, fn := range []FunctionInfo{
+		// Agregados
+		{Name: "count", MinArgs: 1, MaxArgs: 1, Returns: "BIGINT", Aggregate: true},
+		{Name: "sum", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC", Aggregate: true},
+		{Name: "avg", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC", Aggregate: true},
+		{Name: "min", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+		{Name: "max", MinArgs: 1, MaxArgs: 1, Aggregate: true},
+		{Name: "string_agg", MinArgs: 2, MaxArgs: 2, Returns: "TEXT", Aggregate: true},
+		{Name: "array_agg", MinArgs: 1, MaxArgs: 1, Returns: "ARRAY", Aggregate: true},
+		{Name: "bool_and", MinArgs: 1, MaxArgs: 1, Returns: "BOOLEAN", Aggregate: true},
+		{Name: "bool_or", MinArgs: 1, MaxArgs: 1, Returns: "BOOLEAN", Aggregate: true},
+		{Name: "every", MinArgs: 1, MaxArgs: 1, Returns: "BOOLEAN", Aggregate: true},
+		{Name: "json_agg", MinArgs: 1, MaxArgs: 1, Returns: "JSON", Aggregate: true},
+		{Name: "jsonb_agg", MinArgs: 1, MaxArgs: 1, Returns: "JSONB", Aggregate: true},
+		{Name: "json_object_agg", MinArgs: 2, MaxArgs: 2, Returns: "JSON", Aggregate: true},
+		{Name: "stddev", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC", Aggregate: true},
+		{Name: "variance", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC", Aggregate: true},
+
+		// Ventana
+		{Name: "row_number", MinArgs: 0, MaxArgs: 0, Returns: "BIGINT", Window: true},
+		{Name: "rank", MinArgs: 0, MaxArgs: 0, Returns: "BIGINT", Window: true},
+		{Name: "dense_rank", MinArgs: 0, MaxArgs: 0, Returns: "BIGINT", Window: true},
+		{Name: "percent_rank", MinArgs: 0, MaxArgs: 0, Returns: "DOUBLE PRECISION", Window: true},
+		{Name: "cume_dist", MinArgs: 0, MaxArgs: 0, Returns: "DOUBLE PRECISION", Window: true},
+		{Name: "ntile", MinArgs: 1, MaxArgs: 1, Returns: "INTEGER", Window: true},
+		{Name: "lag", MinArgs: 1, MaxArgs: 3, Window: true},
+		{Name: "lead", MinArgs: 1, MaxArgs: 3, Window: true},
+		{Name: "first_value", MinArgs: 1, MaxArgs: 1, Window: true},
+		{Name: "last_value", MinArgs: 1, MaxArgs: 1, Window: true},
+		{Name: "nth_value", MinArgs: 2, MaxArgs: 2, Window: true},
+
+		// Texto
+		{Name: "length", MinArgs: 1, MaxArgs: 1, Returns: "INTEGER"},
+		{Name: "char_length", MinArgs: 1, MaxArgs: 1, Returns: "INTEGER"},
+		{Name: "lower", MinArgs: 1, MaxArgs: 1, Returns: "TEXT"},
+		{Name: "upper", MinArgs: 1, MaxArgs: 1, Returns: "TEXT"},
+		{Name: "initcap", MinArgs: 1, MaxArgs: 1, Returns: "TEXT"},
+		{Name: "btrim", MinArgs: 1, MaxArgs: 2, Returns: "TEXT"},
+		{Name: "ltrim", MinArgs: 1, MaxArgs: 2, Returns: "TEXT"},
+		{Name: "rtrim", MinArgs: 1, MaxArgs: 2, Returns: "TEXT"},
+		{Name: "substr", MinArgs: 2, MaxArgs: 3, Returns: "TEXT"},
+		{Name: "substring", MinArgs: 2, MaxArgs: 3, Returns: "TEXT"},
+		{Name: "left", MinArgs: 2, MaxArgs: 2, Returns: "TEXT"},
+		{Name: "right", MinArgs: 2, MaxArgs: 2, Returns: "TEXT"},
+		{Name: "lpad", MinArgs: 2, MaxArgs: 3, Returns: "TEXT"},
+		{Name: "rpad", MinArgs: 2, MaxArgs: 3, Returns: "TEXT"},
+		{Name: "concat", MinArgs: 1, MaxArgs: variadic, Returns: "TEXT"},
+		{Name: "concat_ws", MinArgs: 2, MaxArgs: variadic, Returns: "TEXT"},
+		{Name: "format", MinArgs: 1, MaxArgs: variadic, Returns: "TEXT"},
+		{Name: "replace", MinArgs: 3, MaxArgs: 3, Returns: "TEXT"},
+		{Name: "translate", MinArgs: 3, MaxArgs: 3, Returns: "TEXT"},
+		{Name: "split_part", MinArgs: 3, MaxArgs: 3, Returns: "TEXT"},
+		{Name: "strpos", MinArgs: 2, MaxArgs: 2, Returns: "INTEGER"},
+		{Name: "repeat", MinArgs: 2, MaxArgs: 2, Returns: "TEXT"},
+		{Name: "reverse", MinArgs: 1, MaxArgs: 1, Returns: "TEXT"},
+		{Name: "md5", MinArgs: 1, MaxArgs: 1, Returns: "TEXT"},
+		{Name: "regexp_replace", MinArgs: 3, MaxArgs: 4, Returns: "TEXT"},
+		{Name: "regexp_match", MinArgs: 2, MaxArgs: 3, Returns: "ARRAY"},
+		{Name: "to_char", MinArgs: 2, MaxArgs: 2, Returns: "TEXT"},
+
+		// Números
+		{Name: "abs", MinArgs: 1, MaxArgs: 1},
+		{Name: "round", MinArgs: 1, MaxArgs: 2, Returns: "NUMERIC"},
+		{Name: "trunc", MinArgs: 1, MaxArgs: 2, Returns: "NUMERIC"},
+		{Name: "ceil", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC"},
+		{Name: "ceiling", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC"},
+		{Name: "floor", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC"},
+		{Name: "mod", MinArgs: 2, MaxArgs: 2, Returns: "NUMERIC"},
+		{Name: "power", MinArgs: 2, MaxArgs: 2, Returns: "NUMERIC"},
+		{Name: "sqrt", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC"},
+		{Name: "exp", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC"},
+		{Name: "ln", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC"},
+		{Name: "log", MinArgs: 1, MaxArgs: 2, Returns: "NUMERIC"},
+		{Name: "sign", MinArgs: 1, MaxArgs: 1, Returns: "NUMERIC"},
+		{Name: "random", MinArgs: 0, MaxArgs: 0, Returns: "DOUBLE PRECISION"},
+		{Name: "to_number", MinArgs: 2, MaxArgs: 2, Returns: "NUMERIC"},
+
+		// Fechas
+		{Name: "now", MinArgs: 0, MaxArgs: 0, Returns: "TIMESTAMP"},
+		{Name: "current_date", MinArgs: 0, MaxArgs: 0, Returns: "DATE"},
+		{Name: "current_time", MinArgs: 0, MaxArgs: 0, Returns: "TIME"},
+		{Name: "current_timestamp", MinArgs: 0, MaxArgs: 0, Returns: "TIMESTAMP"},
+		{Name: "localtime", MinArgs: 0, MaxArgs: 0, Returns: "TIME"},
+		{Name: "localtimestamp", MinArgs: 0, MaxArgs: 0, Returns: "TIMESTAMP"},
+		{Name: "clock_timestamp", MinArgs: 0, MaxArgs: 0, Returns: "TIMESTAMP"},
+		{Name: "date_trunc", MinArgs: 2, MaxArgs: 3, Returns: "TIMESTAMP"},
+		{Name: "date_part", MinArgs: 2, MaxArgs: 2, Returns: "DOUBLE PRECISION"},
+		{Name: "extract", MinArgs: 2, MaxArgs: 2, Returns: "NUMERIC"},
+		{Name: "age", MinArgs: 1, MaxArgs: 2, Returns: "INTERVAL"},
+		{Name: "to_date", MinArgs: 2, MaxArgs: 2, Returns: "DATE"},
+		{Name: "to_timestamp", MinArgs: 1, MaxArgs: 2, Returns: "TIMESTAMP"},
+		{Name: "make_date", MinArgs: 3, MaxArgs: 3, Returns: "DATE"},
+
+		// Valores nulos y comparación
+		{Name: "coalesce", MinArgs: 1, MaxArgs: variadic},
+		{Name: "nullif", MinArgs: 2, MaxArgs: 2},
+		{Name: "greatest", MinArgs: 1, MaxArgs: variadic},
+		{Name: "least", MinArgs: 1, MaxArgs: variadic},
+
+		// Sesión, JSON y otros
+		{Name: "current_user", MinArgs: 0, MaxArgs: 0, Returns: "TEXT"},
+		{Name: "session_user", MinArgs: 0, MaxArgs: 0, Returns: "TEXT"},
+		{Name: "user", MinArgs: 0, MaxArgs: 0, Returns: "TEXT"},
+		{Name: "gen_random_uuid", MinArgs: 0, MaxArgs: 0, Returns: "UUID"},
+		{Name: "json_build_object", MinArgs: 0, MaxArgs: variadic, Returns: "JSON"},
+		{Name: "jsonb_build_object", MinArgs: 0, MaxArgs: variadic, Returns: "JSONB"},
+		{Name: "to_json", MinArgs: 1, MaxArgs: 1, Returns: "JSON"},
+		{Name: "to_jsonb", MinArgs: 1, MaxArgs: 1, Returns: "JSONB"},
+	} {
+		builtinFunctions[fn.Name] = fn
+	}
+}
+
+// lookupFunction busca una función del catálogo. Las funciones calificadas
+// con un esquema (mi_esquema.fn) no están en el catálogo.
+func lookupFunction(name QualifiedName) (FunctionInfo, bool) {
+	if len(name.Parts) != 1 {
+		return FunctionInfo{}, false
+	}
+	fn, ok := builtinFunctions[strings.ToLower(name.Name())]
+	return fn, ok
+}
+
+// describeArity describe la cantidad de argumentos que acepta una función.
+func (fn FunctionInfo) describeArity() string {
+	switch {
+	case fn.MaxArgs == variadic:
+		return "al menos " + pluralArgs(fn.MinArgs)
+	case fn.MinArgs == fn.MaxArgs:
+		return pluralArgs(fn.MinArgs)
+	default:
+		return fmt.Sprintf("entre %d y %s", fn.MinArgs, pluralArgs(fn.MaxArgs))
+	}
+}
+
+func pluralArgs(n int) string {
+	if n == 1 {
+		return "1 argumento"
+	}
+	return fmt.Sprintf("%d argumentos", n)
+}
+
+// checkFunctions verifica cada llamada a función de la sentencia contra el
+// catálogo: funciones desconocidas, cantidad de argumentos, uso de '*',
+// DISTINCT, ORDER BY y FILTER fuera de los agregados y uso de OVER.
+func (info *SemanticInfo) checkFunctions(stmt Statement) {
+	Walk(stmt, func(node Node) bool {
+		call, ok := node.(*FuncCall)
+		if !ok || len(call.Name.Parts) != 1 {
+			// Las funciones calificadas con un esquema son de usuario
+			return true
+		}
+		fn, known := info.catalog.Function(call.Name)
+		if !known {
+			// Puede ser una función definida por el usuario en la base
+			info.addWarning(call.Span, "función desconocida: '%s'", call.Name.String())
+			return true
+		}
+
+		name := strings.ToUpper(fn.Name)
+		switch {
+		case call.Star && fn.Name != "count":
+			info.addError(call.Span, "%s(*) no es válido, solo COUNT admite '*'", name)
+		case !call.Star && !call.NoParens && (len(call.Args) < fn.MinArgs ||
+			(fn.MaxArgs != variadic && len(call.Args) > fn.MaxArgs)):
+			info.addError(call.Span, "la función %s recibe %s, se indicaron %d",
+				name, fn.describeArity(), len(call.Args))
+		}
+		switch {
+		case fn.Window && call.Over == nil:
+			info.addError(call.Span, "la función de ventana %s requiere una cláusula OVER", name)
+		case !fn.Window && !fn.Aggregate && call.Over != nil:
+			info.addError(call.Span, "%s no es una función de agregado ni de ventana, no admite OVER", name)
+		case fn.Aggregate && call.Over != nil && call.Distinct:
+			info.addError(call.Span, "DISTINCT no se admite en %s usada como función de ventana", name)
+		}
+		if !fn.Aggregate {
+			switch {
+			case call.Distinct:
+				info.addError(call.Span, "DISTINCT solo se permite en funciones de agregado, no en %s", name)
+			case len(call.OrderBy) > 0:
+				info.addError(call.Span, "ORDER BY solo se permite en funciones de agregado, no en %s", name)
+			case call.Filter != nil:
+				info.addError(call.Span, "FILTER solo se permite en funciones de agregado, no en %s", name)
+			}
+		}
+		return true
+	})
+}
+
+// checkWindows verifica las funciones de ventana de cada SELECT de la
+// sentencia.
+func (info *SemanticInfo) checkWindows(stmt Statement) {
+	Walk(stmt, func(node Node) bool {
+		if query, ok := node.(*SelectStmt); ok {
+			info.checkSelectWindows(query)
+		}
+		return true
+	})
+}
+
+// checkSelectWindows verifica que las funciones de ventana de un SELECT no
+// aparezcan en WHERE, GROUP BY ni HAVING, que no se aniden y que las
+// ventanas con nombre que usan estén definidas en su cláusula WINDOW.
+func (info *SemanticInfo) checkSelectWindows(query *SelectStmt) {
+	windows := map[string]*WindowSpec{}
+	for _, window := range query.Windows {
+		// Una ventana de WINDOW solo puede apoyarse en las anteriores
+		info.checkWindowBase(window, windows)
+		windows[window.Name.Name] = window
+	}
+
+	for _, child := range query.children() {
+		if _, ok := child.(*WindowSpec); ok {
+			continue
+		}
+		for _, call := range windowCalls(child) {
+			info.checkWindowBase(call.Over, windows)
+			for _, arg := range call.children() {
+				for _, inner := range windowCalls(arg) {
+					info.addError(inner.Span, "no se pueden anidar funciones de ventana: %s está dentro de %s",
+						inner.Name.String(), call.Name.String())
+				}
+			}
+		}
+	}
+
+	if query.Where != nil {
+		info.rejectWindowCalls(query.Where, "WHERE")
+	}
+	for _, expr := range query.GroupBy {
+		info.rejectWindowCalls(expr, "GROUP BY")
+	}
+	if query.Having != nil {
+		info.rejectWindowCalls(query.Having, "HAVING")
+	}
+}
+
+// rejectWindowCalls marca como error cada función de ventana de una
+// cláusula que no las admite.
+func (info *SemanticInfo) rejectWindowCalls(expr Expr, clause string) {
+	for _, call := range windowCalls(expr) {
+		info.addError(call.Span, "no se permiten funciones de ventana en %s: %s", clause, call.Name.String())
+	}
+}
+
+// checkWindowBase verifica la ventana base de una definición: que exista y
+// que la definición no redefina su partición, su orden ni su marco.
+func (info *SemanticInfo) checkWindowBase(window *WindowSpec, windows map[string]*WindowSpec) {
+	if window.Base.Name == "" {
+		return
+	}
+	base, ok := windows[window.Base.Name]
+	switch {
+	case !ok:
+		info.addError(window.Base.Span, "la ventana '%s' no está definida en WINDOW", window.Base.String())
+	case len(window.PartitionBy) > 0:
+		info.addError(window.Span, "no se puede redefinir PARTITION BY de la ventana '%s'", window.Base.String())
+	case len(window.OrderBy) > 0 && len(base.OrderBy) > 0:
+		info.addError(window.Span, "no se puede redefinir ORDER BY de la ventana '%s'", window.Base.String())
+	case base.Frame != nil && (len(window.OrderBy) > 0 || window.Frame != nil):
+		info.addError(window.Span, "no se puede extender la ventana '%s' porque tiene un marco", window.Base.String())
+	}
+}
+
+// windowCalls devuelve las llamadas con OVER de una parte de un SELECT, sin
+// entrar en sus subconsultas.
+func windowCalls(node Node) []*FuncCall {
+	var calls []*FuncCall
+	Walk(node, func(node Node) bool {
+		switch n := node.(type) {
+		case Query:
+			return false
+		case *FuncCall:
+			if n.Over != nil {
+				calls = append(calls, n)
+			}
+		}
+		return true
+	})
+	return calls
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import "testing"
+
+func TestFunctionCalls(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT count(*), count(id), sum(edad), max(nombre) FROM usuarios", valid: true},
+		{query: "SELECT lower(nombre), coalesce(edad, 0), now() FROM usuarios", valid: true},
+		{query: "SELECT string_agg(nombre, ',' ORDER BY nombre) FROM usuarios", valid: true},
+		{query: "SELECT count(DISTINCT edad) FILTER (WHERE activo) FROM usuarios", valid: true},
+		{query: "SELECT count() FROM usuarios", error: "la función COUNT recibe 1 argumento, se indicaron 0"},
+		{query: "SELECT sum(*) FROM usuarios", error: "SUM(*) no es válido"},
+		{query: "SELECT lower(nombre, edad) FROM usuarios", error: "la función LOWER recibe"},
+		{query: "SELECT lower(DISTINCT nombre) FROM usuarios", error: "DISTINCT solo se permite en funciones de agregado"},
+	})
+}
+
+func TestUnknownFunctions(t *testing.T) {
+	catalog := newTestCatalog(t)
+	info, err := SemanticAnalysisWithCatalog("SELECT calcular(edad) FROM usuarios", catalog)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	// Puede ser una función de la base: se avisa pero la query es válida
+	if !info.Valid || len(info.Warnings) != 1 {
+		t.Errorf("Valid = %v, avisos %v", info.Valid, info.Warnings)
+	}
+
+	catalog.AddFunction(FunctionInfo{Name: "calcular", MinArgs: 1, MaxArgs: 1, Returns: "INTEGER"})
+	checkSemanticInfo(t, mustAnalyze(t, "SELECT calcular(edad) + 1 FROM usuarios", catalog), true, "")
+	checkSemanticInfo(t, mustAnalyze(t, "SELECT calcular() FROM usuarios", catalog), false, "la función CALCULAR recibe")
+}
+
+func TestWindowFunctions(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT nombre, row_number() OVER (PARTITION BY edad ORDER BY nombre) FROM usuarios", valid: true},
+		{query: "SELECT sum(edad) OVER w FROM usuarios WINDOW w AS (ORDER BY id)", valid: true},
+		{query: "SELECT row_number() FROM usuarios", error: "requiere una cláusula OVER"},
+		{query: "SELECT lower(nombre) OVER () FROM usuarios", error: "no admite OVER"},
+		{query: "SELECT sum(edad) OVER w FROM usuarios", error: "la ventana 'w' no está definida"},
+		{query: "SELECT * FROM usuarios WHERE row_number() OVER () > 1", error: "no se permiten funciones de ventana"},
+	})
+}
+
+// mustAnalyze hace el análisis semántico de una query sin errores
+// sintácticos.
+func mustAnalyze(t *testing.T, query string, catalog Catalog) *SemanticInfo {
+	t.Helper()
+	info, err := SemanticAnalysisWithCatalog(query, catalog)
+	if err != nil {
+		t.Fatalf("error sintáctico en %q: %v", query, err)
+	}
+	return info
+}
@@ -0,0 +1,10 @@
+package analyzer
+
+// checkCreateIndex verifica que las columnas del índice existan en la tabla.
+func (info *SemanticInfo) checkCreateIndex(stmt *CreateIndexStmt) {
+	for _, column := range stmt.Columns {
+		if !info.addTableColumn(column, stmt.Table.Name) {
+			info.addError(column.Span, "la columna '%s' del índice no existe en la tabla '%s'", column.String(), stmt.Table.Name.String())
+		}
+	}
+}
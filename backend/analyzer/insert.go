@@ -0,0 +1,93 @@
+package analyzer
+
+import "strings"
+
+// checkInsert verifica las columnas de un INSERT contra las de la tabla de
+// destino: que existan, que no se repitan y que cada fila (de VALUES o de
+// la consulta) tenga tantos valores como columnas de destino.
+func (info *SemanticInfo) checkInsert(stmt *InsertStmt) {
+	table := stmt.Table.Name
+	columns, known := info.columnsOf(table)
+
+	seen := map[string]bool{}
+	for _, column := range stmt.Columns {
+		if seen[column.Name] {
+			info.addError(column.Span, "la columna '%s' aparece más de una vez en INSERT", column.String())
+			continue
+		}
+		seen[column.Name] = true
+		if !info.addTableColumn(column, table) {
+			info.addError(column.Span, "la columna '%s' no existe en la tabla '%s'", column.String(), table.String())
+		}
+	}
+
+	// Sin lista de columnas, los valores se asignan a las columnas de la
+	// tabla en orden y pueden faltar las últimas
+	targets := len(stmt.Columns)
+	if targets == 0 && known {
+		targets = len(columns)
+	}
+	if targets > 0 {
+		if len(stmt.Values) > 0 {
+			info.checkInsertCount(stmt, len(stmt.Values[0]), targets, stmt.Values[0][0].Location())
+		}
+		if stmt.Query != nil {
+			if output, ok := outputColumns(stmt.Query); ok {
+				info.checkInsertCount(stmt, len(output), targets, stmt.Query.Location())
+			}
+		}
+	}
+
+	if conflict := stmt.OnConflict; conflict != nil {
+		info.checkConflictTarget(stmt)
+		info.checkAssignments(conflict.Set, table)
+	}
+}
+
+// checkInsertCount compara la cantidad de valores de cada fila con la de
+// columnas de destino.
+func (info *SemanticInfo) checkInsertCount(stmt *InsertStmt, values, targets int, span Span) {
+	switch {
+	case values > targets:
+		info.addError(span, "INSERT tiene más valores (%d) que columnas de destino (%d)", values, targets)
+	case values < targets && len(stmt.Columns) > 0:
+		info.addError(span, "INSERT tiene más columnas de destino (%d) que valores (%d)", targets, values)
+	}
+}
+
+// checkConflictTarget verifica que las columnas de ON CONFLICT existan y
+// que formen una PRIMARY KEY o un UNIQUE de la tabla, o que exista el
+// constraint de ON CONFLICT ON CONSTRAINT: PostgreSQL necesita un índice
+// único para detectar el conflicto. Con WHERE el índice puede ser parcial y
+// no se verifica.
+func (info *SemanticInfo) checkConflictTarget(stmt *InsertStmt) {
+	conflict, table := stmt.OnConflict, stmt.Table.Name
+	var names []string
+	missing := false
+	for _, column := range conflict.Target {
+		if !info.addTableColumn(column, table) {
+			info.addError(column.Span, "la columna '%s' de ON CONFLICT no existe en la tabla '%s'", column.String(), table.String())
+			missing = true
+		}
+		names = append(names, column.Name)
+	}
+
+	cached, known := info.tableOf(table)
+	if !known || missing || conflict.TargetWhere != nil {
+		return
+	}
+	if conflict.Constraint.Name != "" {
+		for _, constraint := range cached.schema.Constraints {
+			if constraint.Name == conflict.Constraint.Name {
+				return
+			}
+		}
+		info.addError(conflict.Constraint.Span, "la tabla '%s' no tiene un constraint llamado '%s'",
+			table.String(), conflict.Constraint.String())
+		return
+	}
+	if len(names) > 0 && !cached.schema.hasUniqueKey(names) {
+		info.addError(conflict.Span, "la tabla '%s' no tiene una PRIMARY KEY ni un UNIQUE sobre (%s) que ON CONFLICT pueda usar",
+			table.String(), strings.Join(names, ", "))
+	}
+}
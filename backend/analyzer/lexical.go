@@ -1,14 +1,20 @@
 package analyzer
 
 import (
-	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type Token struct {
 	Type  string `json:"tipo"`
 	Value string `json:"token"`
+	// Literal es el valor decodificado de una CADENA o de un identificador
+	// entre comillas dobles (sin comillas ni secuencias de escape); Value
+	// conserva el texto original.
+	Literal string `json:"valor,omitempty"`
+	Span
 }
 
 var keywords = []string{
@@ -22,48 +28,98 @@ var keywords = []string{
 	"ALL", "DATABASE", "USE", "IF", "EXISTS", "CASCADE",
 	"CONSTRAINT", "INDEX", "VIEW", "PROCEDURE", "FUNCTION",
 	"TRIGGER", "BEGIN", "END", "COMMIT", "ROLLBACK",
+	"CASE", "WHEN", "THEN", "ELSE", "CAST",
 }
 
 func LexicalAnalysis(query string) ([]Token, error) {
+	return lexAt(query, startSpan.Start)
+}
+
+// lexAt analiza query como si empezara en la posición start de un texto
+// mayor, de modo que los rangos de los tokens (y de los errores) quedan en
+// las coordenadas de ese texto. Se usa para las sentencias de un script.
+func lexAt(query string, start Position) ([]Token, error) {
 	var tokens []Token
-	query = strings.TrimSpace(query)
 
-	if query == "" {
-		return nil, fmt.Errorf("query vacía")
+	if strings.TrimSpace(query) == "" {
+		return nil, newAnalysisError(Span{Start: start, End: start}, "query vacía")
 	}
 
 	// Patrones de expresiones regulares
 	patterns := map[string]*regexp.Regexp{
 		"NUMBER":     regexp.MustCompile(`^\d+(\.\d+)?`),
-		"STRING":     regexp.MustCompile(`^'[^']*'`),
 		"IDENTIFIER": regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`),
-		"OPERATOR":   regexp.MustCompile(`^(>=|<=|<>|!=|[><=+\-*/])`),
-		"DELIMITER":  regexp.MustCompile(`^[(),;.]`),
+		"OPERATOR":   regexp.MustCompile(`^(::|>=|<=|<>|!=|\|\||[><=+\-*/%^])`),
+		"DELIMITER":  regexp.MustCompile(`^[(),;.\[\]]`),
+	}
+
+	pos := start
+	emit := func(tokenType, match string) {
+		end := pos.advance(match)
+		tokens = append(tokens, Token{Type: tokenType, Value: match, Span: Span{Start: pos, End: end}})
+		pos = end
 	}
 
-	i := 0
-	for i < len(query) {
+	for pos.Offset-start.Offset < len(query) {
+		remaining := query[pos.Offset-start.Offset:]
+
 		// Saltar espacios en blanco
-		if query[i] == ' ' || query[i] == '\t' || query[i] == '\n' {
-			i++
+		switch remaining[0] {
+		case ' ', '\t', '\n', '\r':
+			pos = pos.advance(remaining[:1])
 			continue
 		}
 
 		matched := false
-		remaining := query[i:]
+
+		// Comentarios: deben reconocerse antes que los operadores '-' y '/'
+		if strings.HasPrefix(remaining, "--") {
+			end := strings.IndexByte(remaining, '\n')
+			if end == -1 {
+				end = len(remaining)
+			}
+			emit("COMENTARIO", strings.TrimRight(remaining[:end], "\r"))
+			continue
+		}
+		if strings.HasPrefix(remaining, "/*") {
+			length, ok := scanBlockComment(remaining)
+			if !ok {
+				return nil, newAnalysisError(Span{Start: pos, End: pos.advance(remaining[:2])},
+					"comentario de bloque '/*' sin cerrar")
+			}
+			emit("COMENTARIO", remaining[:length])
+			continue
+		}
 
 		// Verificar operadores primero
 		if match := patterns["OPERATOR"].FindString(remaining); match != "" {
-			tokens = append(tokens, Token{Type: "OPERADOR", Value: match})
-			i += len(match)
+			emit("OPERADOR", match)
 			matched = true
 		} else if match := patterns["NUMBER"].FindString(remaining); match != "" {
-			tokens = append(tokens, Token{Type: "NUMERO", Value: match})
-			i += len(match)
+			emit("NUMERO", match)
+			matched = true
+		} else if length, value, kind := scanString(remaining); kind != "" {
+			if length < 0 {
+				return nil, newAnalysisError(Span{Start: pos, End: pos.advance(remaining[:-length])},
+					"%s sin cerrar", kind)
+			}
+			emit("CADENA", remaining[:length])
+			tokens[len(tokens)-1].Literal = value
 			matched = true
-		} else if match := patterns["STRING"].FindString(remaining); match != "" {
-			tokens = append(tokens, Token{Type: "CADENA", Value: match})
-			i += len(match)
+		} else if remaining[0] == '"' {
+			// Identificador entre comillas dobles: conserva mayúsculas y
+			// nunca es palabra clave
+			length, name := scanQuoted(remaining, 1, '"', false)
+			if length < 0 {
+				return nil, newAnalysisError(Span{Start: pos, End: pos.advance(`"`)},
+					"identificador entre comillas sin cerrar")
+			}
+			if name == "" {
+				return nil, newAnalysisError(Span{Start: pos, End: pos.advance(remaining[:length])},
+					"identificador entre comillas vacío")
+			}
+			emit("IDENTIFICADOR", remaining[:length])
+			tokens[len(tokens)-1].Literal = name
 			matched = true
 		} else if match := patterns["IDENTIFIER"].FindString(remaining); match != "" {
 			upperMatch := strings.ToUpper(match)
@@ -77,19 +133,175 @@ func LexicalAnalysis(query string) ([]Token, error) {
 				}
 			}
 
-			tokens = append(tokens, Token{Type: tokenType, Value: match})
-			i += len(match)
+			emit(tokenType, match)
 			matched = true
 		} else if match := patterns["DELIMITER"].FindString(remaining); match != "" {
-			tokens = append(tokens, Token{Type: "DELIMITADOR", Value: match})
-			i += len(match)
+			emit("DELIMITADOR", match)
 			matched = true
 		}
 
 		if !matched {
-			return nil, fmt.Errorf("carácter no reconocido: '%c' en posición %d", query[i], i)
+			r, size := utf8.DecodeRuneInString(remaining)
+			return nil, newAnalysisError(Span{Start: pos, End: pos.advance(remaining[:size])},
+				"carácter no reconocido: '%c'", r)
 		}
 	}
 
 	return tokens, nil
 }
+
+// scanString reconoce un literal de cadena al inicio de s en cualquiera de
+// las formas de PostgreSQL: 'texto' (con dos comillas seguidas como comilla
+// escapada), E'texto' (con escapes de barra invertida), $$texto$$ y
+// $etiqueta$texto$etiqueta$. Devuelve la longitud del literal, su valor
+// decodificado y el tipo de literal; kind vacío indica que s no empieza con
+// una cadena. Si la cadena no está cerrada, length es el negativo de la
+// longitud del delimitador de apertura.
+func scanString(s string) (length int, value string, kind string) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		length, value = scanQuoted(s, 1, '\'', false)
+		if length < 0 {
+			return -1, "", "cadena"
+		}
+		return length, value, "cadena"
+
+	case len(s) > 1 && (s[0] == 'E' || s[0] == 'e') && s[1] == '\'':
+		length, value = scanQuoted(s, 2, '\'', true)
+		if length < 0 {
+			return -2, "", "cadena con escapes E''"
+		}
+		return length, value, "cadena con escapes E''"
+
+	case strings.HasPrefix(s, "$"):
+		delimiter := dollarQuotePattern.FindString(s)
+		if delimiter == "" {
+			return 0, "", ""
+		}
+		end := strings.Index(s[len(delimiter):], delimiter)
+		if end == -1 {
+			return -len(delimiter), "", "cadena entre " + delimiter
+		}
+		value = s[len(delimiter) : len(delimiter)+end]
+		return len(delimiter) + end + len(delimiter), value, "cadena entre " + delimiter
+	}
+
+	return 0, "", ""
+}
+
+var dollarQuotePattern = regexp.MustCompile(`^\$([a-zA-Z_][a-zA-Z0-9_]*)?\$`)
+
+// scanQuoted recorre un texto delimitado por quote (comilla simple o doble)
+// cuyo contenido empieza en s[start]; el delimitador duplicado representa al
+// propio carácter. Con escapes activo interpreta las secuencias de barra
+// invertida de las cadenas E'...'. Devuelve -1 si el texto no está cerrado.
+func scanQuoted(s string, start int, quote byte, escapes bool) (int, string) {
+	var value strings.Builder
+	i := start
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				value.WriteByte(quote)
+				i += 2
+				continue
+			}
+			return i + 1, value.String()
+
+		case c == '\\' && escapes && i+1 < len(s):
+			i += decodeEscape(s[i+1:], &value) + 1
+
+		default:
+			value.WriteByte(c)
+			i++
+		}
+	}
+	return -1, ""
+}
+
+// decodeEscape decodifica la secuencia que sigue a una barra invertida en
+// una cadena E'...' y devuelve cuántos bytes consumió.
+func decodeEscape(s string, value *strings.Builder) int {
+	simple := map[byte]byte{'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t'}
+	if c, ok := simple[s[0]]; ok {
+		value.WriteByte(c)
+		return 1
+	}
+
+	// Octal (\o, \oo, \ooo), hexadecimal (\xh, \xhh) y Unicode (\uXXXX, \UXXXXXXXX)
+	readDigits := func(from, max int, isDigit func(byte) bool) string {
+		end := from
+		for end < len(s) && end-from < max && isDigit(s[end]) {
+			end++
+		}
+		return s[from:end]
+	}
+	isOctal := func(c byte) bool { return c >= '0' && c <= '7' }
+	isHex := func(c byte) bool {
+		return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+	}
+
+	switch {
+	case isOctal(s[0]):
+		digits := readDigits(0, 3, isOctal)
+		n, _ := strconv.ParseUint(digits, 8, 8)
+		value.WriteByte(byte(n))
+		return len(digits)
+	case s[0] == 'x':
+		if digits := readDigits(1, 2, isHex); digits != "" {
+			n, _ := strconv.ParseUint(digits, 16, 8)
+			value.WriteByte(byte(n))
+			return 1 + len(digits)
+		}
+	case s[0] == 'u' || s[0] == 'U':
+		size := 4
+		if s[0] == 'U' {
+			size = 8
+		}
+		if digits := readDigits(1, size, isHex); len(digits) == size {
+			n, _ := strconv.ParseUint(digits, 16, 32)
+			value.WriteRune(rune(n))
+			return 1 + size
+		}
+	}
+
+	// Cualquier otro carácter se toma literalmente (\\, \', etc.)
+	_, size := utf8.DecodeRuneInString(s)
+	value.WriteString(s[:size])
+	return size
+}
+
+// scanBlockComment devuelve la longitud del comentario /* ... */ al inicio de
+// s. Como en PostgreSQL, los comentarios de bloque pueden anidarse.
+func scanBlockComment(s string) (int, bool) {
+	depth := 0
+	for i := 0; i+1 < len(s); {
+		switch s[i : i+2] {
+		case "/*":
+			depth++
+			i += 2
+		case "*/":
+			depth--
+			i += 2
+			if depth == 0 {
+				return i, true
+			}
+		default:
+			i++
+		}
+	}
+	return 0, false
+}
+
+// withoutComments devuelve los tokens sin los comentarios, que el análisis
+// sintáctico y semántico ignoran.
+func withoutComments(tokens []Token) []Token {
+	result := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Type != "COMENTARIO" {
+			result = append(result, token)
+		}
+	}
+	return result
+}
@@ -0,0 +1,271 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// MemoryCatalog es un catálogo que se arma a mano, sin base de datos. Los
+// nombres sin esquema pertenecen a public. Las funciones incorporadas y los
+// tipos de validTypes se conocen siempre.
+//
+// Creado con NewOverlayCatalog, parte de los objetos de otro catálogo y
+// guarda solo los cambios: lo que se crea o modifica en tables y objects, y
+// lo que se elimina en dropped. El catálogo base no se modifica.
+type MemoryCatalog struct {
+	base      Catalog
+	tables    map[string]*TableSchema
+	objects   map[string]catalogObject
+	dropped   map[string]bool
+	functions map[string]FunctionInfo
+	types     map[string]string
+}
+
+// catalogObject es un objeto que no es una tabla: una vista, un índice, una
+// secuencia o un esquema. dependsOn son las claves de los objetos de los que
+// depende, y columns las columnas de una vista, si se conocen.
+type catalogObject struct {
+	objectType string
+	name       string
+	dependsOn  []string
+	columns    []ColumnSchema
+}
+
+// NewMemoryCatalog crea un catálogo vacío.
+func NewMemoryCatalog() *MemoryCatalog {
+	return &MemoryCatalog{
+		tables:    map[string]*TableSchema{},
+		objects:   map[string]catalogObject{},
+		dropped:   map[string]bool{},
+		functions: map[string]FunctionInfo{},
+		types:     map[string]string{},
+	}
+}
+
+// NewOverlayCatalog crea un catálogo en memoria que empieza con los objetos
+// de base, para aplicarle sentencias DDL sin modificar base.
+func NewOverlayCatalog(base Catalog) *MemoryCatalog {
+	c := NewMemoryCatalog()
+	c.base = base
+	return c
+}
+
+// AddTable agrega una tabla, o la reemplaza si ya existe una con ese nombre.
+func (c *MemoryCatalog) AddTable(table *TableSchema) {
+	c.tables[nameKey(table.Name)] = table
+}
+
+// AddObject agrega un objeto que no es una tabla. dependsOn son los nombres
+// de las tablas o vistas que usa, como las de la consulta de una vista.
+func (c *MemoryCatalog) AddObject(objectType, name string, dependsOn ...string) {
+	object := catalogObject{objectType: objectType, name: name}
+	for _, dependency := range dependsOn {
+		object.dependsOn = append(object.dependsOn, nameKey(dependency))
+	}
+	c.objects[nameKey(name)] = object
+}
+
+// AddFunction agrega una función definida por el usuario.
+func (c *MemoryCatalog) AddFunction(fn FunctionInfo) {
+	c.functions[strings.ToLower(fn.Name)] = fn
+}
+
+// AddType agrega un tipo definido por el usuario (un dominio o un enum) con
+// el tipo incorporado al que equivale, como AddType("estado", "TEXT").
+func (c *MemoryCatalog) AddType(name, baseType string) {
+	c.types[strings.ToLower(name)] = catalogTypeCategory(baseType)
+}
+
+// Table busca una tabla o, como PostgresCatalog, una vista cuyas columnas
+// se conocen.
+func (c *MemoryCatalog) Table(name QualifiedName) (*TableSchema, bool) {
+	key := catalogKey(name)
+	if object, ok := c.objects[key]; ok && object.columns != nil {
+		return &TableSchema{Name: key, Columns: object.columns}, true
+	}
+	return c.table(key)
+}
+
+// table busca una tabla por su clave, primero entre las propias y después,
+// si no se eliminó, en el catálogo base.
+func (c *MemoryCatalog) table(key string) (*TableSchema, bool) {
+	if table, ok := c.tables[key]; ok {
+		return table, true
+	}
+	if c.dropped[key] || c.base == nil {
+		return nil, false
+	}
+	return c.base.Table(keyName(key))
+}
+
+func (c *MemoryCatalog) ObjectExists(objectType string, name QualifiedName) bool {
+	key := catalogKey(name)
+	switch objectType {
+	case "TABLE":
+		_, ok := c.table(key)
+		return ok
+	case "SCHEMA":
+		// public siempre existe; otro esquema, si tiene algún objeto
+		if name.Name() == "public" {
+			return true
+		}
+		for _, other := range c.sortedKeys() {
+			if strings.HasPrefix(other, name.Name()+".") {
+				return true
+			}
+		}
+	}
+	if object, ok := c.objects[key]; ok {
+		return object.objectType == objectType
+	}
+	return !c.dropped[key] && c.base != nil && c.base.ObjectExists(objectType, name)
+}
+
+// relationExists indica si hay una tabla, vista, índice o secuencia con esa
+// clave: comparten el espacio de nombres.
+func (c *MemoryCatalog) relationExists(key string) bool {
+	for _, objectType := range []string{"TABLE", "VIEW", "MATERIALIZED VIEW", "INDEX", "SEQUENCE"} {
+		if c.ObjectExists(objectType, keyName(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove elimina un objeto propio y, si hay un catálogo base, lo oculta.
+func (c *MemoryCatalog) remove(key string) {
+	delete(c.tables, key)
+	delete(c.objects, key)
+	if c.base != nil {
+		c.dropped[key] = true
+	}
+}
+
+func (c *MemoryCatalog) Dependents(objectType string, name QualifiedName) []DependentObject {
+	key := catalogKey(name)
+	dependents := c.baseDependents(objectType, name)
+	if objectType == "SCHEMA" {
+		for _, other := range c.sortedKeys() {
+			if strings.HasPrefix(other, name.Name()+".") {
+				dependents = append(dependents, DependentObject{Kind: c.objectType(other), Name: other, DependsOn: name.String()})
+			}
+		}
+		return dependents
+	}
+
+	for _, other := range c.sortedKeys() {
+		// Los índices se eliminan siempre con su tabla y no se informan
+		if object, ok := c.objects[other]; ok && object.objectType != "INDEX" && containsName(object.dependsOn, key) {
+			dependents = append(dependents, DependentObject{Kind: object.objectType, Name: object.name, DependsOn: name.String()})
+		}
+	}
+	if objectType == "TABLE" {
+		for _, other := range c.sortedKeys() {
+			table, ok := c.tables[other]
+			if !ok || other == key {
+				continue
+			}
+			for _, constraint := range table.Constraints {
+				if constraint.Kind == "FOREIGN KEY" && nameKey(constraint.References) == key {
+					dependents = append(dependents, DependentObject{
+						Kind: "FOREIGN KEY", Name: constraint.Name, Table: table.Name, DependsOn: name.String(),
+					})
+				}
+			}
+		}
+	}
+	return dependents
+}
+
+// baseDependents devuelve los objetos del catálogo base que dependen del
+// indicado, salvo los eliminados y los que se modificaron en este catálogo
+// (que Dependents revisa por su cuenta).
+func (c *MemoryCatalog) baseDependents(objectType string, name QualifiedName) []DependentObject {
+	if c.base == nil || c.dropped[catalogKey(name)] {
+		return nil
+	}
+	var dependents []DependentObject
+	for _, dependent := range c.base.Dependents(objectType, name) {
+		owner := nameKey(dependent.Name)
+		if dependent.Kind == "FOREIGN KEY" {
+			owner = nameKey(dependent.Table)
+		}
+		_, local := c.tables[owner]
+		if _, ok := c.objects[owner]; ok {
+			local = true
+		}
+		if !local && !c.dropped[owner] {
+			dependents = append(dependents, dependent)
+		}
+	}
+	return dependents
+}
+
+func (c *MemoryCatalog) Function(name QualifiedName) (FunctionInfo, bool) {
+	if fn, ok := lookupFunction(name); ok {
+		return fn, true
+	}
+	if fn, ok := c.functions[strings.ToLower(name.Name())]; ok {
+		return fn, true
+	}
+	if c.base != nil {
+		return c.base.Function(name)
+	}
+	return FunctionInfo{}, false
+}
+
+func (c *MemoryCatalog) TypeCategory(typeName string) string {
+	if category := catalogTypeCategory(typeName); category != typeUnknown {
+		return category
+	}
+	if category, ok := c.types[strings.ToLower(typeName)]; ok {
+		return category
+	}
+	if c.base != nil {
+		return c.base.TypeCategory(typeName)
+	}
+	return typeUnknown
+}
+
+// clone copia el catálogo. Las tablas no se copian porque nunca se
+// modifican: cada cambio las reemplaza por una nueva.
+func (c *MemoryCatalog) clone() *MemoryCatalog {
+	copied := NewOverlayCatalog(c.base)
+	for key, table := range c.tables {
+		copied.tables[key] = table
+	}
+	for key, object := range c.objects {
+		copied.objects[key] = object
+	}
+	for key := range c.dropped {
+		copied.dropped[key] = true
+	}
+	for key, fn := range c.functions {
+		copied.functions[key] = fn
+	}
+	for key, category := range c.types {
+		copied.types[key] = category
+	}
+	return copied
+}
+
+// sortedKeys devuelve las claves de todos los objetos en orden, para que
+// los resultados no dependan del orden de los mapas.
+func (c *MemoryCatalog) sortedKeys() []string {
+	var keys []string
+	for key := range c.tables {
+		keys = append(keys, key)
+	}
+	for key := range c.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *MemoryCatalog) objectType(key string) string {
+	if _, ok := c.tables[key]; ok {
+		return "TABLE"
+	}
+	return c.objects[key].objectType
+}
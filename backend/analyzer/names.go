@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Identifier es una parte de un nombre SQL. Name está normalizado como lo
+// hace PostgreSQL: en minúsculas salvo que el identificador esté entre
+// comillas dobles, en cuyo caso se respeta tal cual.
+type Identifier struct {
+	Name   string `json:"name"`
+	Quoted bool   `json:"quoted,omitempty"`
+	Span
+}
+
+// identifierFromToken construye el identificador que representa un token
+// IDENTIFICADOR (o una palabra clave usada como nombre).
+func identifierFromToken(tok Token) Identifier {
+	if strings.HasPrefix(tok.Value, `"`) {
+		return Identifier{Name: tok.Literal, Quoted: true, Span: tok.Span}
+	}
+	return Identifier{Name: strings.ToLower(tok.Value), Span: tok.Span}
+}
+
+var plainIdentifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// String devuelve el identificador como se escribiría en SQL, con comillas
+// solo cuando hacen falta.
+func (id Identifier) String() string {
+	if id.Name == "*" || plainIdentifierPattern.MatchString(id.Name) {
+		return id.Name
+	}
+	return `"` + strings.ReplaceAll(id.Name, `"`, `""`) + `"`
+}
+
+// QualifiedName es un nombre de una o más partes separadas por puntos, como
+// tabla, esquema.tabla o tabla.columna.
+type QualifiedName struct {
+	Parts []Identifier `json:"parts"`
+	Span
+}
+
+// Name devuelve la última parte del nombre (la tabla o la columna).
+func (n QualifiedName) Name() string {
+	return n.Parts[len(n.Parts)-1].Name
+}
+
+// Qualifier devuelve la parte que precede al último punto (el esquema de una
+// tabla o la tabla de una columna), o "" si el nombre no está calificado.
+func (n QualifiedName) Qualifier() string {
+	if len(n.Parts) < 2 {
+		return ""
+	}
+	return n.Parts[len(n.Parts)-2].Name
+}
+
+func (n QualifiedName) String() string {
+	parts := make([]string, len(n.Parts))
+	for i, part := range n.Parts {
+		parts[i] = part.String()
+	}
+	return strings.Join(parts, ".")
+}
+
+// readQualifiedName lee un nombre parte(.parte)* empezando en tokens[i], que
+// debe ser un IDENTIFICADOR. Con allowStar acepta además un '.*' final
+// (tabla.*). Devuelve el índice del primer token posterior al nombre.
+func readQualifiedName(tokens []Token, i int, allowStar bool) (QualifiedName, int, error) {
+	name := QualifiedName{
+		Parts: []Identifier{identifierFromToken(tokens[i])},
+		Span:  tokens[i].Span,
+	}
+	i++
+
+	for i < len(tokens) && tokens[i].Value == "." {
+		i++
+		if i < len(tokens) && tokens[i].Type == "IDENTIFICADOR" {
+			name.Parts = append(name.Parts, identifierFromToken(tokens[i]))
+		} else if i < len(tokens) && allowStar && tokens[i].Value == "*" {
+			name.Parts = append(name.Parts, Identifier{Name: "*", Span: tokens[i].Span})
+			name.Span.End = tokens[i].End
+			return name, i + 1, nil
+		} else {
+			return name, i, errorAt(tokens, i, "se esperaba un nombre después de '.' en '%s'", name.String())
+		}
+		name.Span.End = tokens[i].End
+		i++
+	}
+
+	return name, i, nil
+}
+
+// nameNode representa un nombre en el árbol sintáctico. Los nombres
+// calificados llevan un hijo QUALIFIED_NAME con cada una de sus partes.
+func nameNode(nodeType string, name QualifiedName) SyntaxNode {
+	node := SyntaxNode{Type: nodeType, Value: name.String(), Span: name.Span}
+	if len(name.Parts) > 1 {
+		qualified := SyntaxNode{Type: "QUALIFIED_NAME", Span: name.Span}
+		for _, part := range name.Parts {
+			qualified.Children = append(qualified.Children,
+				SyntaxNode{Type: "NAME_PART", Value: part.String(), Span: part.Span})
+		}
+		node.Children = append(node.Children, qualified)
+	}
+	return node
+}
@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Position ubica un punto dentro de la query. Line y Column empiezan en 1
+// (la columna cuenta caracteres, no bytes); Offset es el índice en bytes.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// advance devuelve la posición que resulta de consumir text desde p.
+func (p Position) advance(text string) Position {
+	for _, r := range text {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	p.Offset += len(text)
+	return p
+}
+
+// IsValid indica si la posición fue asignada.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Span es el rango [Start, End) de texto que ocupa un token, nodo o error.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location devuelve el propio rango; permite que los tipos que embeben
+// Span expongan su ubicación.
+func (s Span) Location() Span {
+	return s
+}
+
+// cover devuelve el menor rango que contiene a s y a other.
+func (s Span) cover(other Span) Span {
+	if !s.Start.IsValid() {
+		return other
+	}
+	if !other.Start.IsValid() {
+		return s
+	}
+	if other.Start.Offset < s.Start.Offset {
+		s.Start = other.Start
+	}
+	if other.End.Offset > s.End.Offset {
+		s.End = other.End
+	}
+	return s
+}
+
+// AnalysisError es un error de cualquiera de las fases del análisis junto
+// con el rango de la query al que se refiere.
+type AnalysisError struct {
+	Message string `json:"message"`
+	Span
+}
+
+func (e *AnalysisError) Error() string {
+	return fmt.Sprintf("%s (línea %d, columna %d)", e.Message, e.Start.Line, e.Start.Column)
+}
+
+func newAnalysisError(span Span, format string, args ...interface{}) *AnalysisError {
+	return &AnalysisError{Message: fmt.Sprintf(format, args...), Span: span}
+}
+
+// ErrorSpan devuelve el rango asociado a err si es un *AnalysisError.
+func ErrorSpan(err error) (Span, bool) {
+	var analysisErr *AnalysisError
+	if errors.As(err, &analysisErr) {
+		return analysisErr.Span, true
+	}
+	return Span{}, false
+}
+
+// startSpan es el rango vacío al inicio de la query.
+var startSpan = Span{Start: Position{Line: 1, Column: 1}, End: Position{Line: 1, Column: 1}}
@@ -0,0 +1,300 @@
+package analyzer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Querier ejecuta las consultas de PostgresCatalog. Lo cumplen el pool
+// (*sql.DB) y cualquier conexión que exponga esos dos métodos, como la de
+// una sesión, que ve además los cambios de su transacción sin confirmar.
+type Querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// PostgresCatalog consulta los objetos en el catálogo de una base
+// PostgreSQL (information_schema y pg_catalog).
+type PostgresCatalog struct {
+	db Querier
+}
+
+// NewPostgresCatalog crea un catálogo que consulta la base a través de db.
+func NewPostgresCatalog(db Querier) *PostgresCatalog {
+	return &PostgresCatalog{db: db}
+}
+
+// Table busca la tabla con to_regclass, que respeta el search_path, y lee
+// sus columnas y constraints. Los índices UNIQUE que no respaldan un
+// constraint se informan como constraints UNIQUE.
+func (c *PostgresCatalog) Table(name QualifiedName) (*TableSchema, bool) {
+	var relation string
+	err := c.db.QueryRow(`
+        SELECT oid::regclass::text
+        FROM pg_class
+        WHERE oid = to_regclass($1)
+        AND relkind IN ('r', 'p', 'v', 'm', 'f');`, name.String()).Scan(&relation)
+	if err != nil {
+		return nil, false
+	}
+	table := &TableSchema{Name: relation}
+
+	rows, err := c.db.Query(`
+        SELECT c.column_name,
+               CASE WHEN c.data_type = 'USER-DEFINED' THEN c.udt_name ELSE c.data_type END,
+               c.is_nullable = 'YES',
+               COALESCE(c.character_maximum_length, 0)
+        FROM information_schema.columns c
+        JOIN pg_namespace n ON n.nspname = c.table_schema
+        JOIN pg_class r ON r.relnamespace = n.oid AND r.relname = c.table_name
+        WHERE r.oid = to_regclass($1)
+        ORDER BY c.ordinal_position;`, name.String())
+	if err != nil {
+		return nil, false
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column ColumnSchema
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &column.Length); err != nil {
+			return nil, false
+		}
+		table.Columns = append(table.Columns, column)
+	}
+
+	table.Constraints = c.constraints(name)
+	return table, true
+}
+
+// constraintKinds son los valores de pg_constraint.contype.
+var constraintKinds = map[string]string{
+	"p": "PRIMARY KEY", "u": "UNIQUE", "f": "FOREIGN KEY", "c": "CHECK",
+}
+
+// constraints lee los constraints de una tabla, una fila por columna.
+func (c *PostgresCatalog) constraints(name QualifiedName) []ConstraintSchema {
+	var constraints []ConstraintSchema
+	add := func(constraintName, kind, column, references, refColumn string) {
+		last := len(constraints) - 1
+		if last < 0 || constraints[last].Name != constraintName {
+			constraints = append(constraints, ConstraintSchema{Name: constraintName, Kind: kind, References: references})
+			last++
+		}
+		constraints[last].Columns = append(constraints[last].Columns, column)
+		if refColumn != "" {
+			constraints[last].RefColumns = append(constraints[last].RefColumns, refColumn)
+		}
+	}
+
+	rows, err := c.db.Query(`
+        SELECT c.conname, c.contype::text, a.attname,
+               CASE WHEN c.contype = 'f' THEN c.confrelid::regclass::text ELSE '' END,
+               COALESCE(fa.attname, '')
+        FROM pg_constraint c
+        CROSS JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(attnum, fattnum, n)
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+        LEFT JOIN pg_attribute fa ON fa.attrelid = c.confrelid AND fa.attnum = k.fattnum
+        WHERE c.conrelid = to_regclass($1)
+        ORDER BY c.conname, k.n;`, name.String())
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var constraintName, kind, column, references, refColumn string
+		if err := rows.Scan(&constraintName, &kind, &column, &references, &refColumn); err != nil {
+			return constraints
+		}
+		add(constraintName, constraintKinds[kind], column, references, refColumn)
+	}
+
+	indexes, err := c.db.Query(`
+        SELECT i.relname, a.attname
+        FROM pg_index x
+        JOIN pg_class i ON i.oid = x.indexrelid
+        CROSS JOIN LATERAL unnest(x.indkey::smallint[]) WITH ORDINALITY AS k(attnum, n)
+        JOIN pg_attribute a ON a.attrelid = x.indrelid AND a.attnum = k.attnum
+        WHERE x.indrelid = to_regclass($1)
+        AND x.indisunique
+        AND x.indexprs IS NULL
+        AND x.indpred IS NULL
+        AND NOT EXISTS (SELECT FROM pg_constraint c WHERE c.conindid = x.indexrelid)
+        ORDER BY i.relname, k.n;`, name.String())
+	if err != nil {
+		return constraints
+	}
+	defer indexes.Close()
+	for indexes.Next() {
+		var index, column string
+		if err := indexes.Scan(&index, &column); err != nil {
+			return constraints
+		}
+		add(index, "UNIQUE", column, "", "")
+	}
+	return constraints
+}
+
+// relationKinds son los valores de pg_class.relkind de cada tipo de objeto.
+var relationKinds = map[string]string{
+	"r": "TABLE", "p": "TABLE", "v": "VIEW", "m": "MATERIALIZED VIEW",
+	"i": "INDEX", "I": "INDEX", "S": "SEQUENCE",
+}
+
+func (c *PostgresCatalog) ObjectExists(objectType string, name QualifiedName) bool {
+	var exists bool
+	var err error
+	switch objectType {
+	case "TABLE":
+		_, ok := c.Table(name)
+		return ok
+	case "SCHEMA":
+		err = c.db.QueryRow(`SELECT EXISTS (SELECT FROM pg_namespace WHERE nspname = $1);`, name.Name()).Scan(&exists)
+	default:
+		var kinds []string
+		for kind, kindType := range relationKinds {
+			if kindType == objectType {
+				kinds = append(kinds, "'"+kind+"'")
+			}
+		}
+		query := fmt.Sprintf(`
+        SELECT EXISTS (
+            SELECT FROM pg_class
+            WHERE oid = to_regclass($1)
+            AND relkind IN (%s)
+        );`, strings.Join(kinds, ", "))
+		err = c.db.QueryRow(query, name.String()).Scan(&exists)
+	}
+	return err == nil && exists
+}
+
+// Dependents busca las vistas que usan el objeto y las claves foráneas que
+// lo referencian o, para un esquema, todo lo que contiene.
+func (c *PostgresCatalog) Dependents(objectType string, name QualifiedName) []DependentObject {
+	var dependents []DependentObject
+	collect := func(query string, scan func(*sql.Rows) (DependentObject, error)) {
+		rows, err := c.db.Query(query, name.String())
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			dependent, err := scan(rows)
+			if err != nil {
+				return
+			}
+			dependent.DependsOn = name.String()
+			dependents = append(dependents, dependent)
+		}
+	}
+	scanRelation := func(rows *sql.Rows) (DependentObject, error) {
+		var kind, relation string
+		err := rows.Scan(&kind, &relation)
+		return DependentObject{Kind: relationKinds[kind], Name: relation}, err
+	}
+
+	switch objectType {
+	case "TABLE", "VIEW", "MATERIALIZED VIEW":
+		collect(`
+        SELECT DISTINCT v.relkind::text, v.oid::regclass::text
+        FROM pg_depend d
+        JOIN pg_rewrite r ON r.oid = d.objid
+        JOIN pg_class v ON v.oid = r.ev_class
+        WHERE d.classid = 'pg_rewrite'::regclass
+        AND d.refobjid = to_regclass($1)
+        AND v.oid <> d.refobjid;`, scanRelation)
+		if objectType == "TABLE" {
+			collect(`
+            SELECT conname, conrelid::regclass::text
+            FROM pg_constraint
+            WHERE contype = 'f'
+            AND confrelid = to_regclass($1)
+            AND conrelid <> confrelid;`, func(rows *sql.Rows) (DependentObject, error) {
+				dependent := DependentObject{Kind: "FOREIGN KEY"}
+				err := rows.Scan(&dependent.Name, &dependent.Table)
+				return dependent, err
+			})
+		}
+	case "SCHEMA":
+		collect(`
+        SELECT c.relkind::text, c.oid::regclass::text
+        FROM pg_class c
+        JOIN pg_namespace n ON n.oid = c.relnamespace
+        WHERE n.nspname = $1
+        AND c.relkind IN ('r', 'p', 'v', 'm', 'S')
+        ORDER BY 2;`, scanRelation)
+	}
+	return dependents
+}
+
+// Function busca primero entre las funciones incorporadas y después en
+// pg_proc, donde la cantidad de argumentos de una función de usuario se
+// deduce de todas sus sobrecargas.
+func (c *PostgresCatalog) Function(name QualifiedName) (FunctionInfo, bool) {
+	if fn, ok := lookupFunction(name); ok {
+		return fn, true
+	}
+	query := `
+        SELECT p.pronargs - p.pronargdefaults, p.pronargs, p.provariadic <> 0, p.prokind::text
+        FROM pg_proc p
+        JOIN pg_namespace n ON n.oid = p.pronamespace
+        WHERE p.proname = $1
+        AND n.nspname = ANY(current_schemas(true));`
+	args := []interface{}{name.Name()}
+	if schema := name.Qualifier(); schema != "" {
+		query = `
+        SELECT p.pronargs - p.pronargdefaults, p.pronargs, p.provariadic <> 0, p.prokind::text
+        FROM pg_proc p
+        JOIN pg_namespace n ON n.oid = p.pronamespace
+        WHERE p.proname = $1
+        AND n.nspname = $2;`
+		args = append(args, schema)
+	}
+
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return FunctionInfo{}, false
+	}
+	defer rows.Close()
+	fn := FunctionInfo{Name: name.Name(), MinArgs: -1}
+	for rows.Next() {
+		var minArgs, maxArgs int
+		var isVariadic bool
+		var kind string
+		if err := rows.Scan(&minArgs, &maxArgs, &isVariadic, &kind); err != nil {
+			return FunctionInfo{}, false
+		}
+		if fn.MinArgs == -1 || minArgs < fn.MinArgs {
+			fn.MinArgs = minArgs
+		}
+		if isVariadic || fn.MaxArgs == variadic {
+			fn.MaxArgs = variadic
+		} else if maxArgs > fn.MaxArgs {
+			fn.MaxArgs = maxArgs
+		}
+		fn.Aggregate = fn.Aggregate || kind == "a"
+		fn.Window = fn.Window || kind == "w"
+	}
+	return fn, fn.MinArgs != -1
+}
+
+// typeCategoryCodes traduce pg_type.typcategory a las categorías de tipos.
+// Los enums ('E') no se convierten implícitamente a texto y quedan sin
+// categoría.
+var typeCategoryCodes = map[string]string{
+	"N": typeNumeric, "S": typeText, "B": typeBoolean, "D": typeDateTime,
+	"T": typeInterval, "A": typeArray,
+}
+
+// TypeCategory reconoce los tipos incorporados por su nombre y los de
+// usuario (dominios, enums) por su categoría en pg_type.
+func (c *PostgresCatalog) TypeCategory(typeName string) string {
+	if category := catalogTypeCategory(typeName); category != typeUnknown {
+		return category
+	}
+	var code string
+	err := c.db.QueryRow(`SELECT typcategory::text FROM pg_type WHERE oid = to_regtype($1);`, typeName).Scan(&code)
+	if err != nil {
+		return typeUnknown
+	}
+	return typeCategoryCodes[code]
+}
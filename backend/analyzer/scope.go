@@ -0,0 +1,687 @@
+package analyzer
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Ámbitos de nombres. Cada SELECT (incluidas sus subconsultas) define un
+// ámbito con las tablas de su FROM; una subconsulta ve además las tablas de
+// las consultas que la contienen, lo que permite referencias correlacionadas
+// como "WHERE p.uid = u.id" dentro de un EXISTS.
+
+// scopeEntry es un elemento de FROM visible por su nombre: una tabla o una
+// subconsulta con alias. pseudo indica una tabla que no aparece en la
+// sentencia, como EXCLUDED en ON CONFLICT.
+type scopeEntry struct {
+	table   *TableRef
+	derived *DerivedTable
+	pseudo  bool
+}
+
+// tableScope es el ámbito de un SELECT (o de un UPDATE/DELETE). joins son
+// los JOIN ... USING y NATURAL JOIN del FROM, cuyas columnas unidas no son
+// ambiguas entre las tablas que unen. aliases son los alias de la lista del SELECT, que solo
+// pueden usarse en GROUP BY y ORDER BY (aliasesVisible).
+type tableScope struct {
+	entries        map[string]scopeEntry
+	parent         *tableScope
+	joins          []usingJoin
+	aliases        map[string]bool
+	aliasesVisible bool
+}
+
+func newScope(parent *tableScope) *tableScope {
+	return &tableScope{entries: map[string]scopeEntry{}, parent: parent}
+}
+
+// usingJoin es un JOIN ... USING o NATURAL JOIN. entries son los nombres de
+// las tablas de sus dos lados y columns las columnas que une; un NATURAL
+// JOIN une todas las que tienen en común.
+type usingJoin struct {
+	entries map[string]bool
+	columns map[string]bool
+	natural bool
+}
+
+// merged indica si una columna que está en varias tablas (matches) es una
+// sola porque un mismo JOIN ... USING o NATURAL JOIN las une a todas.
+func (scope *tableScope) merged(column string, matches []string) bool {
+	for _, join := range scope.joins {
+		if !join.natural && !join.columns[column] {
+			continue
+		}
+		all := true
+		for _, name := range matches {
+			all = all && join.entries[name]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup busca un nombre en el ámbito y, si no está, en los que lo
+// contienen.
+func (scope *tableScope) lookup(name string) (scopeEntry, bool) {
+	for s := scope; s != nil; s = s.parent {
+		if entry, ok := s.entries[name]; ok {
+			return entry, true
+		}
+	}
+	return scopeEntry{}, false
+}
+
+// add registra un elemento de FROM; dos elementos con el mismo nombre
+// visible son un error.
+func (info *SemanticInfo) addToScope(scope *tableScope, name string, entry scopeEntry, span Span) {
+	if _, exists := scope.entries[name]; exists {
+		info.addError(span, "el nombre '%s' aparece más de una vez en FROM, use un alias distinto", name)
+		return
+	}
+	scope.entries[name] = entry
+}
+
+// checkScopes verifica los nombres de la sentencia y devuelve el ámbito de
+// su nivel superior (nil si la sentencia no tiene FROM).
+func (info *SemanticInfo) checkScopes(stmt Statement) *tableScope {
+	switch s := stmt.(type) {
+	case Query:
+		return info.checkQuery(s, nil)
+	case *UpdateStmt:
+		return info.checkModifyScope(s, s.Table, s.From)
+	case *InsertStmt:
+		info.checkInsertScopes(s)
+		return nil
+	case *DeleteStmt:
+		return info.checkModifyScope(s, s.Table, s.Using)
+	case *CreateIndexStmt:
+		// Las expresiones del índice usan las columnas de la tabla
+		scope := newScope(nil)
+		info.addToScope(scope, s.Table.VisibleName(), scopeEntry{table: s.Table}, s.Table.Span)
+		for _, expr := range s.Expressions {
+			info.checkNames(expr, scope)
+		}
+		return scope
+	default:
+		info.checkNames(stmt, nil)
+		return nil
+	}
+}
+
+// checkModifyScope arma el ámbito de un UPDATE o DELETE con la tabla que
+// modifica y las de su FROM o USING, y verifica con él el resto de la
+// sentencia.
+func (info *SemanticInfo) checkModifyScope(stmt Statement, target *TableRef, from []TableExpr) *tableScope {
+	scope := newScope(nil)
+	info.addToScope(scope, target.VisibleName(), scopeEntry{table: target}, target.Span)
+	var joins []*JoinExpr
+	for _, item := range from {
+		joins = info.addFromItem(scope, nil, item, joins)
+	}
+	for _, join := range joins {
+		if join.On != nil {
+			info.checkNames(join.On, scope)
+		}
+	}
+	for _, child := range stmt.children() {
+		if _, ok := child.(TableExpr); !ok {
+			info.checkNames(child, scope)
+		}
+	}
+	return scope
+}
+
+// checkInsertScopes verifica los nombres de un INSERT. VALUES y la consulta
+// de origen no ven la tabla de destino; ON CONFLICT ve además la fila
+// propuesta como EXCLUDED, y RETURNING ve la tabla de destino.
+func (info *SemanticInfo) checkInsertScopes(stmt *InsertStmt) {
+	for _, row := range stmt.Values {
+		for _, value := range row {
+			info.checkNames(value, nil)
+			Walk(value, func(node Node) bool {
+				switch n := node.(type) {
+				case Query:
+					return false
+				case *ColumnRef:
+					info.addError(n.Span, "VALUES no puede referirse a la columna '%s': use SELECT para tomar valores de otra tabla", n.Name.String())
+				}
+				return true
+			})
+		}
+	}
+	if stmt.Query != nil {
+		info.checkQuery(stmt.Query, nil)
+	}
+
+	scope := newScope(nil)
+	info.addToScope(scope, stmt.Table.VisibleName(), scopeEntry{table: stmt.Table}, stmt.Table.Span)
+	if stmt.OnConflict != nil {
+		conflict := newScope(scope)
+		excluded := &TableRef{Name: stmt.Table.Name, Alias: Identifier{Name: "excluded"}, Span: stmt.OnConflict.Span}
+		info.addToScope(conflict, "excluded", scopeEntry{table: excluded, pseudo: true}, excluded.Span)
+		info.checkNames(stmt.OnConflict, conflict)
+	}
+	for _, item := range stmt.Returning {
+		info.checkNames(item, scope)
+	}
+}
+
+// checkQuery verifica una consulta y devuelve el ámbito de su FROM. Las
+// operaciones de conjuntos no tienen un ámbito propio y devuelven nil.
+func (info *SemanticInfo) checkQuery(query Query, parent *tableScope) *tableScope {
+	switch q := query.(type) {
+	case *SelectStmt:
+		info.checkWith(q.With, parent)
+		return info.checkSelect(q, parent)
+	case *SetOperation:
+		info.checkSetBranches(q, parent)
+		info.checkSetOperation(q)
+	}
+	return nil
+}
+
+// checkSetBranches verifica cada rama de una operación de conjuntos. Las
+// operaciones anidadas se recorren aquí para que checkSetOperation compare
+// todas las ramas una sola vez, desde la raíz.
+func (info *SemanticInfo) checkSetBranches(query Query, parent *tableScope) {
+	set, ok := query.(*SetOperation)
+	if !ok {
+		info.checkQuery(query, parent)
+		return
+	}
+	info.checkWith(set.With, parent)
+	info.checkSetBranches(set.Left, parent)
+	info.checkSetBranches(set.Right, parent)
+	info.checkSetOrderBy(set)
+	for _, limit := range []Expr{set.Limit, set.Offset} {
+		if limit != nil {
+			info.checkNames(limit, nil)
+		}
+	}
+}
+
+// checkSetOrderBy verifica el ORDER BY de una operación de conjuntos. Como
+// en PostgreSQL, solo puede nombrar columnas del resultado (con los nombres
+// de la primera rama) o indicar su posición; no admite expresiones.
+func (info *SemanticInfo) checkSetOrderBy(set *SetOperation) {
+	names, known := outputColumns(set)
+	for _, item := range set.OrderBy {
+		switch expr := item.Expr.(type) {
+		case *Literal:
+			position, err := strconv.Atoi(expr.Value)
+			if expr.Kind != "NUMERO" || err != nil {
+				break
+			}
+			if known && (position < 1 || position > len(names)) {
+				info.addError(expr.Span, "la posición %d de ORDER BY no está entre 1 y %d, las columnas del resultado de %s",
+					position, len(names), set.Op)
+			}
+			continue
+		case *ColumnRef:
+			if len(expr.Name.Parts) > 1 || expr.IsStar() {
+				break
+			}
+			if known && !containsName(names, expr.Name.Name()) {
+				info.addError(expr.Span, "la columna '%s' de ORDER BY no está en el resultado de %s: use una de sus columnas (%s) o su posición",
+					expr.Name.String(), set.Op, strings.Join(names, ", "))
+			}
+			continue
+		}
+		info.addError(item.Expr.Location(), "el ORDER BY de %s solo admite nombres o posiciones de las columnas del resultado, no expresiones",
+			set.Op)
+	}
+}
+
+// checkWith verifica las CTE de una cláusula WITH. Las CTE no ven el FROM
+// de la consulta, solo el ámbito exterior.
+func (info *SemanticInfo) checkWith(with *WithClause, parent *tableScope) {
+	if with == nil {
+		return
+	}
+	for _, cte := range with.CTEs {
+		info.checkQuery(cte.Query, parent)
+		if columns, known := outputColumns(cte.Query); known && len(cte.Columns) > len(columns) {
+			info.addError(cte.Span, "la CTE '%s' devuelve %d columnas pero se indicaron %d nombres",
+				cte.Name.String(), len(columns), len(cte.Columns))
+		}
+	}
+}
+
+// checkSelect arma el ámbito de un SELECT a partir de su FROM y verifica
+// con él el resto de la consulta.
+func (info *SemanticInfo) checkSelect(query *SelectStmt, parent *tableScope) *tableScope {
+	scope := newScope(parent)
+	var joins []*JoinExpr
+	for _, from := range query.From {
+		joins = info.addFromItem(scope, parent, from, joins)
+	}
+
+	// Las condiciones ON se verifican con todo el FROM ya registrado
+	for _, join := range joins {
+		if join.On != nil {
+			info.checkNames(join.On, scope)
+		}
+	}
+	// GROUP BY y ORDER BY pueden usar los alias de la lista del SELECT
+	scope.aliases = map[string]bool{}
+	for _, item := range query.Columns {
+		if item.Alias.Name != "" {
+			scope.aliases[item.Alias.Name] = true
+		}
+	}
+	sortScope := *scope
+	sortScope.aliasesVisible = true
+	sorting := map[Node]bool{}
+	for _, expr := range query.GroupBy {
+		sorting[expr] = true
+	}
+	for _, item := range query.OrderBy {
+		sorting[item] = true
+	}
+
+	for _, child := range query.children() {
+		switch {
+		case isTableExpr(child):
+		case sorting[child]:
+			info.checkNames(child, &sortScope)
+		default:
+			info.checkNames(child, scope)
+		}
+	}
+	return scope
+}
+
+func isTableExpr(node Node) bool {
+	switch node.(type) {
+	case TableExpr, *WithClause:
+		return true
+	}
+	return false
+}
+
+// addFromItem registra en el ámbito las tablas de un elemento de FROM y
+// devuelve los JOINs encontrados. Una subconsulta en FROM no ve las tablas
+// hermanas, salvo que sea LATERAL.
+func (info *SemanticInfo) addFromItem(scope, parent *tableScope, item TableExpr, joins []*JoinExpr) []*JoinExpr {
+	switch t := item.(type) {
+	case *TableRef:
+		info.addToScope(scope, t.VisibleName(), scopeEntry{table: t}, t.Span)
+	case *DerivedTable:
+		outer := parent
+		if t.Lateral {
+			outer = scope
+		}
+		info.checkQuery(t.Query, outer)
+		if columns, known := outputColumns(t.Query); known && len(t.ColumnAliases) > len(columns) {
+			info.addError(t.Span, "la subconsulta '%s' devuelve %d columnas pero se indicaron %d alias",
+				t.Alias.String(), len(columns), len(t.ColumnAliases))
+		}
+		info.addToScope(scope, t.Alias.Name, scopeEntry{derived: t}, t.Span)
+	case *JoinExpr:
+		joins = info.addFromItem(scope, parent, t.Left, joins)
+		joins = info.addFromItem(scope, parent, t.Right, joins)
+		joins = append(joins, t)
+		if len(t.Using) == 0 && !t.Natural {
+			break
+		}
+		join := usingJoin{entries: map[string]bool{}, columns: map[string]bool{}, natural: t.Natural}
+		for _, column := range t.Using {
+			if info.checkUsingColumn(column, t.Left) {
+				info.checkUsingColumn(column, t.Right)
+			}
+			join.columns[column.Name] = true
+		}
+		var entries []scopeEntry
+		collectEntries(t, &entries)
+		for _, entry := range entries {
+			if entry.derived != nil {
+				join.entries[entry.derived.Alias.Name] = true
+			} else {
+				join.entries[entry.table.VisibleName()] = true
+			}
+		}
+		scope.joins = append(scope.joins, join)
+	}
+	return joins
+}
+
+// checkUsingColumn verifica que una columna de JOIN ... USING exista en uno
+// de los lados del JOIN y devuelve false si informó que no existe.
+func (info *SemanticInfo) checkUsingColumn(column Identifier, side TableExpr) bool {
+	var entries []scopeEntry
+	collectEntries(side, &entries)
+	var tables []string
+	for _, entry := range entries {
+		columns, known := info.entryColumns(entry)
+		if !known {
+			return true
+		}
+		if _, ok := columns[column.Name]; ok {
+			return true
+		}
+		tables = append(tables, entryName(entry))
+	}
+	switch {
+	case len(entries) == 0:
+		return true
+	case len(entries) > 1:
+		info.addError(column.Span, "la columna '%s' de USING no existe en ninguna de las tablas de ese lado del JOIN: %s",
+			column.String(), strings.Join(tables, ", "))
+	case entries[0].derived != nil:
+		info.addError(column.Span, "la subconsulta '%s' no devuelve la columna '%s'", tables[0], column.String())
+	case entries[0].table.CTE != nil:
+		info.addError(column.Span, "la CTE '%s' no devuelve la columna '%s'", tables[0], column.String())
+	default:
+		info.addError(column.Span, "la columna '%s' no existe en la tabla '%s'", column.String(), tables[0])
+	}
+	return false
+}
+
+// collectEntries agrega los elementos de FROM que forman un lado de un JOIN.
+func collectEntries(item TableExpr, entries *[]scopeEntry) {
+	switch t := item.(type) {
+	case *TableRef:
+		*entries = append(*entries, scopeEntry{table: t})
+	case *DerivedTable:
+		*entries = append(*entries, scopeEntry{derived: t})
+	case *JoinExpr:
+		collectEntries(t.Left, entries)
+		collectEntries(t.Right, entries)
+	}
+}
+
+// checkNames recorre una parte de la sentencia verificando las columnas
+// calificadas y las subconsultas que contiene.
+func (info *SemanticInfo) checkNames(node Node, scope *tableScope) {
+	Walk(node, func(node Node) bool {
+		switch n := node.(type) {
+		case Query:
+			info.checkQuery(n, scope)
+			return false
+		case *Assignment:
+			// La subconsulta de "(a, b) = (SELECT ...)" devuelve varias
+			// columnas: checkAssignments compara su cantidad con la lista
+			if subquery, ok := n.Value.(*SubqueryExpr); ok && len(n.Columns) > 0 {
+				info.checkQuery(subquery.Query, scope)
+				return false
+			}
+		case *SubqueryExpr:
+			info.checkSingleColumn(n.Query, "una subconsulta escalar")
+		case *InExpr:
+			if n.Subquery != nil {
+				info.checkSingleColumn(n.Subquery, "una subconsulta de IN")
+			}
+		case *QuantifiedExpr:
+			if n.Query != nil {
+				info.checkSingleColumn(n.Query, "una subconsulta de "+n.Quantifier)
+			}
+		case *ColumnRef:
+			if scope != nil {
+				info.resolveColumn(n, scope)
+			}
+		}
+		return true
+	})
+}
+
+// checkSingleColumn verifica que una subconsulta usada como valor devuelva
+// exactamente una columna.
+func (info *SemanticInfo) checkSingleColumn(query Query, what string) {
+	columns, known := outputColumns(query)
+	if known && len(columns) != 1 {
+		info.addError(query.Location(), "%s debe devolver una sola columna, devuelve %d", what, len(columns))
+	}
+}
+
+// resolveColumn busca la tabla de una columna en el ámbito y registra su
+// tipo en info.Columns. Una columna calificada (u.nombre) debe usar un
+// alias o nombre de tabla visible, y la tabla debe tener esa columna. Una
+// sin calificar se busca en las tablas de cada nivel, del más interno al
+// más externo, y es un error que esté en más de una del mismo nivel o que
+// no esté en ninguna.
+func (info *SemanticInfo) resolveColumn(col *ColumnRef, scope *tableScope) {
+	qualifier := col.Name.Qualifier()
+	column := col.Name.Parts[len(col.Name.Parts)-1]
+	if qualifier == "" {
+		if !col.IsStar() {
+			info.resolveUnqualified(col, column, scope)
+		}
+		return
+	}
+
+	entry, ok := scope.lookup(qualifier)
+	if !ok {
+		info.unknownQualifier(col, qualifier, column, scope)
+		return
+	}
+	if col.IsStar() {
+		return
+	}
+	columns, known := info.entryColumns(entry)
+	if !known {
+		return
+	}
+	schema, ok := columns[column.Name]
+	if !ok {
+		switch {
+		case entry.derived != nil:
+			info.addError(col.Span, "la subconsulta '%s' no devuelve la columna '%s'", qualifier, column.String())
+		case entry.table.CTE != nil:
+			info.addError(col.Span, "la CTE '%s' no devuelve la columna '%s'", entry.table.CTE.Name.String(), column.String())
+		default:
+			info.addError(col.Span, "la columna '%s' no existe en la tabla '%s'", column.String(), entry.table.Name.String())
+		}
+		info.addColumnInfo(column.Name, col.Span, entryName(entry), nil)
+		return
+	}
+	info.addColumnInfo(column.Name, col.Span, entryName(entry), &schema)
+	info.columnTypes[col] = schema
+}
+
+// resolveUnqualified resuelve una columna sin calificar.
+func (info *SemanticInfo) resolveUnqualified(col *ColumnRef, column Identifier, scope *tableScope) {
+	var searched []string
+	for s := scope; s != nil; s = s.parent {
+		var matches []string
+		unknown := false
+		for _, name := range sortedEntries(s) {
+			entry := s.entries[name]
+			if entry.pseudo {
+				// EXCLUDED solo se usa calificada
+				continue
+			}
+			columns, known := info.entryColumns(entry)
+			if !known {
+				unknown = true
+				continue
+			}
+			searched = append(searched, entryName(entry))
+			if _, ok := columns[column.Name]; ok {
+				matches = append(matches, name)
+			}
+		}
+
+		switch {
+		case len(matches) == 1 || len(matches) > 1 && s.merged(column.Name, matches):
+			entry := s.entries[matches[0]]
+			columns, _ := info.entryColumns(entry)
+			schema := columns[column.Name]
+			info.addColumnInfo(column.Name, col.Span, entryName(entry), &schema)
+			info.columnTypes[col] = schema
+			return
+		case len(matches) > 1 && s.aliasesVisible && s.aliases[column.Name]:
+			return
+		case len(matches) > 1:
+			var tables []string
+			for _, name := range matches {
+				tables = append(tables, "'"+entryName(s.entries[name])+"'")
+			}
+			info.addError(col.Span, "la columna '%s' es ambigua: existe en %s; califíquela con el nombre o alias de la tabla",
+				column.String(), strings.Join(tables, ", "))
+			info.addColumnInfo(column.Name, col.Span, "", nil)
+			return
+		case s.aliasesVisible && s.aliases[column.Name]:
+			return
+		case unknown:
+			// Puede pertenecer a una tabla cuyas columnas no se conocen
+			return
+		}
+		if s.aliases[column.Name] {
+			info.addError(col.Span, "'%s' es un alias de la lista del SELECT y solo puede usarse en GROUP BY y ORDER BY; repita la expresión",
+				column.String())
+			return
+		}
+	}
+
+	// Una tabla sin calificar como valor es la fila completa: SELECT t FROM t
+	if _, ok := scope.lookup(column.Name); ok {
+		return
+	}
+	if len(searched) == 0 {
+		info.addError(col.Span, "la columna '%s' no existe: la consulta no tiene FROM", column.String())
+	} else {
+		info.addError(col.Span, "la columna '%s' no existe en ninguna de las tablas de FROM: %s", column.String(), strings.Join(searched, ", "))
+	}
+	info.addColumnInfo(column.Name, col.Span, "", nil)
+}
+
+// unknownQualifier informa una columna calificada con un nombre que no está
+// en FROM. Una tabla con alias solo puede referenciarse por su alias.
+func (info *SemanticInfo) unknownQualifier(col *ColumnRef, qualifier string, column Identifier, scope *tableScope) {
+	for s := scope; s != nil; s = s.parent {
+		for _, entry := range s.entries {
+			if ref := entry.table; ref != nil && !entry.pseudo && ref.Alias.Name != "" && ref.Name.Name() == qualifier {
+				info.addError(col.Span, "la tabla '%s' tiene el alias '%s', use '%s.%s'",
+					ref.Name.String(), ref.Alias.Name, ref.Alias.String(), column.String())
+				return
+			}
+		}
+	}
+	info.addError(col.Span, "la tabla o alias '%s' de la columna '%s' no aparece en FROM", qualifier, col.Name.String())
+}
+
+// entryColumns devuelve las columnas de un elemento de FROM. Las de las
+// subconsultas y CTE no tienen tipo. known es false si no se pueden conocer:
+// la tabla no existe o la subconsulta usa '*'.
+func (info *SemanticInfo) entryColumns(entry scopeEntry) (map[string]ColumnSchema, bool) {
+	var names []string
+	var known bool
+	switch {
+	case entry.derived != nil:
+		names, known = renamedColumns(entry.derived.Query, entry.derived.ColumnAliases)
+	case entry.table.CTE != nil:
+		names, known = renamedColumns(entry.table.CTE.Query, entry.table.CTE.Columns)
+	default:
+		return info.columnsOf(entry.table.Name)
+	}
+	if !known {
+		return nil, false
+	}
+	columns := map[string]ColumnSchema{}
+	for _, name := range names {
+		columns[name] = ColumnSchema{Name: name, Nullable: true}
+	}
+	return columns, true
+}
+
+// entryName es el nombre con el que se informa un elemento de FROM: el de
+// la tabla o CTE, o el alias de la subconsulta.
+func entryName(entry scopeEntry) string {
+	switch {
+	case entry.derived != nil:
+		return entry.derived.Alias.String()
+	case entry.table.CTE != nil:
+		return entry.table.CTE.Name.String()
+	case entry.pseudo:
+		return entry.table.Alias.String()
+	}
+	return entry.table.Name.String()
+}
+
+// sortedEntries devuelve los nombres del ámbito en orden, para que los
+// mensajes no dependan del orden de los mapas.
+func sortedEntries(scope *tableScope) []string {
+	names := make([]string, 0, len(scope.entries))
+	for name := range scope.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// outputItems devuelve la lista de columnas que define el resultado de una
+// consulta; en una operación de conjuntos es la de su primera rama. known
+// es false si la lista incluye '*', porque entonces las columnas dependen
+// de las tablas.
+func outputItems(query Query) (items []*SelectItem, known bool) {
+	switch q := query.(type) {
+	case *SelectStmt:
+		for _, item := range q.Columns {
+			if column, ok := item.Expr.(*ColumnRef); ok && column.IsStar() {
+				return nil, false
+			}
+		}
+		return q.Columns, true
+	case *SetOperation:
+		return outputItems(q.Left)
+	}
+	return nil, false
+}
+
+// outputColumns devuelve los nombres de las columnas que produce una
+// consulta.
+func outputColumns(query Query) (names []string, known bool) {
+	items, known := outputItems(query)
+	if !known {
+		return nil, false
+	}
+	for _, item := range items {
+		names = append(names, itemName(item))
+	}
+	return names, true
+}
+
+// itemName es el nombre de la columna que produce un elemento de la lista
+// del SELECT: su alias o, como en PostgreSQL, el de la columna o función,
+// "case" o "?column?".
+func itemName(item *SelectItem) string {
+	if item.Alias.Name != "" {
+		return item.Alias.Name
+	}
+	switch expr := item.Expr.(type) {
+	case *ColumnRef:
+		return expr.Name.Name()
+	case *FuncCall:
+		return expr.Name.Name()
+	case *CaseExpr:
+		return "case"
+	}
+	return "?column?"
+}
+
+// renamedColumns devuelve las columnas de una subconsulta de FROM o de una
+// CTE teniendo en cuenta los nombres que les asigna su lista de alias.
+func renamedColumns(query Query, aliases []Identifier) ([]string, bool) {
+	names, known := outputColumns(query)
+	if !known {
+		return nil, false
+	}
+	for i, alias := range aliases {
+		if i < len(names) {
+			names[i] = alias.Name
+		}
+	}
+	return names, true
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package analyzer
+
+import "testing"
+
+func TestColumnResolution(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT nombre, edad FROM usuarios", valid: true},
+		{query: "SELECT usuarios.nombre FROM usuarios", valid: true},
+		{query: "SELECT apellido FROM usuarios", error: "la columna 'apellido' no existe"},
+		{query: "SELECT usuarios.apellido FROM usuarios", error: "no existe en la tabla 'usuarios'"},
+		{query: "SELECT nombre FROM usuarios WHERE total > 1", error: "'total' no existe"},
+		{query: "SELECT x.nombre FROM usuarios", error: "'x' de la columna 'x.nombre' no aparece en FROM"},
+		{query: "SELECT 1 WHERE zz = 1", error: "la consulta no tiene FROM"},
+		{query: "SELECT id, nombre FROM usuarios_activos", valid: true},
+		{query: "SELECT edad FROM usuarios_activos", error: "'edad' no existe"},
+	})
+}
+
+func TestJoinScopes(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT u.nombre, p.total FROM usuarios u JOIN pedidos p ON p.usuario_id = u.id", valid: true},
+		{query: "SELECT nombre, total FROM usuarios JOIN pedidos ON usuario_id = usuarios.id", valid: true},
+		{query: "SELECT * FROM usuarios u LEFT JOIN pedidos p ON p.usuario_id = u.id WHERE p.id IS NULL", valid: true},
+		{query: "SELECT * FROM usuarios CROSS JOIN productos", valid: true},
+		{query: "SELECT id FROM usuarios JOIN pedidos ON pedidos.usuario_id = usuarios.id", error: "la columna 'id' es ambigua"},
+		{query: "SELECT * FROM usuarios u JOIN pedidos p ON p.cliente_id = u.id", error: "cliente_id"},
+		{query: "SELECT * FROM usuarios JOIN usuarios ON true", error: "aparece más de una vez en FROM"},
+		{query: "SELECT * FROM usuarios u JOIN usuarios v ON u.id = v.id", valid: true},
+	})
+}
+
+func TestAliases(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT u.nombre AS n FROM usuarios AS u ORDER BY n", valid: true},
+		{query: "SELECT u.nombre n FROM usuarios u GROUP BY n", valid: true},
+		{query: "SELECT usuarios.nombre FROM usuarios u", error: "tiene el alias 'u', use 'u.nombre'"},
+		{query: "SELECT nombre AS n FROM usuarios WHERE n = 'a'", error: "'n' es un alias de la lista del SELECT"},
+		{query: "UPDATE usuarios u SET edad = 1 WHERE u.id = 1", valid: true},
+		{query: "DELETE FROM pedidos p WHERE p.total > 100", valid: true},
+		{query: "DELETE FROM pedidos p WHERE pedidos.total > 100", error: "tiene el alias 'p'"},
+	})
+}
+
+func TestJoinUsing(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT id, nombre FROM usuarios JOIN usuarios_activos USING (id, nombre)", valid: true},
+		{query: "SELECT id FROM usuarios u JOIN usuarios v USING (id) JOIN usuarios w USING (id)", valid: true},
+		{query: "SELECT id FROM usuarios NATURAL JOIN usuarios_activos", valid: true},
+		{query: "SELECT * FROM usuarios JOIN pedidos USING (zz)", error: "la columna 'zz' no existe en la tabla 'usuarios'"},
+		{query: "SELECT * FROM usuarios JOIN pedidos USING (edad)", error: "la columna 'edad' no existe en la tabla 'pedidos'"},
+		{query: "SELECT * FROM usuarios u JOIN (SELECT 1 AS x) s USING (id)", error: "la subconsulta 's' no devuelve la columna 'id'"},
+		{
+			query: "SELECT id FROM usuarios u JOIN usuarios_activos v USING (id) JOIN pedidos p ON p.usuario_id = u.id",
+			error: "la columna 'id' es ambigua",
+		},
+		{query: "SELECT id FROM usuarios JOIN usuarios_activos USING (id), pedidos", error: "la columna 'id' es ambigua"},
+		{query: "SELECT id FROM usuarios NATURAL JOIN usuarios_activos, pedidos", error: "la columna 'id' es ambigua"},
+	})
+}
+
+func TestSubqueriesAndCTEs(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT nombre FROM usuarios u WHERE EXISTS (SELECT 1 FROM pedidos p WHERE p.usuario_id = u.id)", valid: true},
+		{query: "SELECT nombre FROM usuarios WHERE id IN (SELECT usuario_id FROM pedidos)", valid: true},
+		{query: "SELECT nombre FROM usuarios WHERE id IN (SELECT usuario_id, total FROM pedidos)", error: "una sola columna"},
+		{query: "SELECT s.n FROM (SELECT nombre AS n FROM usuarios) s", valid: true},
+		{query: "SELECT s.nombre FROM (SELECT nombre AS n FROM usuarios) s", error: "la subconsulta 's' no devuelve la columna 'nombre'"},
+		{query: "WITH activos AS (SELECT id FROM usuarios WHERE activo) SELECT id FROM activos", valid: true},
+		{query: "WITH activos AS (SELECT id FROM usuarios) SELECT activos.nombre FROM activos", error: "la CTE 'activos' no devuelve la columna 'nombre'"},
+	})
+}
+
+func TestSetOperationScopes(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT nombre FROM usuarios UNION SELECT nombre FROM productos", valid: true},
+		{query: "SELECT id, nombre AS n FROM usuarios UNION ALL SELECT id, nombre FROM productos ORDER BY n DESC, 1", valid: true},
+		{query: "SELECT nombre FROM usuarios INTERSECT SELECT nombre FROM productos ORDER BY nombre LIMIT 5", valid: true},
+		{query: "SELECT id FROM usuarios EXCEPT SELECT id FROM pedidos LIMIT ALL", valid: true},
+		{query: "SELECT id FROM usuarios UNION SELECT id FROM pedidos LIMIT (SELECT count(*) FROM productos)", valid: true},
+		{query: "SELECT nombre FROM usuarios UNION SELECT nombre FROM productos ORDER BY zz", error: "la columna 'zz' de ORDER BY no está en el resultado de UNION"},
+		{query: "SELECT nombre FROM usuarios UNION SELECT nombre FROM productos ORDER BY edad", error: "la columna 'edad' de ORDER BY"},
+		{query: "SELECT nombre FROM usuarios UNION SELECT nombre FROM productos ORDER BY 2", error: "la posición 2 de ORDER BY"},
+		{query: "SELECT id FROM usuarios UNION SELECT id FROM pedidos ORDER BY id + 1", error: "no expresiones"},
+		{query: "SELECT id, nombre FROM usuarios UNION SELECT id FROM pedidos", error: "columnas"},
+		{query: "SELECT id FROM usuarios UNION SELECT zz FROM pedidos", error: "'zz' no existe"},
+	})
+}
@@ -0,0 +1,218 @@
+package analyzer
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ScriptStatement es una de las sentencias de un script. Span ubica la
+// sentencia dentro del script, y los análisis de la sentencia informan sus
+// tokens, nodos y errores en esas mismas coordenadas.
+type ScriptStatement struct {
+	Index int    `json:"index"`
+	Text  string `json:"text"`
+	Span
+}
+
+var scriptWordPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_$]*`)
+
+// SplitScript divide un script en sentencias separadas por ';'. Los ';'
+// dentro de cadenas (incluidas las $$...$$), identificadores entre comillas
+// y comentarios no separan sentencias. Los tramos vacíos o que solo tienen
+// comentarios se omiten. Un texto sin cerrar se extiende hasta el final del
+// script, y el análisis de esa sentencia informa el error.
+func SplitScript(script string) []ScriptStatement {
+	var statements []ScriptStatement
+	begin := startSpan.Start
+	pos := begin
+
+	add := func(end Position) {
+		text := script[begin.Offset:end.Offset]
+		trimmed := strings.TrimLeft(text, " \t\r\n")
+		start := begin.advance(text[:len(text)-len(trimmed)])
+		trimmed = strings.TrimRight(trimmed, " \t\r\n")
+		if hasStatement(trimmed, start) {
+			statements = append(statements, ScriptStatement{
+				Index: len(statements),
+				Text:  trimmed,
+				Span:  Span{Start: start, End: start.advance(trimmed)},
+			})
+		}
+	}
+
+	for pos.Offset < len(script) {
+		rest := script[pos.Offset:]
+		length := 1
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			if length = strings.IndexByte(rest, '\n'); length == -1 {
+				length = len(rest)
+			}
+		case strings.HasPrefix(rest, "/*"):
+			var closed bool
+			if length, closed = scanBlockComment(rest); !closed {
+				length = len(rest)
+			}
+		case rest[0] == '"':
+			if length, _ = scanQuoted(rest, 1, '"', false); length < 0 {
+				length = len(rest)
+			}
+		case rest[0] == ';':
+			pos = pos.advance(";")
+			add(pos)
+			begin = pos
+			continue
+		default:
+			if n, _, kind := scanString(rest); kind != "" {
+				if length = n; length < 0 {
+					length = len(rest)
+				}
+			} else if word := scriptWordPattern.FindString(rest); word != "" {
+				// Las palabras se saltan enteras para que un '$' dentro de un
+				// identificador no se tome como inicio de una cadena $$
+				length = len(word)
+			}
+		}
+		pos = pos.advance(rest[:length])
+	}
+	add(pos)
+
+	return statements
+}
+
+// hasStatement indica si el tramo tiene algo más que comentarios y ';'. Un
+// tramo con errores léxicos cuenta como sentencia para que se informen.
+func hasStatement(text string, start Position) bool {
+	if text == "" {
+		return false
+	}
+	tokens, err := lexAt(text, start)
+	if err != nil {
+		return true
+	}
+	for _, token := range withoutComments(tokens) {
+		if token.Value != ";" {
+			return true
+		}
+	}
+	return false
+}
+
+// Lexical hace el análisis léxico de la sentencia.
+func (s ScriptStatement) Lexical() ([]Token, error) {
+	return lexAt(s.Text, s.Start)
+}
+
+// Syntactic hace el análisis sintáctico de la sentencia.
+func (s ScriptStatement) Syntactic() (Statement, error) {
+	return parseStatementAt(s.Text, s.Start)
+}
+
+// Semantic hace el análisis semántico de la sentencia contra
+// DefaultCatalog.
+func (s ScriptStatement) Semantic() (*SemanticInfo, error) {
+	return s.SemanticWithCatalog(DefaultCatalog)
+}
+
+// SemanticWithCatalog hace el análisis semántico de la sentencia contra el
+// catálogo indicado.
+func (s ScriptStatement) SemanticWithCatalog(catalog Catalog) (*SemanticInfo, error) {
+	stmt, err := s.Syntactic()
+	if err != nil {
+		return nil, err
+	}
+	return analyzeSemantics(stmt, catalog), nil
+}
+
+// ScriptAnalyzer hace el análisis semántico de las sentencias de un script
+// en orden, sobre una copia de trabajo del catálogo a la que aplica cada
+// CREATE, ALTER y DROP válido. Así una sentencia se verifica contra el
+// esquema tal como quedará después de las anteriores, sin modificar el
+// catálogo original. Como en PostgreSQL, un ROLLBACK deshace los cambios
+// de esquema hechos desde el BEGIN, y un ROLLBACK TO los hechos desde el
+// SAVEPOINT.
+type ScriptAnalyzer struct {
+	catalog *MemoryCatalog
+	// begin es el estado del catálogo al abrir la transacción, o nil.
+	begin *MemoryCatalog
+	// savepoints son los savepoints de la transacción, del más antiguo al
+	// más reciente.
+	savepoints []savepoint
+}
+
+// savepoint es el estado del catálogo al crear un SAVEPOINT.
+type savepoint struct {
+	name    string
+	catalog *MemoryCatalog
+}
+
+// NewScriptAnalyzer crea un analizador que parte de los objetos de catalog.
+func NewScriptAnalyzer(catalog Catalog) *ScriptAnalyzer {
+	return &ScriptAnalyzer{catalog: NewOverlayCatalog(catalog)}
+}
+
+// Semantic analiza la sentencia contra el estado actual del script y, si
+// es válida, aplica sus cambios de esquema. Un error al aplicarlos (por
+// ejemplo, crear una tabla que ya existe) invalida la sentencia.
+func (a *ScriptAnalyzer) Semantic(s ScriptStatement) (*SemanticInfo, error) {
+	stmt, err := s.Syntactic()
+	if err != nil {
+		return nil, err
+	}
+	info := analyzeSemantics(stmt, a.catalog)
+	if !info.Valid {
+		return info, nil
+	}
+
+	if transaction, ok := stmt.(*TransactionStmt); ok {
+		switch transaction.Kind {
+		case "BEGIN":
+			a.begin, a.savepoints = a.catalog.clone(), nil
+		case "COMMIT":
+			a.begin, a.savepoints = nil, nil
+		case "ROLLBACK":
+			if a.begin != nil {
+				a.catalog, a.begin, a.savepoints = a.begin, nil, nil
+			}
+		default:
+			a.applySavepoint(transaction, info)
+		}
+		return info, nil
+	}
+	var applyErr *AnalysisError
+	if errors.As(a.catalog.Apply(stmt), &applyErr) {
+		info.addError(applyErr.Span, "%s", applyErr.Message)
+	}
+	return info, nil
+}
+
+// applySavepoint aplica un SAVEPOINT, ROLLBACK TO o RELEASE. ROLLBACK TO vuelve
+// al estado del savepoint, que sigue existiendo, y RELEASE lo elimina; ambos
+// eliminan los savepoints creados después. Fuera de una transacción no
+// tienen efecto.
+func (a *ScriptAnalyzer) applySavepoint(stmt *TransactionStmt, info *SemanticInfo) {
+	if a.begin == nil {
+		return
+	}
+	name := stmt.Savepoint.Name
+	if stmt.Kind == "SAVEPOINT" {
+		a.savepoints = append(a.savepoints, savepoint{name: name, catalog: a.catalog.clone()})
+		return
+	}
+
+	i := len(a.savepoints) - 1
+	for i >= 0 && a.savepoints[i].name != name {
+		i--
+	}
+	if i < 0 {
+		info.addError(stmt.Savepoint.Span, "el savepoint '%s' no existe en la transacción", stmt.Savepoint.String())
+		return
+	}
+	if stmt.Kind == "ROLLBACK TO" {
+		a.catalog = a.savepoints[i].catalog.clone()
+		a.savepoints = a.savepoints[:i+1]
+	} else {
+		a.savepoints = a.savepoints[:i]
+	}
+}
@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"una sentencia", "SELECT 1", []string{"SELECT 1"}},
+		{"varias sentencias", "SELECT 1; SELECT 2;\nSELECT 3", []string{"SELECT 1;", "SELECT 2;", "SELECT 3"}},
+		{"punto y coma en cadena", "SELECT ';'; SELECT 2", []string{"SELECT ';';", "SELECT 2"}},
+		{"cadena con comilla escapada", "SELECT 'a'';b'; SELECT 2", []string{"SELECT 'a'';b';", "SELECT 2"}},
+		{"cadena con escapes", `SELECT E'a\';b'; SELECT 2`, []string{`SELECT E'a\';b';`, "SELECT 2"}},
+		{"identificador entre comillas", `SELECT 1 AS "a;b"; SELECT 2`, []string{`SELECT 1 AS "a;b";`, "SELECT 2"}},
+		{"cadena con dólares", "SELECT $$a;b$$; SELECT $f$x;y$f$", []string{"SELECT $$a;b$$;", "SELECT $f$x;y$f$"}},
+		{"dólar en un identificador", "SELECT a$1; SELECT 2", []string{"SELECT a$1;", "SELECT 2"}},
+		{"comentarios", "SELECT 1; -- a; b\n/* c; /* d; */ e; */ SELECT 2", []string{"SELECT 1;", "-- a; b\n/* c; /* d; */ e; */ SELECT 2"}},
+		{"tramos vacíos y solo comentarios", ";; SELECT 1;; -- fin;\n", []string{"SELECT 1;"}},
+		{"script vacío", "  \n", nil},
+		{"cadena sin cerrar", "SELECT 1; SELECT 'a; SELECT 2", []string{"SELECT 1;", "SELECT 'a; SELECT 2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, stmt := range SplitScript(test.script) {
+				got = append(got, stmt.Text)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SplitScript(%q) = %q, se esperaba %q", test.script, got, test.want)
+			}
+		})
+	}
+}
+
+func TestSplitScriptPositions(t *testing.T) {
+	statements := SplitScript("SELECT 1;\n  SELECT *\n  FROM zz;")
+	if len(statements) != 2 {
+		t.Fatalf("%d sentencias, se esperaban 2", len(statements))
+	}
+	second := statements[1]
+	if second.Index != 1 || second.Start.Line != 2 || second.Start.Column != 3 || second.Start.Offset != 12 {
+		t.Errorf("la segunda sentencia empieza en %+v", second.Start)
+	}
+
+	// Los errores de cada sentencia se ubican en el script
+	info, err := second.SemanticWithCatalog(newTestCatalog(t))
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	if len(info.Errors) != 1 || info.Errors[0].Start.Line != 3 || info.Errors[0].Start.Column != 8 {
+		t.Errorf("errores %v, se esperaba uno en la línea 3, columna 8", info.Errors)
+	}
+	if _, err := SplitScript("SELECT 1;\nSELECT FROM")[1].Syntactic(); err == nil {
+		t.Error("se esperaba un error sintáctico")
+	} else if span, _ := ErrorSpan(err); span.Start.Line != 2 {
+		t.Errorf("error en %+v, se esperaba la línea 2", span.Start)
+	}
+}
+
+// scriptResult es el resultado esperado de una sentencia de un script.
+type scriptResult struct {
+	valid bool
+	error string
+}
+
+func TestScriptAnalyzer(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []scriptResult
+	}{
+		{
+			name:   "tabla creada en el script",
+			script: "CREATE TABLE notas (id INTEGER, texto TEXT); INSERT INTO notas VALUES (1, 'a'); SELECT texto FROM notas",
+			want:   []scriptResult{{valid: true}, {valid: true}, {valid: true}},
+		},
+		{
+			name:   "tabla usada antes de crearla",
+			script: "SELECT * FROM notas; CREATE TABLE notas (id INTEGER)",
+			want:   []scriptResult{{error: "'notas' no existe"}, {valid: true}},
+		},
+		{
+			name:   "tabla creada dos veces",
+			script: "CREATE TABLE notas (id INTEGER); CREATE TABLE notas (id INTEGER)",
+			want:   []scriptResult{{valid: true}, {error: "ya existe"}},
+		},
+		{
+			name:   "columnas agregadas y eliminadas",
+			script: "ALTER TABLE usuarios ADD COLUMN pais TEXT; SELECT pais FROM usuarios; ALTER TABLE usuarios DROP COLUMN edad; SELECT edad FROM usuarios",
+			want:   []scriptResult{{valid: true}, {valid: true}, {valid: true}, {error: "'edad' no existe"}},
+		},
+		{
+			name:   "tabla eliminada",
+			script: "DROP TABLE productos; SELECT * FROM productos",
+			want:   []scriptResult{{valid: true}, {error: "'productos' no existe"}},
+		},
+		{
+			name:   "ROLLBACK deshace los cambios",
+			script: "BEGIN; CREATE TABLE notas (id INTEGER); ROLLBACK; SELECT * FROM notas",
+			want:   []scriptResult{{valid: true}, {valid: true}, {valid: true}, {error: "'notas' no existe"}},
+		},
+		{
+			name:   "COMMIT conserva los cambios",
+			script: "BEGIN; CREATE TABLE notas (id INTEGER); COMMIT; SELECT * FROM notas",
+			want:   []scriptResult{{valid: true}, {valid: true}, {valid: true}, {valid: true}},
+		},
+		{
+			name: "ROLLBACK TO vuelve al savepoint",
+			script: `BEGIN; CREATE TABLE a (id INTEGER); SAVEPOINT s1; CREATE TABLE b (id INTEGER);
+				ROLLBACK TO SAVEPOINT s1; SELECT * FROM a; SELECT * FROM b; CREATE TABLE b (id INTEGER); COMMIT`,
+			want: []scriptResult{
+				{valid: true}, {valid: true}, {valid: true}, {valid: true},
+				{valid: true}, {valid: true}, {error: "'b' no existe"}, {valid: true}, {valid: true},
+			},
+		},
+		{
+			name: "ROLLBACK TO elimina los savepoints posteriores",
+			script: `BEGIN; SAVEPOINT s1; CREATE TABLE a (id INTEGER); SAVEPOINT s2;
+				ROLLBACK TO s1; ROLLBACK TO s2; ROLLBACK TO s1`,
+			want: []scriptResult{
+				{valid: true}, {valid: true}, {valid: true}, {valid: true},
+				{valid: true}, {error: "el savepoint 's2' no existe"}, {valid: true},
+			},
+		},
+		{
+			name:   "RELEASE elimina el savepoint",
+			script: "BEGIN; SAVEPOINT s1; CREATE TABLE a (id INTEGER); RELEASE s1; ROLLBACK TO s1; SELECT * FROM a",
+			want: []scriptResult{
+				{valid: true}, {valid: true}, {valid: true}, {valid: true},
+				{error: "el savepoint 's1' no existe"}, {valid: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			catalog := newTestCatalog(t)
+			script := NewScriptAnalyzer(catalog)
+			statements := SplitScript(test.script)
+			if len(statements) != len(test.want) {
+				t.Fatalf("%d sentencias, se esperaban %d", len(statements), len(test.want))
+			}
+			for i, stmt := range statements {
+				info, err := script.Semantic(stmt)
+				if err != nil {
+					t.Fatalf("sentencia %d: error sintáctico: %v", i+1, err)
+				}
+				checkSemanticInfo(t, info, test.want[i].valid, test.want[i].error)
+			}
+
+			// El catálogo original no cambia
+			if _, ok := catalog.Table(QualifiedName{Parts: []Identifier{{Name: "notas"}}}); ok {
+				t.Error("el script modificó el catálogo original")
+			}
+		})
+	}
+}
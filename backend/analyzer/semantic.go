@@ -1,22 +1,37 @@
 package analyzer
 
-import (
-	"database/sql"
-	"fmt"
-	"sql-analyzer/database"
-	"strings"
-)
+import "fmt"
 
 type SemanticInfo struct {
-	Tables   []TableInfo  `json:"tables"`
-	Columns  []ColumnInfo `json:"columns"`
-	Warnings []string     `json:"warnings"`
-	Valid    bool         `json:"valid"`
+	Tables   []TableInfo      `json:"tables"`
+	Columns  []ColumnInfo     `json:"columns"`
+	Warnings []string         `json:"warnings"`
+	Errors   []*AnalysisError `json:"errors,omitempty"`
+	Valid    bool             `json:"valid"`
+	// Dependents son los objetos que un DROP ... CASCADE eliminaría además
+	// de los indicados.
+	Dependents []DependentObject `json:"dependents,omitempty"`
+
+	catalog Catalog
+	// tables guarda las tablas ya consultadas en el catálogo.
+	tables map[string]cachedTable
+	// columnTypes guarda la columna a la que se resolvió cada referencia,
+	// para deducir el tipo de las expresiones.
+	columnTypes map[*ColumnRef]ColumnSchema
+}
+
+type cachedTable struct {
+	schema  *TableSchema
+	columns map[string]ColumnSchema
+	ok      bool
 }
 
 type TableInfo struct {
 	Name   string `json:"name"`
 	Exists bool   `json:"exists"`
+	// Virtual indica una tabla definida en la propia consulta (una CTE).
+	Virtual bool `json:"virtual,omitempty"`
+	Span
 }
 
 type ColumnInfo struct {
@@ -24,114 +39,200 @@ type ColumnInfo struct {
 	Column string `json:"column"`
 	Type   string `json:"type"`
 	Exists bool   `json:"exists"`
+	// Nullable indica si la columna admite NULL.
+	Nullable bool `json:"nullable"`
+	Span
+}
+
+// addError marca el resultado como inválido y registra el problema tanto en
+// Warnings (texto) como en Errors (con su ubicación).
+func (info *SemanticInfo) addError(span Span, format string, args ...interface{}) {
+	err := newAnalysisError(span, format, args...)
+	info.Valid = false
+	info.Warnings = append(info.Warnings, err.Message)
+	info.Errors = append(info.Errors, err)
+}
+
+// addWarning registra un aviso que no invalida la consulta.
+func (info *SemanticInfo) addWarning(span Span, format string, args ...interface{}) {
+	info.Warnings = append(info.Warnings, fmt.Sprintf(format, args...))
 }
 
+// SemanticAnalysis verifica la query contra DefaultCatalog.
 func SemanticAnalysis(query string) (*SemanticInfo, error) {
-	tokens, err := LexicalAnalysis(query)
+	return SemanticAnalysisWithCatalog(query, DefaultCatalog)
+}
+
+// SemanticAnalysisWithCatalog verifica la query contra el catálogo
+// indicado, por ejemplo un MemoryCatalog para analizar sin base de datos.
+func SemanticAnalysisWithCatalog(query string, catalog Catalog) (*SemanticInfo, error) {
+	stmt, err := SyntacticAnalysis(query)
 	if err != nil {
 		return nil, err
 	}
+	return analyzeSemantics(stmt, catalog), nil
+}
 
+// analyzeSemantics verifica una sentencia ya analizada sintácticamente.
+func analyzeSemantics(stmt Statement, catalog Catalog) *SemanticInfo {
 	info := &SemanticInfo{
-		Valid:    true,
-		Warnings: []string{},
+		Valid:       true,
+		Warnings:    []string{},
+		Columns:     []ColumnInfo{},
+		catalog:     catalog,
+		tables:      map[string]cachedTable{},
+		columnTypes: map[*ColumnRef]ColumnSchema{},
 	}
 
-	// Extraer tablas y columnas de la consulta
-	tables := extractTables(tokens)
-	columns := extractColumns(tokens)
+	// Extraer las tablas de la consulta y resolver sus columnas
+	tables := extractTables(stmt)
+	info.checkScopes(stmt)
+	info.checkFunctions(stmt)
+	info.checkWindows(stmt)
+	info.checkCases(stmt)
+	info.checkTypes(stmt)
+
+	// Las CTE son tablas virtuales: existen mientras dura la consulta
+	for _, cte := range extractCTEs(stmt) {
+		info.Tables = append(info.Tables, TableInfo{
+			Name:    cte.Name.String(),
+			Exists:  true,
+			Virtual: true,
+			Span:    cte.Name.Span,
+		})
+	}
 
 	// Verificar existencia de tablas
-	db := database.GetDB()
 	for _, table := range tables {
-		exists := checkTableExists(db, table)
+		exists := info.tableExists(table)
 		info.Tables = append(info.Tables, TableInfo{
-			Name:   table,
+			Name:   table.String(),
 			Exists: exists,
+			Span:   table.Span,
 		})
 		if !exists {
-			info.Valid = false
-			info.Warnings = append(info.Warnings,
-				fmt.Sprintf("La tabla '%s' no existe", table))
+			info.addError(table.Span, "La tabla '%s' no existe", table.String())
 		}
 	}
-
-	// Verificar columnas
-	for _, col := range columns {
-		if col != "*" {
-			// Verificación simplificada
-			info.Columns = append(info.Columns, ColumnInfo{
-				Column: col,
-				Exists: true, // Simplificado
-			})
-		}
+	switch s := stmt.(type) {
+	case *InsertStmt:
+		info.checkInsert(s)
+	case *UpdateStmt:
+		info.checkUpdate(s)
+	case *AlterTableStmt:
+		info.checkAlterTable(s)
+	case *DropStmt:
+		info.checkDrop(s)
+	case *CreateIndexStmt:
+		info.checkCreateIndex(s)
 	}
 
-	return info, nil
+	return info
 }
 
-func extractTables(tokens []Token) []string {
-	tables := []string{}
-	fromFound := false
-
-	for i, token := range tokens {
-		if strings.ToUpper(token.Value) == "FROM" ||
-			strings.ToUpper(token.Value) == "INTO" ||
-			strings.ToUpper(token.Value) == "UPDATE" {
-			fromFound = true
-			continue
-		}
-
-		if fromFound && token.Type == "IDENTIFICADOR" {
-			tables = append(tables, token.Value)
-			fromFound = false
-		}
+// addTableColumn registra una columna de una tabla nombrada en la sentencia
+// (en INSERT, SET, ON CONFLICT o CREATE INDEX) y devuelve si existe. Si no
+// se conocen las columnas de la tabla, no registra nada y devuelve true.
+func (info *SemanticInfo) addTableColumn(column Identifier, table QualifiedName) bool {
+	columns, known := info.columnsOf(table)
+	if !known {
+		return true
+	}
+	schema, ok := columns[column.Name]
+	if !ok {
+		info.addColumnInfo(column.Name, column.Span, table.String(), nil)
+		return false
+	}
+	info.addColumnInfo(column.Name, column.Span, table.String(), &schema)
+	return true
+}
 
-		if strings.ToUpper(token.Value) == "TABLE" && i+1 < len(tokens) {
-			if tokens[i+1].Type == "IDENTIFICADOR" {
-				tables = append(tables, tokens[i+1].Value)
-			}
+// tableOf busca una tabla en el catálogo, consultándolo una sola vez por
+// análisis.
+func (info *SemanticInfo) tableOf(table QualifiedName) (cachedTable, bool) {
+	key := table.String()
+	cached, ok := info.tables[key]
+	if !ok {
+		cached.schema, cached.ok = info.catalog.Table(table)
+		if cached.ok {
+			cached.columns = cached.schema.columnMap()
 		}
+		info.tables[key] = cached
 	}
-
-	return tables
+	return cached, cached.ok
 }
 
-func extractColumns(tokens []Token) []string {
-	columns := []string{}
-	selectFound := false
+// tableExists indica si la tabla existe en el catálogo.
+func (info *SemanticInfo) tableExists(table QualifiedName) bool {
+	_, ok := info.tableOf(table)
+	return ok
+}
 
-	for _, token := range tokens {
-		if strings.ToUpper(token.Value) == "SELECT" {
-			selectFound = true
-			continue
-		}
+// columnsOf devuelve las columnas de una tabla por nombre. El mapa es
+// compartido y no debe modificarse.
+func (info *SemanticInfo) columnsOf(table QualifiedName) (map[string]ColumnSchema, bool) {
+	cached, ok := info.tableOf(table)
+	return cached.columns, ok
+}
 
-		if selectFound && strings.ToUpper(token.Value) == "FROM" {
-			break
-		}
+// addColumnInfo registra una columna resuelta. schema es nil si la columna
+// no existe.
+func (info *SemanticInfo) addColumnInfo(name string, span Span, table string, schema *ColumnSchema) {
+	column := ColumnInfo{
+		Table:  table,
+		Column: name,
+		Exists: schema != nil,
+		Span:   span,
+	}
+	if schema != nil {
+		column.Type = schema.Type
+		column.Nullable = schema.Nullable
+	}
+	info.Columns = append(info.Columns, column)
+}
 
-		if selectFound && (token.Type == "IDENTIFICADOR" || token.Value == "*") {
-			columns = append(columns, token.Value)
+// extractTables devuelve las tablas que la sentencia usa y que por lo tanto
+// deben existir: las de FROM (incluidas las de cada JOIN, salvo las CTE),
+// INSERT, UPDATE, DELETE, REFERENCES, CREATE INDEX, DROP TABLE y ALTER TABLE
+// (estos dos, salvo con IF EXISTS).
+func extractTables(stmt Statement) []QualifiedName {
+	tables := []QualifiedName{}
+
+	var visit func(node Node) bool
+	visit = func(node Node) bool {
+		switch n := node.(type) {
+		case *TableRef:
+			if n.CTE == nil {
+				tables = append(tables, n.Name)
+			}
+		case *DropStmt:
+			if n.ObjectType == "TABLE" && !n.IfExists {
+				tables = append(tables, n.Names...)
+			}
+		case *AlterTableStmt:
+			if n.IfExists {
+				// La tabla puede no existir, pero las de REFERENCES sí
+				for _, action := range n.Actions {
+					Walk(action, visit)
+				}
+				return false
+			}
 		}
+		return true
 	}
+	Walk(stmt, visit)
 
-	return columns
+	return tables
 }
 
-func checkTableExists(db *sql.DB, tableName string) bool {
-	var exists bool
-	query := `
-        SELECT EXISTS (
-            SELECT FROM information_schema.tables 
-            WHERE table_schema = 'public' 
-            AND table_name = $1
-        );`
-
-	err := db.QueryRow(query, strings.ToLower(tableName)).Scan(&exists)
-	if err != nil {
-		return false
-	}
-
-	return exists
+// extractCTEs devuelve las CTE definidas en cualquier nivel de la sentencia.
+func extractCTEs(stmt Statement) []*CommonTableExpr {
+	var ctes []*CommonTableExpr
+	Walk(stmt, func(node Node) bool {
+		if cte, ok := node.(*CommonTableExpr); ok {
+			ctes = append(ctes, cte)
+		}
+		return true
+	})
+	return ctes
 }
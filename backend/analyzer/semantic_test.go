@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+// testSchema es el esquema de las pruebas del análisis semántico.
+const testSchema = `
+CREATE TABLE usuarios (
+    id INTEGER PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL,
+    email TEXT UNIQUE,
+    edad INTEGER,
+    activo BOOLEAN,
+    creado TIMESTAMP
+);
+CREATE TABLE pedidos (
+    id INTEGER PRIMARY KEY,
+    usuario_id INTEGER REFERENCES usuarios (id),
+    total NUMERIC(10, 2),
+    fecha DATE,
+    codigo UUID
+);
+CREATE TABLE productos (
+    id INTEGER PRIMARY KEY,
+    nombre VARCHAR(10),
+    precio NUMERIC,
+    sigla CHAR(3)
+);
+CREATE VIEW usuarios_activos AS SELECT id, nombre FROM usuarios WHERE activo;
+`
+
+// newTestCatalog crea un catálogo en memoria con testSchema.
+func newTestCatalog(t *testing.T) *MemoryCatalog {
+	t.Helper()
+	catalog := NewMemoryCatalog()
+	if _, err := catalog.ApplyScript(testSchema); err != nil {
+		t.Fatalf("no se pudo cargar el esquema de prueba: %v", err)
+	}
+	return catalog
+}
+
+// semanticTest es un caso de las pruebas del análisis semántico: si la
+// query es válida y, si no, una parte del mensaje de error esperado.
+type semanticTest struct {
+	query string
+	valid bool
+	error string
+}
+
+// runSemanticTests analiza cada query contra el catálogo de prueba.
+func runSemanticTests(t *testing.T, tests []semanticTest) {
+	t.Helper()
+	catalog := newTestCatalog(t)
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			info, err := SemanticAnalysisWithCatalog(test.query, catalog)
+			if err != nil {
+				t.Fatalf("error sintáctico: %v", err)
+			}
+			checkSemanticInfo(t, info, test.valid, test.error)
+		})
+	}
+}
+
+// checkSemanticInfo compara el resultado de un análisis con el esperado.
+func checkSemanticInfo(t *testing.T, info *SemanticInfo, valid bool, message string) {
+	t.Helper()
+	if info.Valid != valid {
+		t.Fatalf("Valid = %v, se esperaba %v (errores: %v)", info.Valid, valid, info.Warnings)
+	}
+	if valid || message == "" {
+		return
+	}
+	for _, err := range info.Errors {
+		if strings.Contains(err.Message, message) {
+			return
+		}
+	}
+	t.Errorf("ningún error contiene %q: %v", message, info.Warnings)
+}
+
+func TestSemanticTables(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT * FROM usuarios", valid: true},
+		{query: "SELECT * FROM usuarios_activos", valid: true},
+		{query: "SELECT * FROM clientes", error: "'clientes' no existe"},
+		{query: "DELETE FROM usuarios WHERE id = 1", valid: true},
+		{query: "INSERT INTO usuarios (id, nombre) VALUES (1, 'Ana')", valid: true},
+		{query: "INSERT INTO usuarios (id, apellido) VALUES (1, 'Ana')", error: "apellido"},
+		{query: "UPDATE pedidos SET total = 10 WHERE id = 1", valid: true},
+		{query: "UPDATE pedidos SET monto = 10", error: "monto"},
+	})
+}
@@ -1,28 +1,30 @@
 package analyzer
 
 import (
-	"fmt"
 	"strings"
 )
 
+// SyntaxNode es la representación genérica del árbol sintáctico que se envía
+// al frontend. Se obtiene a partir del AST con SyntaxTree.
 type SyntaxNode struct {
 	Type     string       `json:"type"`
 	Value    string       `json:"value,omitempty"`
 	Children []SyntaxNode `json:"children,omitempty"`
+	Span
 }
 
 // Stack para verificar balance de paréntesis
 type ParenthesisStack struct {
-	items []string
+	items []Token
 }
 
-func (s *ParenthesisStack) Push(item string) {
+func (s *ParenthesisStack) Push(item Token) {
 	s.items = append(s.items, item)
 }
 
-func (s *ParenthesisStack) Pop() (string, bool) {
+func (s *ParenthesisStack) Pop() (Token, bool) {
 	if len(s.items) == 0 {
-		return "", false
+		return Token{}, false
 	}
 	item := s.items[len(s.items)-1]
 	s.items = s.items[:len(s.items)-1]
@@ -33,14 +35,22 @@ func (s *ParenthesisStack) IsEmpty() bool {
 	return len(s.items) == 0
 }
 
-func SyntacticAnalysis(query string) (*SyntaxNode, error) {
-	tokens, err := LexicalAnalysis(query)
+// SyntacticAnalysis analiza una sentencia y devuelve su AST.
+func SyntacticAnalysis(query string) (Statement, error) {
+	return parseStatementAt(query, startSpan.Start)
+}
+
+// parseStatementAt analiza la sentencia query que empieza en la posición
+// start de un texto mayor (ver lexAt).
+func parseStatementAt(query string, start Position) (Statement, error) {
+	tokens, err := lexAt(query, start)
 	if err != nil {
 		return nil, err
 	}
+	tokens = withoutComments(tokens)
 
 	if len(tokens) == 0 {
-		return nil, fmt.Errorf("query vacía")
+		return nil, newAnalysisError(Span{Start: start, End: start}, "query vacía")
 	}
 
 	// Verificar balance de paréntesis en toda la query
@@ -48,30 +58,8 @@ func SyntacticAnalysis(query string) (*SyntaxNode, error) {
 		return nil, err
 	}
 
-	// Verificar punto y coma al final
-	if len(tokens) > 0 && tokens[len(tokens)-1].Value != ";" {
-		// Nota: No es un error, pero es mejor práctica terminar con ;
-	}
-
-	upperQuery := strings.ToUpper(strings.TrimSpace(query))
-
-	// Análisis por tipo de sentencia
-	switch {
-	case strings.HasPrefix(upperQuery, "SELECT"):
-		return analyzeSelect(tokens)
-	case strings.HasPrefix(upperQuery, "INSERT"):
-		return analyzeInsert(tokens)
-	case strings.HasPrefix(upperQuery, "UPDATE"):
-		return analyzeUpdate(tokens)
-	case strings.HasPrefix(upperQuery, "DELETE"):
-		return analyzeDelete(tokens)
-	case strings.HasPrefix(upperQuery, "CREATE"):
-		return analyzeCreate(tokens)
-	case strings.HasPrefix(upperQuery, "DROP"):
-		return analyzeDrop(tokens)
-	default:
-		return nil, fmt.Errorf("tipo de sentencia no reconocida: %s", tokens[0].Value)
-	}
+	p := &parser{tokens: tokens}
+	return p.analyzeStatement()
 }
 
 func checkParenthesisBalance(tokens []Token) error {
@@ -79,1299 +67,2172 @@ func checkParenthesisBalance(tokens []Token) error {
 
 	for i, token := range tokens {
 		if token.Value == "(" {
-			stack.Push("(")
+			stack.Push(token)
 		} else if token.Value == ")" {
 			if _, ok := stack.Pop(); !ok {
-				return fmt.Errorf("paréntesis de cierre ')' sin paréntesis de apertura correspondiente en posición %d", i)
+				return errorAt(tokens, i, "paréntesis de cierre ')' sin paréntesis de apertura correspondiente")
 			}
 		}
 	}
 
-	if !stack.IsEmpty() {
-		return fmt.Errorf("paréntesis de apertura '(' sin cerrar")
+	if open, ok := stack.Pop(); ok {
+		return newAnalysisError(open.Span, "paréntesis de apertura '(' sin cerrar")
 	}
 
 	return nil
 }
 
-func analyzeCreateTable(tokens []Token, startIndex int, root *SyntaxNode) (*SyntaxNode, error) {
-	i := startIndex + 1
-
-	// Verificar IF NOT EXISTS (opcional)
-	ifNotExists := false
-	if i+2 < len(tokens) &&
-		strings.ToUpper(tokens[i].Value) == "IF" &&
-		strings.ToUpper(tokens[i+1].Value) == "NOT" &&
-		strings.ToUpper(tokens[i+2].Value) == "EXISTS" {
-		ifNotExists = true
-		i += 3
+// errorAt construye un error ubicado en tokens[i]. Si i está fuera de rango
+// el error apunta al final de la query, que es donde faltaba algo.
+func errorAt(tokens []Token, i int, format string, args ...interface{}) error {
+	var span Span
+	switch {
+	case i >= 0 && i < len(tokens):
+		span = tokens[i].Span
+	case len(tokens) > 0:
+		end := tokens[len(tokens)-1].End
+		span = Span{Start: end, End: end}
+	default:
+		span = startSpan
 	}
+	return newAnalysisError(span, format, args...)
+}
 
-	// Nombre de tabla
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba nombre de tabla después de CREATE TABLE")
+// spanBetween devuelve el rango que va desde tokens[from] hasta tokens[to].
+func spanBetween(tokens []Token, from, to int) Span {
+	if to >= len(tokens) {
+		to = len(tokens) - 1
 	}
-
-	if tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("nombre de tabla inválido: '%s'", tokens[i].Value)
+	if to < from {
+		to = from
 	}
+	return Span{Start: tokens[from].Start, End: tokens[to].End}
+}
 
-	tableNode := &SyntaxNode{Type: "TABLE", Value: tokens[i].Value}
-	if ifNotExists {
-		tableNode.Children = append(tableNode.Children,
-			SyntaxNode{Type: "IF_NOT_EXISTS", Value: "true"})
+// fillSpans extiende el rango de cada nodo para que cubra el de sus hijos.
+// Los nodos auxiliares que no tienen ubicación propia (DIRECTION, SIZE...)
+// toman la de su padre.
+func fillSpans(node *SyntaxNode) {
+	for i := range node.Children {
+		fillSpans(&node.Children[i])
+		node.Span = node.Span.cover(node.Children[i].Span)
 	}
-	root.Children = append(root.Children, *tableNode)
-	i++
-
-	// Debe haber paréntesis de apertura
-	if i >= len(tokens) || tokens[i].Value != "(" {
-		return nil, fmt.Errorf("se esperaba '(' después del nombre de tabla '%s'", tableNode.Value)
+	for i := range node.Children {
+		inheritSpan(&node.Children[i], node.Span)
 	}
-	i++
-
-	columnsNode := &SyntaxNode{Type: "COLUMNS"}
-	columnCount := 0
+}
 
-	// Verificar que no esté vacío
-	if i < len(tokens) && tokens[i].Value == ")" {
-		return nil, fmt.Errorf("la definición de tabla no puede estar vacía")
+func inheritSpan(node *SyntaxNode, span Span) {
+	if node.Start.IsValid() {
+		return
 	}
+	node.Span = span
+	for i := range node.Children {
+		inheritSpan(&node.Children[i], span)
+	}
+}
 
-	for i < len(tokens) && tokens[i].Value != ")" {
-		// Nombre de columna
-		if tokens[i].Type != "IDENTIFICADOR" {
-			return nil, fmt.Errorf("se esperaba nombre de columna, se encontró '%s'", tokens[i].Value)
-		}
-
-		columnName := tokens[i].Value
-		columnDef := &SyntaxNode{Type: "COLUMN_DEFINITION", Value: columnName}
-		i++
+// parser recorre la lista de tokens (sin comentarios) de una sentencia.
+type parser struct {
+	tokens []Token
+	pos    int
+	// ctes son las CTE visibles en la posición actual, de la más externa a
+	// la más interna.
+	ctes []*CommonTableExpr
+}
 
-		// Tipo de dato
-		if i >= len(tokens) {
-			return nil, fmt.Errorf("se esperaba tipo de dato para la columna '%s'", columnName)
-		}
-
-		// Mapa de tipos de datos válidos
-		validTypes := map[string]bool{
-			"INT": true, "INTEGER": true, "BIGINT": true, "SMALLINT": true,
-			"SERIAL": true, "BIGSERIAL": true,
-			"VARCHAR": true, "TEXT": true, "CHAR": true,
-			"DECIMAL": true, "NUMERIC": true, "FLOAT": true, "REAL": true,
-			"DOUBLE": true, "MONEY": true,
-			"DATE": true, "TIME": true, "TIMESTAMP": true, "INTERVAL": true,
-			"BOOLEAN": true, "BOOL": true,
-			"UUID": true, "JSON": true, "JSONB": true,
-			"ARRAY": true, "BYTEA": true,
-		}
-
-		upperType := strings.ToUpper(tokens[i].Value)
-		if !validTypes[upperType] {
-			// Verificar si es un tipo con palabras múltiples
-			if upperType == "DOUBLE" && i+1 < len(tokens) &&
-				strings.ToUpper(tokens[i+1].Value) == "PRECISION" {
-				upperType = "DOUBLE PRECISION"
-				i++
-			} else {
-				return nil, fmt.Errorf("tipo de dato inválido: '%s' para columna '%s'", tokens[i].Value, columnName)
-			}
-		}
+func (p *parser) atEnd() bool {
+	return p.pos >= len(p.tokens)
+}
 
-		dataType := &SyntaxNode{Type: "DATA_TYPE", Value: upperType}
-		columnDef.Children = append(columnDef.Children, *dataType)
-		i++
+// peek devuelve el token actual, o un token vacío al final de la query.
+func (p *parser) peek() Token {
+	return p.peekAt(0)
+}
 
-		// Verificar parámetros del tipo (ej: VARCHAR(50))
-		if i < len(tokens) && tokens[i].Value == "(" {
-			i++
-			if i >= len(tokens) {
-				return nil, fmt.Errorf("se esperaba tamaño después de '(' en tipo %s", upperType)
-			}
+func (p *parser) peekAt(offset int) Token {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+	return Token{}
+}
 
-			// Para tipos como DECIMAL(10,2)
-			sizeParams := []string{}
-
-			if tokens[i].Type == "NUMERO" {
-				sizeParams = append(sizeParams, tokens[i].Value)
-				i++
-
-				// Verificar si hay segundo parámetro (para DECIMAL)
-				if i < len(tokens) && tokens[i].Value == "," {
-					i++
-					if i < len(tokens) && tokens[i].Type == "NUMERO" {
-						sizeParams = append(sizeParams, tokens[i].Value)
-						i++
-					} else {
-						return nil, fmt.Errorf("se esperaba segundo parámetro numérico después de ',' en %s", upperType)
-					}
-				}
-			} else {
-				return nil, fmt.Errorf("se esperaba número para el tamaño de %s", upperType)
-			}
+func (p *parser) next() Token {
+	tok := p.peek()
+	if !p.atEnd() {
+		p.pos++
+	}
+	return tok
+}
 
-			if i >= len(tokens) || tokens[i].Value != ")" {
-				return nil, fmt.Errorf("se esperaba ')' para cerrar los parámetros de %s", upperType)
-			}
-			i++
+// current describe el token actual para los mensajes de error.
+func (p *parser) current() string {
+	if p.atEnd() {
+		return "fin de query"
+	}
+	return p.peek().Value
+}
 
-			for _, param := range sizeParams {
-				dataType.Children = append(dataType.Children,
-					SyntaxNode{Type: "SIZE", Value: param})
-			}
-		} else if upperType == "VARCHAR" || upperType == "CHAR" {
-			// VARCHAR y CHAR deberían tener tamaño
-			return nil, fmt.Errorf("tipo %s requiere especificar tamaño, ejemplo: %s(50)", upperType, upperType)
-		}
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return errorAt(p.tokens, p.pos, format, args...)
+}
 
-		// Constraints
-		for i < len(tokens) && tokens[i].Value != "," && tokens[i].Value != ")" {
-			upperConstraint := strings.ToUpper(tokens[i].Value)
+// spanFrom devuelve el rango desde tokens[start] hasta el último token
+// consumido.
+func (p *parser) spanFrom(start int) Span {
+	return spanBetween(p.tokens, start, p.pos-1)
+}
 
-			switch upperConstraint {
-			case "NOT":
-				if i+1 >= len(tokens) {
-					return nil, fmt.Errorf("se esperaba NULL después de NOT")
-				}
-				if strings.ToUpper(tokens[i+1].Value) != "NULL" {
-					return nil, fmt.Errorf("se esperaba NULL después de NOT, se encontró '%s'", tokens[i+1].Value)
-				}
-				columnDef.Children = append(columnDef.Children,
-					SyntaxNode{Type: "CONSTRAINT", Value: "NOT NULL"})
-				i += 2
-
-			case "NULL":
-				columnDef.Children = append(columnDef.Children,
-					SyntaxNode{Type: "CONSTRAINT", Value: "NULL"})
-				i++
-
-			case "PRIMARY":
-				if i+1 >= len(tokens) {
-					return nil, fmt.Errorf("se esperaba KEY después de PRIMARY")
-				}
-				if strings.ToUpper(tokens[i+1].Value) != "KEY" {
-					return nil, fmt.Errorf("se esperaba KEY después de PRIMARY, se encontró '%s'", tokens[i+1].Value)
-				}
-				columnDef.Children = append(columnDef.Children,
-					SyntaxNode{Type: "CONSTRAINT", Value: "PRIMARY KEY"})
-				i += 2
-
-			case "UNIQUE":
-				columnDef.Children = append(columnDef.Children,
-					SyntaxNode{Type: "CONSTRAINT", Value: "UNIQUE"})
-				i++
-
-			case "DEFAULT":
-				i++
-				if i >= len(tokens) {
-					return nil, fmt.Errorf("se esperaba valor después de DEFAULT")
-				}
+// isWord indica si tok es la palabra indicada (palabra clave o identificador
+// sin comillas), sin distinguir mayúsculas.
+func isWord(tok Token, word string) bool {
+	return (tok.Type == "PALABRA_CLAVE" || tok.Type == "IDENTIFICADOR") &&
+		!strings.HasPrefix(tok.Value, `"`) &&
+		strings.EqualFold(tok.Value, word)
+}
 
-				defaultValue := tokens[i].Value
-				// Verificar valores especiales de DEFAULT
-				upperDefault := strings.ToUpper(defaultValue)
-				if upperDefault == "CURRENT_TIMESTAMP" || upperDefault == "NOW()" ||
-					tokens[i].Type == "NUMERO" || tokens[i].Type == "CADENA" ||
-					upperDefault == "TRUE" || upperDefault == "FALSE" ||
-					upperDefault == "NULL" {
-					columnDef.Children = append(columnDef.Children,
-						SyntaxNode{Type: "DEFAULT", Value: defaultValue})
-					i++
-				} else {
-					return nil, fmt.Errorf("valor DEFAULT inválido: '%s'", defaultValue)
-				}
+// isKeyword indica si los próximos tokens son las palabras indicadas.
+func (p *parser) isKeyword(words ...string) bool {
+	for i, word := range words {
+		if !isWord(p.peekAt(i), word) {
+			return false
+		}
+	}
+	return true
+}
 
-			case "REFERENCES":
-				i++
-				if i >= len(tokens) {
-					return nil, fmt.Errorf("se esperaba nombre de tabla después de REFERENCES")
-				}
-				if tokens[i].Type != "IDENTIFICADOR" {
-					return nil, fmt.Errorf("nombre de tabla inválido después de REFERENCES: '%s'", tokens[i].Value)
-				}
+// acceptKeyword consume las palabras indicadas si son las siguientes.
+func (p *parser) acceptKeyword(words ...string) bool {
+	if !p.isKeyword(words...) {
+		return false
+	}
+	p.pos += len(words)
+	return true
+}
 
-				refNode := &SyntaxNode{Type: "REFERENCES", Value: tokens[i].Value}
-				i++
+// expectKeyword consume la palabra indicada o devuelve un error con el
+// contexto en el que se esperaba.
+func (p *parser) expectKeyword(word, context string) error {
+	if !p.acceptKeyword(word) {
+		return p.errorf("se esperaba %s %s, se encontró '%s'", word, context, p.current())
+	}
+	return nil
+}
 
-				// Columna referenciada (opcional pero recomendada)
-				if i < len(tokens) && tokens[i].Value == "(" {
-					i++
-					if i >= len(tokens) {
-						return nil, fmt.Errorf("se esperaba nombre de columna después de '(' en REFERENCES")
-					}
-					if tokens[i].Type != "IDENTIFICADOR" {
-						return nil, fmt.Errorf("nombre de columna inválido en REFERENCES: '%s'", tokens[i].Value)
-					}
-					refNode.Children = append(refNode.Children,
-						SyntaxNode{Type: "REF_COLUMN", Value: tokens[i].Value})
-					i++
-					if i >= len(tokens) || tokens[i].Value != ")" {
-						return nil, fmt.Errorf("se esperaba ')' después de la columna en REFERENCES")
-					}
-					i++
-				}
-				columnDef.Children = append(columnDef.Children, *refNode)
+// is indica si el token actual es el delimitador u operador indicado.
+func (p *parser) is(value string) bool {
+	tok := p.peek()
+	return (tok.Type == "DELIMITADOR" || tok.Type == "OPERADOR") && tok.Value == value
+}
 
-			case "CHECK":
-				// CHECK constraint
-				i++
-				if i >= len(tokens) || tokens[i].Value != "(" {
-					return nil, fmt.Errorf("se esperaba '(' después de CHECK")
-				}
-				i++
-
-				// Capturar el contenido del CHECK
-				checkDepth := 1
-				checkContent := []string{}
-
-				for i < len(tokens) && checkDepth > 0 {
-					if tokens[i].Value == "(" {
-						checkDepth++
-					} else if tokens[i].Value == ")" {
-						checkDepth--
-						if checkDepth == 0 {
-							break
-						}
-					}
-					checkContent = append(checkContent, tokens[i].Value)
-					i++
-				}
+func (p *parser) accept(value string) bool {
+	if !p.is(value) {
+		return false
+	}
+	p.pos++
+	return true
+}
 
-				if checkDepth != 0 {
-					return nil, fmt.Errorf("paréntesis no balanceados en constraint CHECK")
-				}
+func (p *parser) expect(value, context string) error {
+	if !p.accept(value) {
+		return p.errorf("se esperaba '%s' %s, se encontró '%s'", value, context, p.current())
+	}
+	return nil
+}
 
-				// Ahora sí usamos checkStart para algo útil
-				checkNode := &SyntaxNode{
-					Type:  "CONSTRAINT",
-					Value: "CHECK",
-				}
+func (p *parser) isIdentifier() bool {
+	return p.peek().Type == "IDENTIFICADOR"
+}
 
-				// Guardar el contenido del CHECK
-				if len(checkContent) > 0 {
-					checkCondition := strings.Join(checkContent, " ")
-					checkNode.Children = append(checkNode.Children,
-						SyntaxNode{Type: "CHECK_CONDITION", Value: checkCondition})
-				}
+// parseIdentifier lee un nombre simple; what describe qué se esperaba.
+func (p *parser) parseIdentifier(what string) (Identifier, error) {
+	if !p.isIdentifier() {
+		return Identifier{}, p.errorf("se esperaba %s, se encontró '%s'", what, p.current())
+	}
+	return identifierFromToken(p.next()), nil
+}
 
-				columnDef.Children = append(columnDef.Children, *checkNode)
-				i++ // Saltar el ')' final
+// parseQualifiedName lee un nombre de la forma parte(.parte)*.
+func (p *parser) parseQualifiedName(what string, allowStar bool) (QualifiedName, error) {
+	if !p.isIdentifier() {
+		return QualifiedName{}, p.errorf("se esperaba %s, se encontró '%s'", what, p.current())
+	}
+	name, next, err := readQualifiedName(p.tokens, p.pos, allowStar)
+	p.pos = next
+	return name, err
+}
 
-			default:
-				return nil, fmt.Errorf("constraint no reconocido: '%s' en columna '%s'", tokens[i].Value, columnName)
-			}
-		}
+// parseTableRef lee una referencia a tabla.
+func (p *parser) parseTableRef(context string) (*TableRef, error) {
+	name, err := p.parseQualifiedName("nombre de tabla "+context, false)
+	if err != nil {
+		return nil, err
+	}
+	return &TableRef{Name: name, Span: name.Span}, nil
+}
 
-		columnsNode.Children = append(columnsNode.Children, *columnDef)
-		columnCount++
+// aliasReserved son palabras que no están en keywords pero que el parser
+// trata como sintaxis, por lo que no pueden usarse como alias sin AS.
+var aliasReserved = map[string]bool{
+	"NATURAL": true, "CROSS": true, "FULL": true, "OUTER": true, "USING": true,
+	"IS": true, "ILIKE": true, "ESCAPE": true, "LATERAL": true, "WITH": true,
+	"INTERSECT": true, "EXCEPT": true, "WINDOW": true, "RETURNING": true,
+}
 
-		// Verificar si hay más columnas o constraints de tabla
-		if i < len(tokens) && tokens[i].Value == "," {
-			i++
+// parseAlias lee un alias opcional: "AS nombre" o un nombre sin AS.
+func (p *parser) parseAlias() (Identifier, error) {
+	if p.acceptKeyword("AS") {
+		return p.parseIdentifier("un alias después de AS")
+	}
+	tok := p.peek()
+	if tok.Type == "IDENTIFICADOR" && (strings.HasPrefix(tok.Value, `"`) || !aliasReserved[strings.ToUpper(tok.Value)]) {
+		return identifierFromToken(p.next()), nil
+	}
+	return Identifier{}, nil
+}
 
-			// Podría ser otra columna o un constraint de tabla
-			if i >= len(tokens) {
-				return nil, fmt.Errorf("se esperaba definición después de ','")
-			}
+// parseAliasedTableRef lee una referencia a tabla con alias opcional.
+func (p *parser) parseAliasedTableRef(context string) (*TableRef, error) {
+	start := p.pos
+	table, err := p.parseTableRef(context)
+	if err != nil {
+		return nil, err
+	}
+	if table.Alias, err = p.parseAlias(); err != nil {
+		return nil, err
+	}
+	table.Span = p.spanFrom(start)
+	return table, nil
+}
 
-			// Verificar constraints de tabla (PRIMARY KEY, FOREIGN KEY, etc.)
-			if i+1 < len(tokens) {
-				upperNext := strings.ToUpper(tokens[i].Value)
-				if upperNext == "PRIMARY" || upperNext == "FOREIGN" ||
-					upperNext == "UNIQUE" || upperNext == "CHECK" ||
-					upperNext == "CONSTRAINT" {
-					// Es un constraint de tabla, analizarlo
-					tableConstraint, newIndex, err := analyzeTableConstraint(tokens, i)
-					if err != nil {
-						return nil, err
-					}
-					columnsNode.Children = append(columnsNode.Children, *tableConstraint)
-					i = newIndex
-
-					// Puede haber más constraints
-					for i < len(tokens) && tokens[i].Value == "," {
-						i++
-						if i >= len(tokens) || tokens[i].Value == ")" {
-							break
-						}
-						tableConstraint, newIndex, err = analyzeTableConstraint(tokens, i)
-						if err != nil {
-							return nil, err
-						}
-						columnsNode.Children = append(columnsNode.Children, *tableConstraint)
-						i = newIndex
-					}
-				}
-			}
+// parseIdentifierList lee una lista "(a, b, ...)" de nombres de columna.
+func (p *parser) parseIdentifierList(context string) ([]Identifier, error) {
+	if err := p.expect("(", context); err != nil {
+		return nil, err
+	}
+	var ids []Identifier
+	for {
+		id, err := p.parseIdentifier("nombre de columna " + context)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		if !p.accept(",") {
+			break
 		}
 	}
-
-	if columnCount == 0 {
-		return nil, fmt.Errorf("se debe definir al menos una columna en la tabla")
+	if err := p.expect(")", "para cerrar la lista de columnas "+context); err != nil {
+		return nil, err
 	}
+	return ids, nil
+}
 
-	if i >= len(tokens) || tokens[i].Value != ")" {
-		return nil, fmt.Errorf("se esperaba ')' para cerrar la definición de tabla, se encontró: '%s'",
-			func() string {
-				if i < len(tokens) {
-					return tokens[i].Value
-				}
-				return "fin de query"
-			}())
+// expectEnd verifica que la sentencia termine, con un ';' opcional.
+func (p *parser) expectEnd(statement string) error {
+	if p.accept(";") && !p.atEnd() {
+		return p.errorf("la query contiene más de una sentencia (a partir de '%s'): analícela como script", p.current())
 	}
-
-	root.Children = append(root.Children, *columnsNode)
-	i++
-
-	// Verificar punto y coma opcional al final
-	if i < len(tokens) && tokens[i].Value != ";" {
-		// Si hay más tokens y no es punto y coma, es un error
-		return nil, fmt.Errorf("se esperaba ';' al final de CREATE TABLE, se encontró: '%s'", tokens[i].Value)
+	if !p.atEnd() {
+		return p.errorf("se esperaba ';' al final de %s, se encontró: '%s'", statement, p.current())
 	}
+	return nil
+}
 
-	return root, nil
+func (p *parser) analyzeStatement() (Statement, error) {
+	// Análisis por tipo de sentencia según la primera palabra
+	tok := p.peek()
+	switch strings.ToUpper(tok.Value) {
+	case "SELECT", "WITH", "(":
+		return p.analyzeQuery()
+	case "INSERT":
+		return p.analyzeInsert()
+	case "UPDATE":
+		return p.analyzeUpdate()
+	case "DELETE":
+		return p.analyzeDelete()
+	case "CREATE":
+		return p.analyzeCreate()
+	case "DROP":
+		return p.analyzeDrop()
+	case "ALTER":
+		return p.analyzeAlter()
+	case "BEGIN", "START", "COMMIT", "END", "ROLLBACK", "SAVEPOINT", "RELEASE":
+		return p.analyzeTransaction()
+	default:
+		return nil, p.errorf("tipo de sentencia no reconocida: %s", tok.Value)
+	}
 }
 
-func analyzeTableConstraint(tokens []Token, startIndex int) (*SyntaxNode, int, error) {
-	i := startIndex
-	constraint := &SyntaxNode{Type: "TABLE_CONSTRAINT"}
+// analyzeQuery analiza una sentencia que devuelve filas: SELECT, WITH u
+// operaciones de conjuntos.
+func (p *parser) analyzeQuery() (Query, error) {
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() && !p.is(";") {
+		return nil, p.errorf("cláusula no reconocida: '%s'", p.current())
+	}
+	return query, p.expectEnd("SELECT")
+}
 
-	// CONSTRAINT nombre (opcional)
-	if strings.ToUpper(tokens[i].Value) == "CONSTRAINT" {
-		i++
-		if i >= len(tokens) || tokens[i].Type != "IDENTIFICADOR" {
-			return nil, i, fmt.Errorf("se esperaba nombre después de CONSTRAINT")
-		}
-		constraint.Value = tokens[i].Value
+// isSubquery indica si en la posición actual empieza una subconsulta
+// entre paréntesis, incluidas las de la forma ((SELECT ...) UNION ...).
+func (p *parser) isSubquery() bool {
+	if !p.is("(") {
+		return false
+	}
+	i := 1
+	for tok := p.peekAt(i); tok.Type == "DELIMITADOR" && tok.Value == "("; tok = p.peekAt(i) {
 		i++
 	}
+	return isWord(p.peekAt(i), "SELECT") || isWord(p.peekAt(i), "WITH")
+}
 
-	if i >= len(tokens) {
-		return nil, i, fmt.Errorf("se esperaba tipo de constraint")
+// parseSubquery lee una consulta entre paréntesis.
+func (p *parser) parseSubquery() (Query, error) {
+	if err := p.expect("(", "antes de la subconsulta"); err != nil {
+		return nil, err
 	}
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")", "para cerrar la subconsulta"); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
 
-	upperConstraint := strings.ToUpper(tokens[i].Value)
-
-	switch upperConstraint {
-	case "PRIMARY":
-		if i+1 >= len(tokens) || strings.ToUpper(tokens[i+1].Value) != "KEY" {
-			return nil, i, fmt.Errorf("se esperaba KEY después de PRIMARY")
+// parseQuery lee una consulta completa: WITH opcional, uno o más SELECT
+// combinados con UNION, INTERSECT o EXCEPT, y el ORDER BY, LIMIT y OFFSET
+// del resultado. Se detiene en el primer token que no le pertenece, para
+// poder usarse también como subconsulta.
+func (p *parser) parseQuery() (Query, error) {
+	start := p.pos
+
+	// Las CTE solo son visibles dentro de esta consulta
+	defer func(visible int) { p.ctes = p.ctes[:visible] }(len(p.ctes))
+	var with *WithClause
+	if p.isKeyword("WITH") {
+		var err error
+		if with, err = p.parseWith(); err != nil {
+			return nil, err
 		}
-		i += 2
+	}
 
-		if i >= len(tokens) || tokens[i].Value != "(" {
-			return nil, i, fmt.Errorf("se esperaba '(' después de PRIMARY KEY")
-		}
-		i++
+	query, err := p.parseSetOperation(precUnion)
+	if err != nil {
+		return nil, err
+	}
 
-		// Lista de columnas
-		pkNode := &SyntaxNode{Type: "PRIMARY_KEY"}
-		for i < len(tokens) && tokens[i].Value != ")" {
-			if tokens[i].Type == "IDENTIFICADOR" {
-				pkNode.Children = append(pkNode.Children,
-					SyntaxNode{Type: "COLUMN", Value: tokens[i].Value})
-				i++
-				if i < len(tokens) && tokens[i].Value == "," {
-					i++
-				}
-			} else {
-				return nil, i, fmt.Errorf("se esperaba nombre de columna en PRIMARY KEY")
-			}
+	// ORDER BY, LIMIT y OFFSET se aplican al resultado completo
+	var clauses *ResultClauses
+	switch q := query.(type) {
+	case *SelectStmt:
+		clauses = &q.ResultClauses
+		if with != nil && q.With != nil {
+			return nil, newAnalysisError(q.With.Span, "la consulta no puede tener dos cláusulas WITH")
 		}
-
-		if i >= len(tokens) || tokens[i].Value != ")" {
-			return nil, i, fmt.Errorf("se esperaba ')' para cerrar PRIMARY KEY")
+		if with != nil {
+			q.With = with
 		}
-		i++
+	case *SetOperation:
+		clauses = &q.ResultClauses
+		q.With = with
+	}
+	if err := p.parseResultClauses(clauses); err != nil {
+		return nil, err
+	}
 
-		constraint.Children = append(constraint.Children, *pkNode)
+	switch q := query.(type) {
+	case *SelectStmt:
+		q.Span = p.spanFrom(start)
+	case *SetOperation:
+		q.Span = p.spanFrom(start)
+	}
+	return query, nil
+}
 
-	case "FOREIGN":
-		if i+1 >= len(tokens) || strings.ToUpper(tokens[i+1].Value) != "KEY" {
-			return nil, i, fmt.Errorf("se esperaba KEY después de FOREIGN")
-		}
-		i += 2
+// Precedencia de las operaciones de conjuntos: INTERSECT se evalúa antes
+// que UNION y EXCEPT.
+const (
+	precUnion = iota + 1
+	precIntersect
+)
 
-		if i >= len(tokens) || tokens[i].Value != "(" {
-			return nil, i, fmt.Errorf("se esperaba '(' después de FOREIGN KEY")
-		}
-		i++
+// parseSetOperation lee operandos combinados con operaciones de conjuntos
+// de al menos la precedencia minPrec; se asocian por la izquierda.
+func (p *parser) parseSetOperation(minPrec int) (Query, error) {
+	left, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
 
-		// Columna local
-		if i >= len(tokens) || tokens[i].Type != "IDENTIFICADOR" {
-			return nil, i, fmt.Errorf("se esperaba nombre de columna en FOREIGN KEY")
+	for {
+		var prec int
+		switch {
+		case p.isKeyword("UNION"), p.isKeyword("EXCEPT"):
+			prec = precUnion
+		case p.isKeyword("INTERSECT"):
+			prec = precIntersect
 		}
-
-		fkNode := &SyntaxNode{Type: "FOREIGN_KEY", Value: tokens[i].Value}
-		i++
-
-		if i >= len(tokens) || tokens[i].Value != ")" {
-			return nil, i, fmt.Errorf("se esperaba ')' después de la columna en FOREIGN KEY")
+		if prec == 0 || prec < minPrec {
+			return left, nil
 		}
-		i++
 
-		if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "REFERENCES" {
-			return nil, i, fmt.Errorf("se esperaba REFERENCES después de FOREIGN KEY")
+		op := &SetOperation{Op: strings.ToUpper(p.next().Value), Left: left}
+		op.All = p.acceptKeyword("ALL")
+		if !op.All {
+			p.acceptKeyword("DISTINCT")
 		}
-		i++
-
-		// Tabla referenciada
-		if i >= len(tokens) || tokens[i].Type != "IDENTIFICADOR" {
-			return nil, i, fmt.Errorf("se esperaba nombre de tabla después de REFERENCES")
+		if !p.isKeyword("SELECT") && !p.is("(") {
+			return nil, p.errorf("se esperaba SELECT después de %s, se encontró '%s'", op.Op, p.current())
 		}
-
-		refNode := &SyntaxNode{Type: "REFERENCES", Value: tokens[i].Value}
-		i++
-
-		if i < len(tokens) && tokens[i].Value == "(" {
-			i++
-			if i >= len(tokens) || tokens[i].Type != "IDENTIFICADOR" {
-				return nil, i, fmt.Errorf("se esperaba nombre de columna en REFERENCES")
-			}
-			refNode.Children = append(refNode.Children,
-				SyntaxNode{Type: "REF_COLUMN", Value: tokens[i].Value})
-			i++
-			if i >= len(tokens) || tokens[i].Value != ")" {
-				return nil, i, fmt.Errorf("se esperaba ')' para cerrar REFERENCES")
-			}
-			i++
+		if op.Right, err = p.parseSetOperation(prec + 1); err != nil {
+			return nil, err
 		}
+		op.Span = left.Location().cover(op.Right.Location())
+		left = op
+	}
+}
 
-		fkNode.Children = append(fkNode.Children, *refNode)
-		constraint.Children = append(constraint.Children, *fkNode)
-
-	case "UNIQUE":
-		if i+1 < len(tokens) && tokens[i+1].Value == "(" {
-			i += 2
-			uniqueNode := &SyntaxNode{Type: "UNIQUE"}
-
-			for i < len(tokens) && tokens[i].Value != ")" {
-				if tokens[i].Type == "IDENTIFICADOR" {
-					uniqueNode.Children = append(uniqueNode.Children,
-						SyntaxNode{Type: "COLUMN", Value: tokens[i].Value})
-					i++
-					if i < len(tokens) && tokens[i].Value == "," {
-						i++
-					}
-				} else {
-					return nil, i, fmt.Errorf("se esperaba nombre de columna en UNIQUE")
-				}
-			}
-
-			if i >= len(tokens) || tokens[i].Value != ")" {
-				return nil, i, fmt.Errorf("se esperaba ')' para cerrar UNIQUE")
-			}
-			i++
+// parseSetOperand lee un SELECT simple o una consulta entre paréntesis.
+func (p *parser) parseSetOperand() (Query, error) {
+	if !p.is("(") {
+		return p.parseSimpleSelect()
+	}
+	p.next()
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")", "para cerrar la consulta"); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
 
-			constraint.Children = append(constraint.Children, *uniqueNode)
-		} else {
-			return nil, i, fmt.Errorf("se esperaba '(' después de UNIQUE")
+// parseResultClauses lee ORDER BY, LIMIT y OFFSET. Una consulta entre
+// paréntesis puede traer ya las suyas; repetirlas es un error.
+func (p *parser) parseResultClauses(clauses *ResultClauses) error {
+	var err error
+	if p.isKeyword("ORDER", "BY") && clauses.OrderBy != nil {
+		return p.errorf("la consulta no puede tener dos cláusulas ORDER BY")
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY", "después de ORDER"); err != nil {
+			return err
+		}
+		if clauses.OrderBy, err = p.parseOrderBy(); err != nil {
+			return err
 		}
-
-	default:
-		return nil, i, fmt.Errorf("tipo de constraint de tabla no reconocido: '%s'", tokens[i].Value)
 	}
-
-	return constraint, i, nil
+	if p.isKeyword("LIMIT") && clauses.Limit != nil {
+		return p.errorf("la consulta no puede tener dos cláusulas LIMIT")
+	}
+	if p.acceptKeyword("LIMIT") {
+		if clauses.Limit, err = p.parseCount("LIMIT"); err != nil {
+			return err
+		}
+	}
+	if p.isKeyword("OFFSET") && clauses.Offset != nil {
+		return p.errorf("la consulta no puede tener dos cláusulas OFFSET")
+	}
+	if p.acceptKeyword("OFFSET") {
+		if clauses.Offset, err = p.parseCount("OFFSET"); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// Mantener las demás funciones del código anterior...
-// analyzeSelect, analyzeInsert, analyzeUpdate, analyzeDelete, analyzeDrop, etc.
-// (las mismas que en la versión anterior)
+// parseSimpleSelect lee un SELECT sin ORDER BY, LIMIT ni OFFSET, que
+// corresponden a la consulta completa.
+func (p *parser) parseSimpleSelect() (*SelectStmt, error) {
+	start := p.pos
+	stmt := &SelectStmt{}
 
-func analyzeSelect(tokens []Token) (*SyntaxNode, error) {
-	root := &SyntaxNode{Type: "SELECT_STATEMENT"}
-	i := 0
-
-	// Verificar SELECT
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "SELECT" {
-		return nil, fmt.Errorf("se esperaba SELECT")
+	if err := p.expectKeyword("SELECT", "al inicio de la consulta"); err != nil {
+		return nil, err
 	}
-	i++
+	stmt.Distinct = p.acceptKeyword("DISTINCT")
 
-	// Verificar que hay columnas después de SELECT
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaban columnas después de SELECT")
+	if p.atEnd() || p.isKeyword("FROM") {
+		return nil, p.errorf("se debe especificar al menos una columna después de SELECT")
 	}
 
-	// Verificar DISTINCT (opcional)
-	hasDistinct := false
-	if strings.ToUpper(tokens[i].Value) == "DISTINCT" {
-		hasDistinct = true
-		i++
-		if i >= len(tokens) {
-			return nil, fmt.Errorf("se esperaban columnas después de DISTINCT")
+	// Columnas
+	for {
+		if p.is(",") || p.isKeyword("FROM") {
+			return nil, p.errorf("se esperaba una columna antes de '%s'", p.current())
+		}
+		item, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		stmt.Columns = append(stmt.Columns, item)
+		if !p.accept(",") {
+			break
 		}
 	}
 
-	// Analizar columnas
-	columnsNode := &SyntaxNode{Type: "COLUMNS"}
-	if hasDistinct {
-		columnsNode.Type = "DISTINCT_COLUMNS"
-	}
-
-	columnCount := 0
-	expectingColumn := true
-
-	for i < len(tokens) && strings.ToUpper(tokens[i].Value) != "FROM" {
-		if tokens[i].Value == "," {
-			if !expectingColumn {
-				expectingColumn = true
-			} else {
-				return nil, fmt.Errorf("se esperaba una columna antes de ','")
-			}
-		} else if expectingColumn {
-			if tokens[i].Type == "IDENTIFICADOR" || tokens[i].Value == "*" {
-				columnsNode.Children = append(columnsNode.Children,
-					SyntaxNode{Type: "COLUMN", Value: tokens[i].Value})
-				columnCount++
-				expectingColumn = false
-			} else if tokens[i].Type == "PALABRA_CLAVE" &&
-				(strings.ToUpper(tokens[i].Value) == "COUNT" ||
-					strings.ToUpper(tokens[i].Value) == "SUM" ||
-					strings.ToUpper(tokens[i].Value) == "AVG" ||
-					strings.ToUpper(tokens[i].Value) == "MAX" ||
-					strings.ToUpper(tokens[i].Value) == "MIN") {
-				// Función agregada
-				funcNode := &SyntaxNode{Type: "FUNCTION", Value: tokens[i].Value}
-				i++
-				if i < len(tokens) && tokens[i].Value == "(" {
-					i++
-					// Contenido de la función
-					for i < len(tokens) && tokens[i].Value != ")" {
-						i++
-					}
-					if i >= len(tokens) {
-						return nil, fmt.Errorf("se esperaba ')' para cerrar la función")
-					}
-				}
-				columnsNode.Children = append(columnsNode.Children, *funcNode)
-				columnCount++
-				expectingColumn = false
-			} else {
-				return nil, fmt.Errorf("se esperaba un nombre de columna o '*', se encontró '%s'", tokens[i].Value)
+	// FROM es opcional (SELECT 1, SELECT now()), salvo para '*'
+	var err error
+	if p.acceptKeyword("FROM") {
+		if stmt.From, err = p.parseFrom(); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, item := range stmt.Columns {
+			if column, ok := item.Expr.(*ColumnRef); ok && column.IsStar() {
+				return nil, newAnalysisError(column.Span, "SELECT %s requiere una cláusula FROM", column.Name.String())
 			}
 		}
-		i++
 	}
 
-	if columnCount == 0 {
-		return nil, fmt.Errorf("se debe especificar al menos una columna después de SELECT")
+	// Cláusulas opcionales, en el orden que exige SQL
+	if p.acceptKeyword("WHERE") {
+		if stmt.Where, err = p.parseCondition("WHERE"); err != nil {
+			return nil, err
+		}
 	}
-
-	if expectingColumn {
-		return nil, fmt.Errorf("se esperaba una columna después de ','")
+	if p.acceptKeyword("GROUP") {
+		if err := p.expectKeyword("BY", "después de GROUP"); err != nil {
+			return nil, err
+		}
+		if stmt.GroupBy, err = p.parseExprList("GROUP BY"); err != nil {
+			return nil, err
+		}
 	}
-
-	root.Children = append(root.Children, *columnsNode)
-
-	// FROM es obligatorio en SELECT
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "FROM" {
-		return nil, fmt.Errorf("se esperaba FROM después de las columnas")
+	if p.acceptKeyword("HAVING") {
+		if stmt.Having, err = p.parseCondition("HAVING"); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("WINDOW") {
+		if stmt.Windows, err = p.parseWindowClause(); err != nil {
+			return nil, err
+		}
 	}
-	i++
+	stmt.Span = p.spanFrom(start)
+	return stmt, nil
+}
 
-	// Tabla después de FROM
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba nombre de tabla después de FROM")
+// parseFrom lee la lista de FROM: elementos separados por comas, cada uno
+// con su cadena de JOINs.
+func (p *parser) parseFrom() ([]TableExpr, error) {
+	var from []TableExpr
+	for {
+		item, err := p.parseJoinChain()
+		if err != nil {
+			return nil, err
+		}
+		from = append(from, item)
+		if !p.accept(",") {
+			return from, nil
+		}
 	}
+}
 
-	if tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("se esperaba un nombre de tabla válido después de FROM, se encontró '%s'", tokens[i].Value)
+// parseJoinChain lee una tabla seguida de cero o más JOINs, que se asocian
+// por la izquierda: a JOIN b JOIN c es (a JOIN b) JOIN c.
+func (p *parser) parseJoinChain() (TableExpr, error) {
+	start := p.pos
+	left, err := p.parseTablePrimary()
+	if err != nil {
+		return nil, err
 	}
 
-	tableNode := &SyntaxNode{Type: "TABLE", Value: tokens[i].Value}
-	root.Children = append(root.Children, *tableNode)
-	i++
+	for {
+		joinStart := p.pos
+		kind, natural, ok, err := p.parseJoinType()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return left, nil
+		}
+		join := &JoinExpr{Kind: kind, Natural: natural, Left: left}
+		name := strings.TrimSpace(strings.Join(p.tokenValues(joinStart, p.pos), " "))
 
-	// Analizar cláusulas opcionales
-	for i < len(tokens) {
-		upperValue := strings.ToUpper(tokens[i].Value)
+		if join.Right, err = p.parseTablePrimary(); err != nil {
+			return nil, err
+		}
 
-		switch upperValue {
-		case "WHERE":
-			whereNode, newIndex, err := analyzeWhereClause(tokens, i)
-			if err != nil {
+		switch {
+		case kind == "CROSS" || natural:
+			if p.isKeyword("ON") || p.isKeyword("USING") {
+				return nil, p.errorf("%s no admite %s", strings.ToUpper(name), strings.ToUpper(p.current()))
+			}
+		case p.acceptKeyword("ON"):
+			if join.On, err = p.parseCondition("ON"); err != nil {
 				return nil, err
 			}
-			root.Children = append(root.Children, *whereNode)
-			i = newIndex
-
-		case "GROUP":
-			if i+1 < len(tokens) && strings.ToUpper(tokens[i+1].Value) == "BY" {
-				groupNode, newIndex, err := analyzeGroupByClause(tokens, i)
-				if err != nil {
-					return nil, err
-				}
-				root.Children = append(root.Children, *groupNode)
-				i = newIndex
-			} else {
-				return nil, fmt.Errorf("se esperaba BY después de GROUP")
+		case p.acceptKeyword("USING"):
+			if join.Using, err = p.parseIdentifierList("en USING"); err != nil {
+				return nil, err
 			}
+		default:
+			return nil, p.errorf("se esperaba ON o USING en %s, se encontró '%s'", strings.ToUpper(name), p.current())
+		}
 
-		case "ORDER":
-			if i+1 < len(tokens) && strings.ToUpper(tokens[i+1].Value) == "BY" {
-				orderNode, newIndex, err := analyzeOrderByClause(tokens, i)
-				if err != nil {
-					return nil, err
-				}
-				root.Children = append(root.Children, *orderNode)
-				i = newIndex
-			} else {
-				return nil, fmt.Errorf("se esperaba BY después de ORDER")
+		join.Span = p.spanFrom(start)
+		left = join
+	}
+}
+
+// parseJoinType lee el tipo de JOIN si lo hay: [NATURAL] [INNER | LEFT
+// [OUTER] | RIGHT [OUTER] | FULL [OUTER]] JOIN o CROSS JOIN.
+func (p *parser) parseJoinType() (kind string, natural bool, ok bool, err error) {
+	natural = p.acceptKeyword("NATURAL")
+
+	switch {
+	case p.isKeyword("JOIN"):
+		kind = "INNER"
+	case p.acceptKeyword("INNER"):
+		kind = "INNER"
+	case p.isKeyword("LEFT") || p.isKeyword("RIGHT") || p.isKeyword("FULL"):
+		kind = strings.ToUpper(p.next().Value)
+		p.acceptKeyword("OUTER")
+	case !natural && p.acceptKeyword("CROSS"):
+		kind = "CROSS"
+	case natural:
+		return "", false, false, p.errorf("se esperaba JOIN después de NATURAL, se encontró '%s'", p.current())
+	default:
+		return "", false, false, nil
+	}
+
+	if err := p.expectKeyword("JOIN", "después de "+kind); err != nil {
+		return "", false, false, err
+	}
+	return kind, natural, true, nil
+}
+
+// parseTablePrimary lee una tabla, una subconsulta con alias o un JOIN
+// entre paréntesis.
+func (p *parser) parseTablePrimary() (TableExpr, error) {
+	if p.isKeyword("LATERAL") || p.isSubquery() {
+		return p.parseDerivedTable()
+	}
+	if !p.accept("(") {
+		table, err := p.parseAliasedTableRef("en FROM")
+		if err != nil {
+			return nil, err
+		}
+		table.CTE = p.lookupCTE(table.Name)
+		return table, nil
+	}
+	item, err := p.parseJoinChain()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")", "para cerrar el JOIN"); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// parseWith lee "WITH [RECURSIVE] cte, ..." y deja visibles sus CTE para el
+// resto de la consulta. Cada CTE ve a las anteriores y, con RECURSIVE,
+// también a sí misma.
+func (p *parser) parseWith() (*WithClause, error) {
+	start := p.pos
+	p.next() // WITH
+	with := &WithClause{Recursive: p.acceptKeyword("RECURSIVE")}
+
+	for {
+		cteStart := p.pos
+		name, err := p.parseIdentifier("el nombre de la CTE")
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range with.CTEs {
+			if other.Name.Name == name.Name {
+				return nil, newAnalysisError(name.Span, "el nombre de CTE '%s' está repetido en WITH", name.String())
 			}
+		}
+		cte := &CommonTableExpr{Name: name}
+		if p.is("(") {
+			if cte.Columns, err = p.parseIdentifierList("en las columnas de " + name.String()); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expectKeyword("AS", "después del nombre de la CTE "+name.String()); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.acceptKeyword("MATERIALIZED"):
+			cte.Materialized = "MATERIALIZED"
+		case p.acceptKeyword("NOT", "MATERIALIZED"):
+			cte.Materialized = "NOT MATERIALIZED"
+		}
 
-		case "LIMIT":
-			if i+1 < len(tokens) && tokens[i+1].Type == "NUMERO" {
-				limitNode := &SyntaxNode{Type: "LIMIT", Value: tokens[i+1].Value}
-				root.Children = append(root.Children, *limitNode)
-				i += 2
-			} else {
-				return nil, fmt.Errorf("se esperaba un número después de LIMIT")
+		if with.Recursive {
+			p.ctes = append(p.ctes, cte)
+		}
+		if !p.isSubquery() {
+			return nil, p.errorf("se esperaba una consulta entre paréntesis para la CTE %s, se encontró '%s'", name.String(), p.current())
+		}
+		if cte.Query, err = p.parseSubquery(); err != nil {
+			return nil, err
+		}
+		if !with.Recursive {
+			p.ctes = append(p.ctes, cte)
+		}
+		cte.Span = p.spanFrom(cteStart)
+		with.CTEs = append(with.CTEs, cte)
+
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	with.Span = p.spanFrom(start)
+	return with, nil
+}
+
+// lookupCTE busca una CTE visible con el nombre indicado.
+func (p *parser) lookupCTE(name QualifiedName) *CommonTableExpr {
+	if len(name.Parts) != 1 {
+		return nil
+	}
+	for i := len(p.ctes) - 1; i >= 0; i-- {
+		if p.ctes[i].Name.Name == name.Name() {
+			return p.ctes[i]
+		}
+	}
+	return nil
+}
+
+// parseDerivedTable lee "[LATERAL] (SELECT ...) [AS] alias [(columnas)]".
+func (p *parser) parseDerivedTable() (*DerivedTable, error) {
+	start := p.pos
+	derived := &DerivedTable{Lateral: p.acceptKeyword("LATERAL")}
+	var err error
+	if derived.Query, err = p.parseSubquery(); err != nil {
+		return nil, err
+	}
+	if derived.Alias, err = p.parseAlias(); err != nil {
+		return nil, err
+	}
+	if derived.Alias.Name == "" {
+		return nil, errorAt(p.tokens, start, "una subconsulta en FROM debe tener un alias")
+	}
+	if p.is("(") {
+		if derived.ColumnAliases, err = p.parseIdentifierList("en los alias de " + derived.Alias.String()); err != nil {
+			return nil, err
+		}
+	}
+	derived.Span = p.spanFrom(start)
+	return derived, nil
+}
+
+// tokenValues devuelve el texto de tokens[from:to].
+func (p *parser) tokenValues(from, to int) []string {
+	values := make([]string, 0, to-from)
+	for _, tok := range p.tokens[from:to] {
+		values = append(values, tok.Value)
+	}
+	return values
+}
+
+func (p *parser) parseSelectItem() (*SelectItem, error) {
+	start := p.pos
+	if p.is("*") {
+		tok := p.next()
+		expr := &ColumnRef{Name: QualifiedName{Parts: []Identifier{{Name: "*", Span: tok.Span}}, Span: tok.Span}, Span: tok.Span}
+		return &SelectItem{Expr: expr, Span: tok.Span}, nil
+	}
+
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	item := &SelectItem{Expr: expr}
+	if column, ok := expr.(*ColumnRef); !ok || !column.IsStar() {
+		// tabla.* no admite alias
+		if item.Alias, err = p.parseAlias(); err != nil {
+			return nil, err
+		}
+	}
+	item.Span = p.spanFrom(start)
+	return item, nil
+}
+
+// parseCondition lee la condición de WHERE, HAVING u ON.
+func (p *parser) parseCondition(clause string) (Expr, error) {
+	if p.atEnd() || p.is(";") || p.is(")") {
+		return nil, p.errorf("%s requiere al menos una condición", clause)
+	}
+	return p.parseExpr()
+}
+
+// parseExprList lee una lista de expresiones separadas por comas.
+func (p *parser) parseExprList(clause string) ([]Expr, error) {
+	var exprs []Expr
+	for {
+		if p.atEnd() || p.is(",") || p.is(";") {
+			return nil, p.errorf("se esperaba una expresión en %s, se encontró '%s'", clause, p.current())
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+		if !p.accept(",") {
+			return exprs, nil
+		}
+	}
+}
+
+func (p *parser) parseOrderBy() ([]*OrderItem, error) {
+	var items []*OrderItem
+	for {
+		start := p.pos
+		if p.atEnd() || p.is(",") || p.is(";") {
+			return nil, p.errorf("se esperaba una expresión en ORDER BY, se encontró '%s'", p.current())
+		}
+		expr, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		item := &OrderItem{Expr: expr}
+
+		// Dirección (opcional)
+		if p.isKeyword("ASC") || p.isKeyword("DESC") {
+			item.Direction = strings.ToUpper(p.next().Value)
+		}
+		item.Span = p.spanFrom(start)
+		items = append(items, item)
+
+		if !p.accept(",") {
+			return items, nil
+		}
+	}
+}
+
+// parseWindowClause lee las ventanas con nombre de WINDOW:
+// nombre AS (definición) [, ...].
+func (p *parser) parseWindowClause() ([]*WindowSpec, error) {
+	var windows []*WindowSpec
+	for {
+		start := p.pos
+		name, err := p.parseIdentifier("el nombre de la ventana en WINDOW")
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range windows {
+			if other.Name.Name == name.Name {
+				return nil, newAnalysisError(name.Span, "la ventana '%s' está definida más de una vez en WINDOW", name.String())
 			}
+		}
+		if err := p.expectKeyword("AS", "después del nombre de la ventana "+name.String()); err != nil {
+			return nil, err
+		}
+		window, err := p.parseWindowSpec()
+		if err != nil {
+			return nil, err
+		}
+		window.Name = name
+		window.Span = p.spanFrom(start)
+		windows = append(windows, window)
 
-		case ";":
-			i++
+		if !p.accept(",") {
+			return windows, nil
+		}
+	}
+}
 
-		default:
-			return nil, fmt.Errorf("cláusula no reconocida: '%s'", tokens[i].Value)
+// parseWindowSpec lee la definición de una ventana entre paréntesis:
+// ([ventana base] [PARTITION BY ...] [ORDER BY ...] [marco]).
+func (p *parser) parseWindowSpec() (*WindowSpec, error) {
+	start := p.pos
+	if err := p.expect("(", "para la definición de la ventana"); err != nil {
+		return nil, err
+	}
+	window := &WindowSpec{}
+	if p.isIdentifier() && !p.isKeyword("PARTITION") && !p.isFrameUnits() {
+		window.Base = identifierFromToken(p.next())
+	}
+
+	var err error
+	if p.acceptKeyword("PARTITION") {
+		if err := p.expectKeyword("BY", "después de PARTITION"); err != nil {
+			return nil, err
+		}
+		if window.PartitionBy, err = p.parseExprList("PARTITION BY"); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err := p.expectKeyword("BY", "después de ORDER"); err != nil {
+			return nil, err
+		}
+		if window.OrderBy, err = p.parseOrderBy(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isFrameUnits() {
+		if window.Frame, err = p.parseWindowFrame(); err != nil {
+			return nil, err
 		}
 	}
 
-	return root, nil
+	if err := p.expect(")", "para cerrar la definición de la ventana"); err != nil {
+		return nil, err
+	}
+	window.Span = p.spanFrom(start)
+	return window, nil
 }
 
-func analyzeInsert(tokens []Token) (*SyntaxNode, error) {
-	root := &SyntaxNode{Type: "INSERT_STATEMENT"}
-	i := 0
+func (p *parser) isFrameUnits() bool {
+	return p.isKeyword("ROWS") || p.isKeyword("RANGE") || p.isKeyword("GROUPS")
+}
 
-	// Verificar INSERT
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "INSERT" {
-		return nil, fmt.Errorf("se esperaba INSERT")
+// frameBoundOrder ordena los límites de un marco: el inicio no puede estar
+// después del fin.
+var frameBoundOrder = map[string]int{
+	"UNBOUNDED PRECEDING": 0, "PRECEDING": 1, "CURRENT ROW": 2, "FOLLOWING": 3, "UNBOUNDED FOLLOWING": 4,
+}
+
+// parseWindowFrame lee el marco de una ventana:
+// ROWS|RANGE|GROUPS {inicio | BETWEEN inicio AND fin} [EXCLUDE ...]. Sin
+// BETWEEN el marco termina en la fila actual.
+func (p *parser) parseWindowFrame() (*WindowFrame, error) {
+	start := p.pos
+	frame := &WindowFrame{Units: strings.ToUpper(p.next().Value)}
+
+	var err error
+	between := p.acceptKeyword("BETWEEN")
+	if frame.Start, err = p.parseFrameBound(); err != nil {
+		return nil, err
+	}
+	if between {
+		if err := p.expectKeyword("AND", "entre los límites del marco"); err != nil {
+			return nil, err
+		}
+		if frame.End, err = p.parseFrameBound(); err != nil {
+			return nil, err
+		}
 	}
-	i++
 
-	// Verificar INTO
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "INTO" {
-		return nil, fmt.Errorf("se esperaba INTO después de INSERT")
+	end := "CURRENT ROW"
+	if frame.End != nil {
+		end = frame.End.Kind
 	}
-	i++
+	switch {
+	case frame.Start.Kind == "UNBOUNDED FOLLOWING":
+		return nil, newAnalysisError(frame.Start.Span, "el marco de la ventana no puede comenzar en UNBOUNDED FOLLOWING")
+	case end == "UNBOUNDED PRECEDING":
+		return nil, newAnalysisError(frame.End.Span, "el marco de la ventana no puede terminar en UNBOUNDED PRECEDING")
+	case frameBoundOrder[frame.Start.Kind] > frameBoundOrder[end]:
+		return nil, newAnalysisError(p.spanFrom(start), "el marco de la ventana no puede comenzar en %s y terminar en %s",
+			frame.Start.Kind, end)
+	}
+
+	if p.acceptKeyword("EXCLUDE") {
+		switch {
+		case p.acceptKeyword("CURRENT", "ROW"):
+			frame.Exclude = "CURRENT ROW"
+		case p.acceptKeyword("GROUP"):
+			frame.Exclude = "GROUP"
+		case p.acceptKeyword("TIES"):
+			frame.Exclude = "TIES"
+		case p.acceptKeyword("NO", "OTHERS"):
+			frame.Exclude = "NO OTHERS"
+		default:
+			return nil, p.errorf("se esperaba CURRENT ROW, GROUP, TIES o NO OTHERS después de EXCLUDE, se encontró '%s'", p.current())
+		}
+	}
+	frame.Span = p.spanFrom(start)
+	return frame, nil
+}
 
-	// Nombre de tabla
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba nombre de tabla después de INTO")
+// parseFrameBound lee un límite del marco: UNBOUNDED PRECEDING, UNBOUNDED
+// FOLLOWING, CURRENT ROW o un desplazamiento seguido de PRECEDING o
+// FOLLOWING.
+func (p *parser) parseFrameBound() (*FrameBound, error) {
+	start := p.pos
+	bound := &FrameBound{}
+	switch {
+	case p.acceptKeyword("UNBOUNDED", "PRECEDING"):
+		bound.Kind = "UNBOUNDED PRECEDING"
+	case p.acceptKeyword("UNBOUNDED", "FOLLOWING"):
+		bound.Kind = "UNBOUNDED FOLLOWING"
+	case p.acceptKeyword("CURRENT", "ROW"):
+		bound.Kind = "CURRENT ROW"
+	default:
+		if p.atEnd() || p.is(")") || p.isKeyword("AND") || p.isKeyword("UNBOUNDED") {
+			return nil, p.errorf("se esperaba un límite del marco (UNBOUNDED PRECEDING, CURRENT ROW, n PRECEDING...), se encontró '%s'", p.current())
+		}
+		// El desplazamiento no puede contener AND, que separa los límites
+		offset, err := p.parseBinary(precAdditive)
+		if err != nil {
+			return nil, err
+		}
+		bound.Offset = offset
+		switch {
+		case p.acceptKeyword("PRECEDING"):
+			bound.Kind = "PRECEDING"
+		case p.acceptKeyword("FOLLOWING"):
+			bound.Kind = "FOLLOWING"
+		default:
+			return nil, p.errorf("se esperaba PRECEDING o FOLLOWING después del desplazamiento del marco, se encontró '%s'", p.current())
+		}
 	}
+	bound.Span = p.spanFrom(start)
+	return bound, nil
+}
 
-	if tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("nombre de tabla inválido: '%s'", tokens[i].Value)
+// parseCount lee la cantidad de LIMIT u OFFSET: una expresión o, en LIMIT,
+// ALL.
+func (p *parser) parseCount(clause string) (Expr, error) {
+	if clause == "LIMIT" && p.isKeyword("ALL") {
+		tok := p.next()
+		return &Literal{Kind: "NULL", Value: "ALL", Span: tok.Span}, nil
+	}
+	if p.atEnd() {
+		return nil, p.errorf("se esperaba una cantidad después de %s", clause)
 	}
+	return p.parseExpr()
+}
+
+func (p *parser) analyzeInsert() (*InsertStmt, error) {
+	start := p.pos
+	stmt := &InsertStmt{}
+	var err error
 
-	tableNode := &SyntaxNode{Type: "TABLE", Value: tokens[i].Value}
-	root.Children = append(root.Children, *tableNode)
-	i++
+	if err := p.expectKeyword("INSERT", "al inicio de la sentencia"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("INTO", "después de INSERT"); err != nil {
+		return nil, err
+	}
+	tableStart := p.pos
+	if stmt.Table, err = p.parseTableRef("después de INTO"); err != nil {
+		return nil, err
+	}
+	// El alias de INSERT solo se admite con AS
+	if p.acceptKeyword("AS") {
+		if stmt.Table.Alias, err = p.parseIdentifier("un alias después de AS"); err != nil {
+			return nil, err
+		}
+		stmt.Table.Span = p.spanFrom(tableStart)
+	}
 
 	// Columnas (opcional)
-	columnsNode := &SyntaxNode{Type: "COLUMNS"}
-	if i < len(tokens) && tokens[i].Value == "(" {
-		i++
+	if p.is("(") && !p.isSubquery() {
+		if stmt.Columns, err = p.parseIdentifierList("en INSERT"); err != nil {
+			return nil, err
+		}
+	}
+
+	// Origen de las filas: VALUES, una consulta o DEFAULT VALUES
+	switch {
+	case p.acceptKeyword("DEFAULT", "VALUES"):
+		if len(stmt.Columns) > 0 {
+			return nil, newAnalysisError(stmt.Table.Span, "DEFAULT VALUES no admite una lista de columnas")
+		}
+		stmt.DefaultValues = true
+	case p.acceptKeyword("VALUES"):
+		if stmt.Values, err = p.parseValuesRows(); err != nil {
+			return nil, err
+		}
+	case p.isKeyword("SELECT") || p.isKeyword("WITH") || p.isSubquery():
+		if stmt.Query, err = p.parseQuery(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorf("se esperaba VALUES, SELECT o DEFAULT VALUES en INSERT, se encontró '%s'", p.current())
+	}
+
+	if p.isKeyword("ON", "CONFLICT") {
+		if stmt.OnConflict, err = p.parseOnConflict(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("RETURNING") {
+		if stmt.Returning, err = p.parseReturning(); err != nil {
+			return nil, err
+		}
+	}
+
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd("INSERT")
+}
+
+// parseValuesRows lee las filas de VALUES: "(v1, v2), (v3, v4)...". Cada
+// valor es una expresión o DEFAULT.
+func (p *parser) parseValuesRows() ([][]Expr, error) {
+	var rows [][]Expr
+	for {
+		if err := p.expect("(", "después de VALUES"); err != nil {
+			return nil, err
+		}
+		if p.is(")") {
+			return nil, p.errorf("se debe especificar al menos un valor")
+		}
+		var row []Expr
+		for {
+			value, err := p.parseValue("VALUES")
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, value)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")", "para cerrar los valores"); err != nil {
+			return nil, err
+		}
+		if len(rows) > 0 && len(row) != len(rows[0]) {
+			return nil, p.errorf("todas las filas de VALUES deben tener la misma cantidad de valores: la primera tiene %d y la fila %d tiene %d",
+				len(rows[0]), len(rows)+1, len(row))
+		}
+		rows = append(rows, row)
+		if !p.accept(",") {
+			return rows, nil
+		}
+	}
+}
+
+// parseOnConflict lee "ON CONFLICT [(columnas) [WHERE ...] | ON CONSTRAINT
+// nombre] DO NOTHING | DO UPDATE SET ... [WHERE ...]".
+func (p *parser) parseOnConflict() (*OnConflict, error) {
+	start := p.pos
+	clause := &OnConflict{}
+	var err error
+	p.pos += 2 // ON CONFLICT
+
+	switch {
+	case p.acceptKeyword("ON", "CONSTRAINT"):
+		if clause.Constraint, err = p.parseIdentifier("nombre de restricción después de ON CONSTRAINT"); err != nil {
+			return nil, err
+		}
+	case p.is("("):
+		if clause.Target, err = p.parseIdentifierList("en ON CONFLICT"); err != nil {
+			return nil, err
+		}
+		if p.acceptKeyword("WHERE") {
+			if clause.TargetWhere, err = p.parseCondition("WHERE"); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-		columnCount := 0
-		expectingColumn := true
+	if err := p.expectKeyword("DO", "en ON CONFLICT"); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.acceptKeyword("NOTHING"):
+		clause.Action = "NOTHING"
+	case p.acceptKeyword("UPDATE"):
+		clause.Action = "UPDATE"
+		if len(clause.Target) == 0 && clause.Constraint.Name == "" {
+			return nil, p.errorf("ON CONFLICT DO UPDATE requiere las columnas del conflicto o ON CONSTRAINT")
+		}
+		if err := p.expectKeyword("SET", "después de DO UPDATE"); err != nil {
+			return nil, err
+		}
+		if clause.Set, err = p.parseAssignments(); err != nil {
+			return nil, err
+		}
+		if p.acceptKeyword("WHERE") {
+			if clause.Where, err = p.parseCondition("WHERE"); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, p.errorf("se esperaba NOTHING o UPDATE después de DO, se encontró '%s'", p.current())
+	}
+
+	clause.Span = p.spanFrom(start)
+	return clause, nil
+}
 
-		for i < len(tokens) && tokens[i].Value != ")" {
-			if tokens[i].Value == "," {
-				if !expectingColumn {
-					expectingColumn = true
-				} else {
-					return nil, fmt.Errorf("se esperaba un nombre de columna antes de ','")
+// parseReturning lee la lista de columnas de RETURNING.
+func (p *parser) parseReturning() ([]*SelectItem, error) {
+	if p.atEnd() || p.is(";") {
+		return nil, p.errorf("RETURNING requiere al menos una columna")
+	}
+	var items []*SelectItem
+	for {
+		item, err := p.parseSelectItem()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.accept(",") {
+			return items, nil
+		}
+	}
+}
+
+// parseAssignments lee las asignaciones de un SET: "columna = valor" o
+// "(a, b) = (valor, valor)", donde los valores pueden venir también de una
+// subconsulta.
+func (p *parser) parseAssignments() ([]*Assignment, error) {
+	var assignments []*Assignment
+	for {
+		assignStart := p.pos
+		assignment := &Assignment{}
+		var err error
+		if p.is("(") {
+			if assignment.Columns, err = p.parseIdentifierList("en SET"); err != nil {
+				return nil, err
+			}
+			if err := p.expect("=", "después de la lista de columnas"); err != nil {
+				return nil, err
+			}
+			if p.isSubquery() {
+				subStart := p.pos
+				query, err := p.parseSubquery()
+				if err != nil {
+					return nil, err
+				}
+				assignment.Value = &SubqueryExpr{Query: query, Span: p.spanFrom(subStart)}
+			} else {
+				p.acceptKeyword("ROW")
+				if err := p.expect("(", "con los valores de la lista de columnas"); err != nil {
+					return nil, err
+				}
+				for {
+					value, err := p.parseValue("SET")
+					if err != nil {
+						return nil, err
+					}
+					assignment.Values = append(assignment.Values, value)
+					if !p.accept(",") {
+						break
+					}
+				}
+				if err := p.expect(")", "para cerrar los valores"); err != nil {
+					return nil, err
+				}
+				if len(assignment.Values) != len(assignment.Columns) {
+					return nil, newAnalysisError(p.spanFrom(assignStart), "la asignación tiene %d columnas pero %d valores",
+						len(assignment.Columns), len(assignment.Values))
 				}
-			} else if expectingColumn && tokens[i].Type == "IDENTIFICADOR" {
-				columnsNode.Children = append(columnsNode.Children,
-					SyntaxNode{Type: "COLUMN", Value: tokens[i].Value})
-				columnCount++
-				expectingColumn = false
-			} else if expectingColumn {
-				return nil, fmt.Errorf("se esperaba un nombre de columna, se encontró '%s'", tokens[i].Value)
 			}
-			i++
+		} else {
+			if assignment.Column, err = p.parseIdentifier("nombre de columna en SET"); err != nil {
+				return nil, err
+			}
+			if err := p.expect("=", "después de '"+assignment.Column.String()+"'"); err != nil {
+				return nil, err
+			}
+			if p.atEnd() || p.is(",") || p.is(";") {
+				return nil, p.errorf("se esperaba un valor después de '='")
+			}
+			if assignment.Value, err = p.parseValue("SET"); err != nil {
+				return nil, err
+			}
+		}
+		assignment.Span = p.spanFrom(assignStart)
+		assignments = append(assignments, assignment)
+		if !p.accept(",") {
+			return assignments, nil
 		}
+	}
+}
+
+// parseValue lee un valor de VALUES o de SET: una expresión o DEFAULT.
+func (p *parser) parseValue(clause string) (Expr, error) {
+	if p.isKeyword("DEFAULT") {
+		tok := p.next()
+		return &Literal{Kind: "DEFAULT", Value: "DEFAULT", Span: tok.Span}, nil
+	}
+	if p.atEnd() || p.is(",") || p.is(")") || p.is(";") {
+		return nil, p.errorf("se esperaba una expresión en %s, se encontró '%s'", clause, p.current())
+	}
+	return p.parseExpr()
+}
+
+func (p *parser) analyzeUpdate() (*UpdateStmt, error) {
+	start := p.pos
+	stmt := &UpdateStmt{}
+	var err error
 
-		if i >= len(tokens) {
-			return nil, fmt.Errorf("se esperaba ')' para cerrar la lista de columnas")
+	if err := p.expectKeyword("UPDATE", "al inicio de la sentencia"); err != nil {
+		return nil, err
+	}
+	if stmt.Table, err = p.parseAliasedTableRef("después de UPDATE"); err != nil {
+		return nil, err
+	}
+
+	// SET es obligatorio
+	if err := p.expectKeyword("SET", "después del nombre de tabla"); err != nil {
+		return nil, err
+	}
+
+	// Asignaciones
+	if stmt.Set, err = p.parseAssignments(); err != nil {
+		return nil, err
+	}
+
+	// FROM agrega tablas para calcular los valores y filtrar las filas
+	if p.acceptKeyword("FROM") {
+		if stmt.From, err = p.parseFrom(); err != nil {
+			return nil, err
+		}
+	}
+
+	// WHERE (opcional pero recomendado)
+	if p.acceptKeyword("WHERE") {
+		if stmt.Where, err = p.parseCondition("WHERE"); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("RETURNING") {
+		if stmt.Returning, err = p.parseReturning(); err != nil {
+			return nil, err
 		}
+	}
+
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd("UPDATE")
+}
+
+func (p *parser) analyzeDelete() (*DeleteStmt, error) {
+	start := p.pos
+	stmt := &DeleteStmt{}
+	var err error
+
+	if err := p.expectKeyword("DELETE", "al inicio de la sentencia"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("FROM", "después de DELETE"); err != nil {
+		return nil, err
+	}
+	if stmt.Table, err = p.parseAliasedTableRef("después de FROM"); err != nil {
+		return nil, err
+	}
 
-		if columnCount == 0 {
-			return nil, fmt.Errorf("se debe especificar al menos una columna")
+	// USING agrega tablas para decidir qué filas eliminar
+	if p.acceptKeyword("USING") {
+		if stmt.Using, err = p.parseFrom(); err != nil {
+			return nil, err
+		}
+	}
+
+	// WHERE (opcional pero muy recomendado)
+	if p.acceptKeyword("WHERE") {
+		if stmt.Where, err = p.parseCondition("WHERE"); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("RETURNING") {
+		if stmt.Returning, err = p.parseReturning(); err != nil {
+			return nil, err
+		}
+	}
+
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd("DELETE")
+}
+
+func (p *parser) analyzeCreate() (Statement, error) {
+	start := p.pos
+	if err := p.expectKeyword("CREATE", "al inicio de la sentencia"); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isKeyword("TABLE"):
+		return p.analyzeCreateTable(start)
+	case p.isKeyword("DATABASE"):
+		return p.analyzeCreateDatabase(start)
+	case p.isKeyword("INDEX"), p.isKeyword("UNIQUE", "INDEX"):
+		return p.analyzeCreateIndex(start)
+	default:
+		return nil, p.errorf("se esperaba TABLE, DATABASE o INDEX después de CREATE, se encontró '%s'", p.current())
+	}
+}
+
+func (p *parser) analyzeCreateTable(start int) (*CreateTableStmt, error) {
+	stmt := &CreateTableStmt{}
+	p.next() // TABLE
+
+	// Verificar IF NOT EXISTS (opcional)
+	stmt.IfNotExists = p.acceptKeyword("IF", "NOT", "EXISTS")
+
+	// Nombre de tabla
+	name, err := p.parseQualifiedName("nombre de tabla después de CREATE TABLE", false)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = name
+
+	// Debe haber paréntesis de apertura
+	if err := p.expect("(", "después del nombre de tabla '"+name.String()+"'"); err != nil {
+		return nil, err
+	}
+
+	// Verificar que no esté vacío
+	if p.is(")") {
+		return nil, p.errorf("la definición de tabla no puede estar vacía")
+	}
+
+	for {
+		if p.atEnd() || p.is(")") {
+			return nil, p.errorf("se esperaba definición después de ','")
+		}
+		if p.isKeyword("PRIMARY") || p.isKeyword("FOREIGN") || p.isKeyword("UNIQUE") ||
+			p.isKeyword("CHECK") || p.isKeyword("CONSTRAINT") {
+			// Es un constraint de tabla
+			constraint, err := p.analyzeTableConstraint()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Constraints = append(stmt.Constraints, constraint)
+		} else {
+			column, err := p.analyzeColumnDefinition()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Columns = append(stmt.Columns, column)
 		}
-
-		if expectingColumn {
-			return nil, fmt.Errorf("se esperaba un nombre de columna después de ','")
+		if !p.accept(",") {
+			break
 		}
-
-		root.Children = append(root.Children, *columnsNode)
-		i++
 	}
 
-	// VALUES es obligatorio
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "VALUES" {
-		return nil, fmt.Errorf("se esperaba VALUES")
+	if len(stmt.Columns) == 0 {
+		return nil, p.errorf("se debe definir al menos una columna en la tabla")
 	}
-	i++
 
-	// Valores
-	if i >= len(tokens) || tokens[i].Value != "(" {
-		return nil, fmt.Errorf("se esperaba '(' después de VALUES")
+	if !p.accept(")") {
+		return nil, p.errorf("se esperaba ')' para cerrar la definición de tabla, se encontró: '%s'", p.current())
 	}
 
-	valuesNode := &SyntaxNode{Type: "VALUES"}
-
-	// Puede haber múltiples conjuntos de valores
-	for i < len(tokens) && tokens[i].Value == "(" {
-		i++
-		valueSet := &SyntaxNode{Type: "VALUE_SET"}
-		valueCount := 0
-		expectingValue := true
-
-		for i < len(tokens) && tokens[i].Value != ")" {
-			if tokens[i].Value == "," {
-				if !expectingValue {
-					expectingValue = true
-				} else {
-					return nil, fmt.Errorf("se esperaba un valor antes de ','")
-				}
-			} else if expectingValue {
-				if tokens[i].Type == "CADENA" || tokens[i].Type == "NUMERO" ||
-					tokens[i].Type == "IDENTIFICADOR" {
-					valueSet.Children = append(valueSet.Children,
-						SyntaxNode{Type: "VALUE", Value: tokens[i].Value})
-					valueCount++
-					expectingValue = false
-				} else {
-					return nil, fmt.Errorf("tipo de valor inválido: '%s'", tokens[i].Value)
-				}
-			}
-			i++
-		}
-
-		if i >= len(tokens) {
-			return nil, fmt.Errorf("se esperaba ')' para cerrar los valores")
-		}
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd("CREATE TABLE")
+}
 
-		if valueCount == 0 {
-			return nil, fmt.Errorf("se debe especificar al menos un valor")
-		}
+// validTypes son los tipos de dato que se aceptan en las definiciones de
+// columna.
+var validTypes = map[string]bool{
+	"INT": true, "INTEGER": true, "BIGINT": true, "SMALLINT": true,
+	"SERIAL": true, "BIGSERIAL": true,
+	"VARCHAR": true, "TEXT": true, "CHAR": true,
+	"DECIMAL": true, "NUMERIC": true, "FLOAT": true, "REAL": true,
+	"DOUBLE PRECISION": true, "MONEY": true,
+	"DATE": true, "TIME": true, "TIMESTAMP": true, "TIMETZ": true, "TIMESTAMPTZ": true, "INTERVAL": true,
+	"BOOLEAN": true, "BOOL": true,
+	"UUID": true, "JSON": true, "JSONB": true,
+	"ARRAY": true, "BYTEA": true,
+}
 
-		if expectingValue {
-			return nil, fmt.Errorf("se esperaba un valor después de ','")
-		}
+// typeAliases son otros nombres que PostgreSQL acepta para los tipos de
+// validTypes.
+var typeAliases = map[string]string{
+	"CHARACTER": "CHAR", "INT4": "INTEGER", "INT8": "BIGINT", "INT2": "SMALLINT",
+	"FLOAT8": "DOUBLE PRECISION", "FLOAT4": "REAL", "DEC": "DECIMAL",
+	"SERIAL4": "SERIAL", "SERIAL8": "BIGSERIAL",
+}
 
-		valuesNode.Children = append(valuesNode.Children, *valueSet)
-		i++
+func (p *parser) analyzeColumnDefinition() (*ColumnDef, error) {
+	start := p.pos
 
-		// Verificar si hay más conjuntos de valores
-		if i < len(tokens) && tokens[i].Value == "," {
-			i++
-		}
+	// Nombre de columna
+	name, err := p.parseIdentifier("nombre de columna")
+	if err != nil {
+		return nil, err
 	}
+	column := &ColumnDef{Name: name}
 
-	if len(valuesNode.Children) == 0 {
-		return nil, fmt.Errorf("se debe especificar al menos un conjunto de valores")
+	// Tipo de dato
+	if p.atEnd() || p.is(",") || p.is(")") {
+		return nil, p.errorf("se esperaba tipo de dato para la columna '%s'", name.String())
+	}
+	if column.Type, err = p.parseDataType(); err != nil {
+		return nil, err
 	}
 
-	root.Children = append(root.Children, *valuesNode)
-
-	// Verificar punto y coma opcional
-	if i < len(tokens) && tokens[i].Value != ";" {
-		return nil, fmt.Errorf("se esperaba ';' al final de INSERT, se encontró: '%s'", tokens[i].Value)
+	// Constraints
+	for !p.atEnd() && !p.is(",") && !p.is(")") && !p.is(";") {
+		constraint, err := p.analyzeColumnConstraint(name)
+		if err != nil {
+			return nil, err
+		}
+		column.Constraints = append(column.Constraints, constraint)
 	}
 
-	return root, nil
+	column.Span = p.spanFrom(start)
+	return column, nil
 }
 
-func analyzeUpdate(tokens []Token) (*SyntaxNode, error) {
-	root := &SyntaxNode{Type: "UPDATE_STATEMENT"}
-	i := 0
+// parseDataType lee un tipo de dato válido con sus parámetros opcionales.
+func (p *parser) parseDataType() (*DataType, error) {
+	start := p.pos
+	tok := p.next()
+	upperType := strings.ToUpper(tok.Value)
 
-	// Verificar UPDATE
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "UPDATE" {
-		return nil, fmt.Errorf("se esperaba UPDATE")
+	// Tipos con palabras múltiples y otros nombres de los tipos
+	switch {
+	case upperType == "DOUBLE" && p.acceptKeyword("PRECISION"):
+		upperType = "DOUBLE PRECISION"
+	case upperType == "CHARACTER" && p.acceptKeyword("VARYING"):
+		upperType = "VARCHAR"
+	case typeAliases[upperType] != "" && !strings.HasPrefix(tok.Value, `"`):
+		upperType = typeAliases[upperType]
+	}
+	if !validTypes[upperType] || strings.HasPrefix(tok.Value, `"`) {
+		return nil, errorAt(p.tokens, start, "tipo de dato inválido: '%s'", tok.Value)
+	}
+	dataType := &DataType{Name: upperType}
+
+	// Verificar parámetros del tipo (ej: VARCHAR(50), DECIMAL(10,2))
+	if p.accept("(") {
+		for {
+			if p.peek().Type != "NUMERO" {
+				return nil, p.errorf("se esperaba número para el tamaño de %s", upperType)
+			}
+			dataType.Params = append(dataType.Params, p.next().Value)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")", "para cerrar los parámetros de "+upperType); err != nil {
+			return nil, err
+		}
 	}
-	i++
 
-	// Nombre de tabla
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba nombre de tabla después de UPDATE")
+	// TIME y TIMESTAMP [(precisión)] WITH | WITHOUT TIME ZONE
+	if upperType == "TIME" || upperType == "TIMESTAMP" {
+		if p.acceptKeyword("WITH", "TIME", "ZONE") {
+			dataType.Name += "TZ"
+		} else {
+			p.acceptKeyword("WITHOUT", "TIME", "ZONE")
+		}
 	}
 
-	if tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("nombre de tabla inválido: '%s'", tokens[i].Value)
+	// Arreglos: INTEGER[], TEXT[][], INTEGER[3] (el tamaño no se verifica)
+	for p.accept("[") {
+		if p.peek().Type == "NUMERO" {
+			p.next()
+		}
+		if err := p.expect("]", "para cerrar el tipo arreglo "+upperType+"[]"); err != nil {
+			return nil, err
+		}
+		dataType.ArrayDims++
 	}
 
-	tableNode := &SyntaxNode{Type: "TABLE", Value: tokens[i].Value}
-	root.Children = append(root.Children, *tableNode)
-	i++
-
-	// SET es obligatorio
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "SET" {
-		return nil, fmt.Errorf("se esperaba SET después del nombre de tabla")
-	}
-	i++
+	dataType.Span = p.spanFrom(start)
+	return dataType, nil
+}
 
-	// Asignaciones
-	setNode := &SyntaxNode{Type: "SET_CLAUSE"}
-	assignmentCount := 0
+func (p *parser) analyzeColumnConstraint(column Identifier) (*ColumnConstraint, error) {
+	start := p.pos
+	constraint := &ColumnConstraint{}
+	var err error
 
-	for i < len(tokens) && strings.ToUpper(tokens[i].Value) != "WHERE" && tokens[i].Value != ";" {
-		// Columna
-		if tokens[i].Type != "IDENTIFICADOR" {
-			return nil, fmt.Errorf("se esperaba nombre de columna, se encontró '%s'", tokens[i].Value)
+	switch {
+	case p.acceptKeyword("NOT"):
+		if err := p.expectKeyword("NULL", "después de NOT"); err != nil {
+			return nil, err
 		}
+		constraint.Kind = "NOT NULL"
 
-		columnName := tokens[i].Value
-		i++
+	case p.acceptKeyword("NULL"):
+		constraint.Kind = "NULL"
 
-		// Operador =
-		if i >= len(tokens) || tokens[i].Value != "=" {
-			return nil, fmt.Errorf("se esperaba '=' después de '%s'", columnName)
+	case p.acceptKeyword("PRIMARY"):
+		if err := p.expectKeyword("KEY", "después de PRIMARY"); err != nil {
+			return nil, err
 		}
-		i++
+		constraint.Kind = "PRIMARY KEY"
 
-		// Valor
-		if i >= len(tokens) {
-			return nil, fmt.Errorf("se esperaba un valor después de '='")
-		}
+	case p.acceptKeyword("UNIQUE"):
+		constraint.Kind = "UNIQUE"
 
-		if tokens[i].Type != "CADENA" && tokens[i].Type != "NUMERO" &&
-			tokens[i].Type != "IDENTIFICADOR" {
-			return nil, fmt.Errorf("tipo de valor inválido para asignación")
+	case p.acceptKeyword("DEFAULT"):
+		constraint.Kind = "DEFAULT"
+		if p.atEnd() || p.is(",") || p.is(")") {
+			return nil, p.errorf("se esperaba valor después de DEFAULT")
 		}
-
-		assignment := &SyntaxNode{
-			Type: "ASSIGNMENT",
-			Children: []SyntaxNode{
-				{Type: "COLUMN", Value: columnName},
-				{Type: "VALUE", Value: tokens[i].Value},
-			},
+		if constraint.Default, err = p.parseDefault(); err != nil {
+			return nil, err
 		}
-		setNode.Children = append(setNode.Children, *assignment)
-		assignmentCount++
-		i++
 
-		// Verificar si hay más asignaciones
-		if i < len(tokens) && tokens[i].Value == "," {
-			i++
+	case p.acceptKeyword("REFERENCES"):
+		constraint.Kind = "REFERENCES"
+		if constraint.References, err = p.parseReference(); err != nil {
+			return nil, err
 		}
-	}
-
-	if assignmentCount == 0 {
-		return nil, fmt.Errorf("se debe especificar al menos una asignación después de SET")
-	}
-
-	root.Children = append(root.Children, *setNode)
 
-	// WHERE (opcional pero recomendado)
-	if i < len(tokens) && strings.ToUpper(tokens[i].Value) == "WHERE" {
-		whereNode, newIndex, err := analyzeWhereClause(tokens, i)
-		if err != nil {
+	case p.acceptKeyword("CHECK"):
+		constraint.Kind = "CHECK"
+		if constraint.Check, err = p.parseCheck(); err != nil {
 			return nil, err
 		}
-		root.Children = append(root.Children, *whereNode)
-		i = newIndex
-	}
 
-	// Verificar punto y coma opcional
-	if i < len(tokens) && tokens[i].Value != ";" {
-		return nil, fmt.Errorf("se esperaba ';' al final de UPDATE, se encontró: '%s'", tokens[i].Value)
+	default:
+		return nil, p.errorf("constraint no reconocido: '%s' en columna '%s'", p.current(), column.String())
 	}
 
-	return root, nil
+	constraint.Span = p.spanFrom(start)
+	return constraint, nil
 }
 
-func analyzeDelete(tokens []Token) (*SyntaxNode, error) {
-	root := &SyntaxNode{Type: "DELETE_STATEMENT"}
-	i := 0
-
-	// Verificar DELETE
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "DELETE" {
-		return nil, fmt.Errorf("se esperaba DELETE")
-	}
-	i++
-
-	// FROM es obligatorio
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "FROM" {
-		return nil, fmt.Errorf("se esperaba FROM después de DELETE")
+// parseDefault lee el valor de DEFAULT. Como en PostgreSQL no admite AND,
+// OR, NOT ni IS, para que "DEFAULT 0 NOT NULL" se lea como valor seguido de
+// constraint, y no puede referirse a otras columnas.
+func (p *parser) parseDefault() (Expr, error) {
+	value, err := p.parseBinary(precComparison)
+	if err != nil {
+		return nil, err
 	}
-	i++
-
-	// Nombre de tabla
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba nombre de tabla después de FROM")
+	var column *ColumnRef
+	Walk(value, func(node Node) bool {
+		if ref, ok := node.(*ColumnRef); ok && column == nil {
+			column = ref
+		}
+		return column == nil
+	})
+	if column != nil {
+		return nil, newAnalysisError(column.Span, "valor DEFAULT inválido: no puede referirse a la columna '%s'", column.Name.String())
 	}
+	return value, nil
+}
 
-	if tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("nombre de tabla inválido: '%s'", tokens[i].Value)
+// parseReference lee el destino de REFERENCES: tabla y columnas opcionales.
+func (p *parser) parseReference() (*Reference, error) {
+	start := p.pos
+	table, err := p.parseTableRef("después de REFERENCES")
+	if err != nil {
+		return nil, err
 	}
+	ref := &Reference{Table: table}
 
-	tableNode := &SyntaxNode{Type: "TABLE", Value: tokens[i].Value}
-	root.Children = append(root.Children, *tableNode)
-	i++
-
-	// WHERE (opcional pero muy recomendado)
-	if i < len(tokens) && strings.ToUpper(tokens[i].Value) == "WHERE" {
-		whereNode, newIndex, err := analyzeWhereClause(tokens, i)
-		if err != nil {
+	// Columnas referenciadas (opcionales pero recomendadas)
+	if p.is("(") {
+		if ref.Columns, err = p.parseIdentifierList("en REFERENCES"); err != nil {
 			return nil, err
 		}
-		root.Children = append(root.Children, *whereNode)
-		i = newIndex
 	}
 
-	// Verificar punto y coma opcional
-	if i < len(tokens) && tokens[i].Value != ";" {
-		return nil, fmt.Errorf("se esperaba ';' al final de DELETE, se encontró: '%s'", tokens[i].Value)
+	// Acciones ON DELETE / ON UPDATE, en cualquier orden
+	for p.isKeyword("ON") {
+		event := strings.ToUpper(p.peekAt(1).Value)
+		if event != "DELETE" && event != "UPDATE" {
+			break
+		}
+		p.pos += 2
+		var action string
+		for _, words := range referentialActions {
+			if p.acceptKeyword(words...) {
+				action = strings.Join(words, " ")
+				break
+			}
+		}
+		if action == "" {
+			return nil, p.errorf("se esperaba CASCADE, RESTRICT, NO ACTION, SET NULL o SET DEFAULT después de ON %s, se encontró '%s'",
+				event, p.current())
+		}
+		if event == "DELETE" {
+			ref.OnDelete = action
+		} else {
+			ref.OnUpdate = action
+		}
 	}
 
-	return root, nil
+	ref.Span = p.spanFrom(start)
+	return ref, nil
 }
 
-func analyzeCreate(tokens []Token) (*SyntaxNode, error) {
-	root := &SyntaxNode{Type: "CREATE_STATEMENT"}
-	i := 0
+// referentialActions son las acciones de ON DELETE y ON UPDATE.
+var referentialActions = [][]string{
+	{"CASCADE"}, {"RESTRICT"}, {"NO", "ACTION"}, {"SET", "NULL"}, {"SET", "DEFAULT"},
+}
 
-	// Verificar CREATE
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "CREATE" {
-		return nil, fmt.Errorf("se esperaba CREATE")
+// parseCheck lee la condición entre paréntesis de un CHECK.
+func (p *parser) parseCheck() (Expr, error) {
+	if err := p.expect("(", "después de CHECK"); err != nil {
+		return nil, err
 	}
-	i++
-
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba TABLE o DATABASE después de CREATE")
+	condition, err := p.parseCondition("CHECK")
+	if err != nil {
+		return nil, err
 	}
-
-	upperValue := strings.ToUpper(tokens[i].Value)
-
-	switch upperValue {
-	case "TABLE":
-		return analyzeCreateTable(tokens, i, root)
-	case "DATABASE":
-		return analyzeCreateDatabase(tokens, i, root)
-	case "INDEX":
-		return analyzeCreateIndex(tokens, i, root)
-	default:
-		return nil, fmt.Errorf("se esperaba TABLE, DATABASE o INDEX después de CREATE, se encontró '%s'", tokens[i].Value)
+	if err := p.expect(")", "para cerrar CHECK"); err != nil {
+		return nil, err
 	}
+	return condition, nil
 }
 
-func analyzeCreateDatabase(tokens []Token, startIndex int, root *SyntaxNode) (*SyntaxNode, error) {
-	i := startIndex + 1
+func (p *parser) analyzeTableConstraint() (*TableConstraint, error) {
+	start := p.pos
+	constraint := &TableConstraint{}
+	var err error
 
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba nombre de base de datos después de CREATE DATABASE")
+	// CONSTRAINT nombre (opcional)
+	if p.acceptKeyword("CONSTRAINT") {
+		name, err := p.parseIdentifier("nombre después de CONSTRAINT")
+		if err != nil {
+			return nil, err
+		}
+		constraint.Name = name.String()
 	}
 
-	if tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("nombre de base de datos inválido: '%s'", tokens[i].Value)
-	}
+	switch {
+	case p.acceptKeyword("PRIMARY"):
+		if err := p.expectKeyword("KEY", "después de PRIMARY"); err != nil {
+			return nil, err
+		}
+		constraint.Kind = "PRIMARY KEY"
+		if constraint.Columns, err = p.parseIdentifierList("en PRIMARY KEY"); err != nil {
+			return nil, err
+		}
+
+	case p.acceptKeyword("FOREIGN"):
+		if err := p.expectKeyword("KEY", "después de FOREIGN"); err != nil {
+			return nil, err
+		}
+		constraint.Kind = "FOREIGN KEY"
+		if constraint.Columns, err = p.parseIdentifierList("en FOREIGN KEY"); err != nil {
+			return nil, err
+		}
+		if err := p.expectKeyword("REFERENCES", "después de FOREIGN KEY"); err != nil {
+			return nil, err
+		}
+		if constraint.References, err = p.parseReference(); err != nil {
+			return nil, err
+		}
+
+	case p.acceptKeyword("UNIQUE"):
+		constraint.Kind = "UNIQUE"
+		if constraint.Columns, err = p.parseIdentifierList("en UNIQUE"); err != nil {
+			return nil, err
+		}
 
-	dbNode := &SyntaxNode{Type: "DATABASE", Value: tokens[i].Value}
-	root.Children = append(root.Children, *dbNode)
-	i++
+	case p.acceptKeyword("CHECK"):
+		constraint.Kind = "CHECK"
+		if constraint.Check, err = p.parseCheck(); err != nil {
+			return nil, err
+		}
 
-	// Verificar punto y coma opcional
-	if i < len(tokens) && tokens[i].Value != ";" {
-		return nil, fmt.Errorf("se esperaba ';' al final de CREATE DATABASE, se encontró: '%s'", tokens[i].Value)
+	default:
+		return nil, p.errorf("tipo de constraint de tabla no reconocido: '%s'", p.current())
 	}
 
-	return root, nil
+	constraint.Span = p.spanFrom(start)
+	return constraint, nil
 }
 
-func analyzeCreateIndex(tokens []Token, startIndex int, root *SyntaxNode) (*SyntaxNode, error) {
-	i := startIndex + 1
-
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba nombre de índice después de CREATE INDEX")
+func (p *parser) analyzeCreateDatabase(start int) (*CreateDatabaseStmt, error) {
+	p.next() // DATABASE
+	name, err := p.parseIdentifier("nombre de base de datos después de CREATE DATABASE")
+	if err != nil {
+		return nil, err
 	}
+	stmt := &CreateDatabaseStmt{Name: name, Span: p.spanFrom(start)}
+	return stmt, p.expectEnd("CREATE DATABASE")
+}
 
-	if tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("nombre de índice inválido: '%s'", tokens[i].Value)
-	}
+// analyzeCreateIndex analiza CREATE [UNIQUE] INDEX [IF NOT EXISTS] nombre
+// ON [ONLY] tabla [USING método] (elementos). Cada elemento es una columna o
+// una expresión, con ASC/DESC y NULLS FIRST/LAST opcionales.
+func (p *parser) analyzeCreateIndex(start int) (*CreateIndexStmt, error) {
+	stmt := &CreateIndexStmt{Unique: p.acceptKeyword("UNIQUE")}
+	p.next() // INDEX
+	stmt.IfNotExists = p.acceptKeyword("IF", "NOT", "EXISTS")
+	var err error
 
-	indexNode := &SyntaxNode{Type: "INDEX", Value: tokens[i].Value}
-	root.Children = append(root.Children, *indexNode)
-	i++
+	if stmt.Name, err = p.parseIdentifier("nombre de índice después de CREATE INDEX"); err != nil {
+		return nil, err
+	}
 
 	// ON tabla
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "ON" {
-		return nil, fmt.Errorf("se esperaba ON después del nombre del índice")
+	if err := p.expectKeyword("ON", "después del nombre del índice"); err != nil {
+		return nil, err
 	}
-	i++
-
-	if i >= len(tokens) || tokens[i].Type != "IDENTIFICADOR" {
-		return nil, fmt.Errorf("se esperaba nombre de tabla después de ON")
+	p.acceptKeyword("ONLY")
+	if stmt.Table, err = p.parseTableRef("después de ON"); err != nil {
+		return nil, err
 	}
-
-	tableNode := &SyntaxNode{Type: "TABLE", Value: tokens[i].Value}
-	indexNode.Children = append(indexNode.Children, *tableNode)
-	i++
-
-	// Columnas
-	if i >= len(tokens) || tokens[i].Value != "(" {
-		return nil, fmt.Errorf("se esperaba '(' después del nombre de tabla")
-	}
-	i++
-
-	columnsNode := &SyntaxNode{Type: "COLUMNS"}
-	for i < len(tokens) && tokens[i].Value != ")" {
-		if tokens[i].Type == "IDENTIFICADOR" {
-			columnsNode.Children = append(columnsNode.Children,
-				SyntaxNode{Type: "COLUMN", Value: tokens[i].Value})
-			i++
-			if i < len(tokens) && tokens[i].Value == "," {
-				i++
-			}
-		} else {
-			return nil, fmt.Errorf("se esperaba nombre de columna en CREATE INDEX")
+	if p.acceptKeyword("USING") {
+		method, err := p.parseIdentifier("método de índice después de USING (btree, hash, gin...)")
+		if err != nil {
+			return nil, err
 		}
+		stmt.Method = strings.ToLower(method.Name)
 	}
 
-	if i >= len(tokens) || tokens[i].Value != ")" {
-		return nil, fmt.Errorf("se esperaba ')' para cerrar las columnas del índice")
+	// Columnas y expresiones
+	if err := p.expect("(", "antes de las columnas del índice"); err != nil {
+		return nil, err
 	}
-	i++
-
-	indexNode.Children = append(indexNode.Children, *columnsNode)
-
-	// Verificar punto y coma opcional
-	if i < len(tokens) && tokens[i].Value != ";" {
-		return nil, fmt.Errorf("se esperaba ';' al final de CREATE INDEX, se encontró: '%s'", tokens[i].Value)
+	for {
+		element, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if column, ok := element.(*ColumnRef); ok && len(column.Name.Parts) == 1 && !column.IsStar() {
+			stmt.Columns = append(stmt.Columns, column.Name.Parts[0])
+		} else {
+			stmt.Expressions = append(stmt.Expressions, element)
+		}
+		if !p.acceptKeyword("ASC") {
+			p.acceptKeyword("DESC")
+		}
+		if p.acceptKeyword("NULLS") && !p.acceptKeyword("FIRST") && !p.acceptKeyword("LAST") {
+			return nil, p.errorf("se esperaba FIRST o LAST después de NULLS, se encontró '%s'", p.current())
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if err := p.expect(")", "para cerrar las columnas del índice"); err != nil {
+		return nil, err
 	}
 
-	return root, nil
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd("CREATE INDEX")
 }
 
-func analyzeDrop(tokens []Token) (*SyntaxNode, error) {
-	root := &SyntaxNode{Type: "DROP_STATEMENT"}
-	i := 0
-
-	// Verificar DROP
-	if i >= len(tokens) || strings.ToUpper(tokens[i].Value) != "DROP" {
-		return nil, fmt.Errorf("se esperaba DROP")
+// analyzeAlter analiza un ALTER TABLE [IF EXISTS] [ONLY] tabla con una
+// lista de acciones separadas por comas. Los RENAME deben ir solos.
+func (p *parser) analyzeAlter() (*AlterTableStmt, error) {
+	start := p.pos
+	p.next() // ALTER
+	if err := p.expectKeyword("TABLE", "después de ALTER"); err != nil {
+		return nil, err
 	}
-	i++
+	stmt := &AlterTableStmt{IfExists: p.acceptKeyword("IF", "EXISTS")}
+	p.acceptKeyword("ONLY")
 
-	if i >= len(tokens) {
-		return nil, fmt.Errorf("se esperaba TABLE o DATABASE después de DROP")
+	var err error
+	if stmt.Table, err = p.parseTableRef("después de ALTER TABLE"); err != nil {
+		return nil, err
+	}
+	if p.atEnd() || p.is(";") {
+		return nil, p.errorf("se esperaba una acción después de ALTER TABLE %s (ADD, DROP, ALTER, RENAME)", stmt.Table.Name.String())
 	}
 
-	upperValue := strings.ToUpper(tokens[i].Value)
-
-	switch upperValue {
-	case "TABLE":
-		i++
-		if i >= len(tokens) {
-			return nil, fmt.Errorf("se esperaba nombre de tabla después de DROP TABLE")
-		}
-		if tokens[i].Type != "IDENTIFICADOR" {
-			return nil, fmt.Errorf("nombre de tabla inválido: '%s'", tokens[i].Value)
-		}
-		tableNode := &SyntaxNode{Type: "TABLE", Value: tokens[i].Value}
-		root.Children = append(root.Children, *tableNode)
-		i++
-
-	case "DATABASE":
-		i++
-		if i >= len(tokens) {
-			return nil, fmt.Errorf("se esperaba nombre de base de datos después de DROP DATABASE")
+	for {
+		action, err := p.parseAlterAction()
+		if err != nil {
+			return nil, err
 		}
-		if tokens[i].Type != "IDENTIFICADOR" {
-			return nil, fmt.Errorf("nombre de base de datos inválido: '%s'", tokens[i].Value)
+		stmt.Actions = append(stmt.Actions, action)
+		if !p.accept(",") {
+			break
 		}
-		dbNode := &SyntaxNode{Type: "DATABASE", Value: tokens[i].Value}
-		root.Children = append(root.Children, *dbNode)
-		i++
-
-	default:
-		return nil, fmt.Errorf("se esperaba TABLE o DATABASE después de DROP, se encontró '%s'", tokens[i].Value)
 	}
-
-	// Verificar punto y coma opcional
-	if i < len(tokens) && tokens[i].Value != ";" {
-		return nil, fmt.Errorf("se esperaba ';' al final de DROP, se encontró: '%s'", tokens[i].Value)
+	for _, action := range stmt.Actions {
+		if strings.HasPrefix(action.Kind, "RENAME") && len(stmt.Actions) > 1 {
+			return nil, newAnalysisError(action.Span, "%s no puede combinarse con otras acciones en ALTER TABLE", action.Kind)
+		}
 	}
 
-	return root, nil
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd("ALTER TABLE")
 }
 
-// Funciones auxiliares
-func analyzeWhereClause(tokens []Token, startIndex int) (*SyntaxNode, int, error) {
-	whereNode := &SyntaxNode{Type: "WHERE_CLAUSE"}
-	i := startIndex + 1
+// parseAlterAction lee una acción de ALTER TABLE.
+func (p *parser) parseAlterAction() (*AlterAction, error) {
+	start := p.pos
+	action := &AlterAction{}
+	var err error
 
-	if i >= len(tokens) {
-		return nil, i, fmt.Errorf("se esperaba condición después de WHERE")
-	}
+	switch {
+	case p.acceptKeyword("ADD"):
+		if p.isKeyword("PRIMARY") || p.isKeyword("FOREIGN") || p.isKeyword("UNIQUE") ||
+			p.isKeyword("CHECK") || p.isKeyword("CONSTRAINT") {
+			action.Kind = "ADD CONSTRAINT"
+			if action.Constraint, err = p.analyzeTableConstraint(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		action.Kind = "ADD COLUMN"
+		p.acceptKeyword("COLUMN")
+		action.IfExists = p.acceptKeyword("IF", "NOT", "EXISTS")
+		if action.Definition, err = p.analyzeColumnDefinition(); err != nil {
+			return nil, err
+		}
+		action.Column = action.Definition.Name
 
-	// Análisis simplificado de condición
-	conditionCount := 0
-	parenDepth := 0
+	case p.acceptKeyword("DROP"):
+		action.Kind = "DROP COLUMN"
+		if p.acceptKeyword("CONSTRAINT") {
+			action.Kind = "DROP CONSTRAINT"
+		} else {
+			p.acceptKeyword("COLUMN")
+		}
+		action.IfExists = p.acceptKeyword("IF", "EXISTS")
+		what := "el nombre de la columna a eliminar"
+		if action.Kind == "DROP CONSTRAINT" {
+			what = "el nombre del constraint a eliminar"
+		}
+		if action.Column, err = p.parseIdentifier(what); err != nil {
+			return nil, err
+		}
+		if p.isKeyword("CASCADE") || p.isKeyword("RESTRICT") {
+			action.Behavior = strings.ToUpper(p.next().Value)
+		}
 
-	for i < len(tokens) &&
-		(parenDepth > 0 || (strings.ToUpper(tokens[i].Value) != "GROUP" &&
-			strings.ToUpper(tokens[i].Value) != "ORDER" &&
-			strings.ToUpper(tokens[i].Value) != "LIMIT" &&
-			tokens[i].Value != ";")) {
+	case p.acceptKeyword("ALTER"):
+		p.acceptKeyword("COLUMN")
+		if action.Column, err = p.parseIdentifier("el nombre de la columna a modificar"); err != nil {
+			return nil, err
+		}
+		if err := p.parseAlterColumn(action); err != nil {
+			return nil, err
+		}
 
-		if tokens[i].Value == "(" {
-			parenDepth++
-		} else if tokens[i].Value == ")" {
-			parenDepth--
-			if parenDepth < 0 {
-				return nil, i, fmt.Errorf("paréntesis ')' inesperado en WHERE")
+	case p.acceptKeyword("RENAME"):
+		switch {
+		case p.acceptKeyword("TO"):
+			action.Kind = "RENAME TO"
+		case p.acceptKeyword("CONSTRAINT"):
+			action.Kind = "RENAME CONSTRAINT"
+			if action.Column, err = p.parseIdentifier("el nombre del constraint a renombrar"); err != nil {
+				return nil, err
+			}
+		default:
+			action.Kind = "RENAME COLUMN"
+			p.acceptKeyword("COLUMN")
+			if action.Column, err = p.parseIdentifier("el nombre de la columna a renombrar"); err != nil {
+				return nil, err
+			}
+		}
+		if action.Kind != "RENAME TO" {
+			if err := p.expectKeyword("TO", "después de "+action.Column.String()); err != nil {
+				return nil, err
 			}
 		}
+		if action.NewName, err = p.parseIdentifier("el nuevo nombre después de TO"); err != nil {
+			return nil, err
+		}
 
-		whereNode.Children = append(whereNode.Children,
-			SyntaxNode{Type: "CONDITION_TOKEN", Value: tokens[i].Value})
-		conditionCount++
-		i++
+	default:
+		return nil, p.errorf("acción de ALTER TABLE no reconocida: '%s' (se esperaba ADD, DROP, ALTER o RENAME)", p.current())
 	}
 
-	if parenDepth != 0 {
-		return nil, i, fmt.Errorf("paréntesis no balanceados en condición WHERE")
-	}
+	action.Span = p.spanFrom(start)
+	return action, nil
+}
 
-	if conditionCount == 0 {
-		return nil, i, fmt.Errorf("WHERE requiere al menos una condición")
+// parseAlterColumn lee lo que sigue a ALTER COLUMN nombre: [SET DATA] TYPE
+// tipo [USING expr], SET/DROP DEFAULT o SET/DROP NOT NULL.
+func (p *parser) parseAlterColumn(action *AlterAction) error {
+	var err error
+	switch {
+	case p.acceptKeyword("TYPE"), p.acceptKeyword("SET", "DATA", "TYPE"):
+		action.Kind = "ALTER COLUMN TYPE"
+		if p.atEnd() || p.is(";") || p.is(",") {
+			return p.errorf("se esperaba el nuevo tipo de la columna '%s'", action.Column.String())
+		}
+		if action.Type, err = p.parseDataType(); err != nil {
+			return err
+		}
+		if p.acceptKeyword("USING") {
+			if action.Using, err = p.parseExpr(); err != nil {
+				return err
+			}
+		}
+	case p.acceptKeyword("SET", "DEFAULT"):
+		action.Kind = "SET DEFAULT"
+		if p.atEnd() || p.is(";") || p.is(",") {
+			return p.errorf("se esperaba un valor después de SET DEFAULT")
+		}
+		if action.Default, err = p.parseDefault(); err != nil {
+			return err
+		}
+	case p.acceptKeyword("DROP", "DEFAULT"):
+		action.Kind = "DROP DEFAULT"
+	case p.acceptKeyword("SET", "NOT", "NULL"):
+		action.Kind = "SET NOT NULL"
+	case p.acceptKeyword("DROP", "NOT", "NULL"):
+		action.Kind = "DROP NOT NULL"
+	default:
+		return p.errorf("se esperaba TYPE, SET DEFAULT, DROP DEFAULT, SET NOT NULL o DROP NOT NULL después de la columna '%s', se encontró '%s'",
+			action.Column.String(), p.current())
 	}
+	return nil
+}
 
-	return whereNode, i, nil
+// dropObjectTypes son los objetos que admite DROP, con la palabra o
+// palabras que los nombran.
+var dropObjectTypes = [][]string{
+	{"TABLE"}, {"VIEW"}, {"MATERIALIZED", "VIEW"}, {"INDEX"}, {"SEQUENCE"}, {"SCHEMA"}, {"DATABASE"},
 }
 
-func analyzeGroupByClause(tokens []Token, startIndex int) (*SyntaxNode, int, error) {
-	groupNode := &SyntaxNode{Type: "GROUP_BY_CLAUSE"}
-	i := startIndex + 2 // Saltar GROUP BY
+// analyzeDrop analiza DROP tipo [IF EXISTS] nombre [, ...] [CASCADE |
+// RESTRICT]. DROP DATABASE admite un solo nombre y no admite CASCADE.
+func (p *parser) analyzeDrop() (*DropStmt, error) {
+	start := p.pos
+	stmt := &DropStmt{}
 
-	if i >= len(tokens) {
-		return nil, i, fmt.Errorf("se esperaba columna después de GROUP BY")
+	if err := p.expectKeyword("DROP", "al inicio de la sentencia"); err != nil {
+		return nil, err
 	}
+	for _, words := range dropObjectTypes {
+		if p.acceptKeyword(words...) {
+			stmt.ObjectType = strings.Join(words, " ")
+			break
+		}
+	}
+	if stmt.ObjectType == "" {
+		return nil, p.errorf("se esperaba TABLE, VIEW, MATERIALIZED VIEW, INDEX, SEQUENCE, SCHEMA o DATABASE después de DROP, se encontró '%s'", p.current())
+	}
+	if stmt.ObjectType == "INDEX" {
+		stmt.Concurrently = p.acceptKeyword("CONCURRENTLY")
+	}
+	stmt.IfExists = p.acceptKeyword("IF", "EXISTS")
 
-	columnCount := 0
-	expectingColumn := true
-
-	for i < len(tokens) &&
-		strings.ToUpper(tokens[i].Value) != "HAVING" &&
-		strings.ToUpper(tokens[i].Value) != "ORDER" &&
-		strings.ToUpper(tokens[i].Value) != "LIMIT" &&
-		tokens[i].Value != ";" {
-
-		if tokens[i].Value == "," {
-			if !expectingColumn {
-				expectingColumn = true
-			} else {
-				return nil, i, fmt.Errorf("se esperaba columna antes de ','")
+	what := "nombre después de DROP " + stmt.ObjectType
+	for {
+		var name QualifiedName
+		if stmt.ObjectType == "SCHEMA" || stmt.ObjectType == "DATABASE" {
+			id, err := p.parseIdentifier(what)
+			if err != nil {
+				return nil, err
+			}
+			name = QualifiedName{Parts: []Identifier{id}, Span: id.Span}
+		} else {
+			var err error
+			if name, err = p.parseQualifiedName(what, false); err != nil {
+				return nil, err
 			}
-		} else if expectingColumn && tokens[i].Type == "IDENTIFICADOR" {
-			groupNode.Children = append(groupNode.Children,
-				SyntaxNode{Type: "COLUMN", Value: tokens[i].Value})
-			columnCount++
-			expectingColumn = false
-		} else if expectingColumn {
-			return nil, i, fmt.Errorf("se esperaba nombre de columna en GROUP BY")
 		}
-		i++
+		for _, other := range stmt.Names {
+			if other.String() == name.String() {
+				return nil, newAnalysisError(name.Span, "'%s' aparece más de una vez en DROP %s", name.String(), stmt.ObjectType)
+			}
+		}
+		stmt.Names = append(stmt.Names, name)
+		if !p.accept(",") {
+			break
+		}
+		if stmt.ObjectType == "DATABASE" {
+			return nil, p.errorf("DROP DATABASE elimina una sola base de datos por sentencia")
+		}
 	}
 
-	if columnCount == 0 {
-		return nil, i, fmt.Errorf("GROUP BY requiere al menos una columna")
+	if p.isKeyword("CASCADE") || p.isKeyword("RESTRICT") {
+		if stmt.ObjectType == "DATABASE" {
+			return nil, p.errorf("DROP DATABASE no admite %s", strings.ToUpper(p.current()))
+		}
+		stmt.Behavior = strings.ToUpper(p.next().Value)
 	}
 
-	if expectingColumn {
-		return nil, i, fmt.Errorf("se esperaba columna después de ','")
-	}
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd("DROP")
+}
 
-	// HAVING (opcional)
-	if i < len(tokens) && strings.ToUpper(tokens[i].Value) == "HAVING" {
-		i++
-		havingNode := &SyntaxNode{Type: "HAVING_CLAUSE"}
+// isolationLevels son los niveles de aislamiento de BEGIN ISOLATION LEVEL.
+var isolationLevels = [][]string{
+	{"SERIALIZABLE"},
+	{"REPEATABLE", "READ"},
+	{"READ", "COMMITTED"},
+	{"READ", "UNCOMMITTED"},
+}
 
-		if i >= len(tokens) {
-			return nil, i, fmt.Errorf("se esperaba condición después de HAVING")
+// analyzeTransaction analiza BEGIN / START TRANSACTION, COMMIT / END,
+// ROLLBACK [TO SAVEPOINT], SAVEPOINT y RELEASE SAVEPOINT.
+func (p *parser) analyzeTransaction() (*TransactionStmt, error) {
+	start := p.pos
+	stmt := &TransactionStmt{}
+	word := strings.ToUpper(p.next().Value)
+
+	switch word {
+	case "BEGIN", "START":
+		if word == "START" {
+			if err := p.expectKeyword("TRANSACTION", "después de START"); err != nil {
+				return nil, err
+			}
+		} else if !p.acceptKeyword("WORK") {
+			p.acceptKeyword("TRANSACTION")
 		}
-
-		// Condición de HAVING
-		conditionCount := 0
-		for i < len(tokens) &&
-			strings.ToUpper(tokens[i].Value) != "ORDER" &&
-			strings.ToUpper(tokens[i].Value) != "LIMIT" &&
-			tokens[i].Value != ";" {
-
-			havingNode.Children = append(havingNode.Children,
-				SyntaxNode{Type: "CONDITION_TOKEN", Value: tokens[i].Value})
-			conditionCount++
-			i++
+		stmt.Kind = "BEGIN"
+		modes, err := p.parseTransactionModes()
+		if err != nil {
+			return nil, err
 		}
+		stmt.Modes = modes
 
-		if conditionCount == 0 {
-			return nil, i, fmt.Errorf("HAVING requiere al menos una condición")
+	case "COMMIT", "END":
+		if !p.acceptKeyword("WORK") {
+			p.acceptKeyword("TRANSACTION")
 		}
+		stmt.Kind = "COMMIT"
 
-		groupNode.Children = append(groupNode.Children, *havingNode)
-	}
-
-	return groupNode, i, nil
-}
+	case "ROLLBACK":
+		if !p.acceptKeyword("WORK") {
+			p.acceptKeyword("TRANSACTION")
+		}
+		stmt.Kind = "ROLLBACK"
+		if p.acceptKeyword("TO") {
+			p.acceptKeyword("SAVEPOINT")
+			stmt.Kind = "ROLLBACK TO"
+		}
 
-func analyzeOrderByClause(tokens []Token, startIndex int) (*SyntaxNode, int, error) {
-	orderNode := &SyntaxNode{Type: "ORDER_BY_CLAUSE"}
-	i := startIndex + 2 // Saltar ORDER BY
+	case "SAVEPOINT":
+		stmt.Kind = "SAVEPOINT"
 
-	if i >= len(tokens) {
-		return nil, i, fmt.Errorf("se esperaba columna después de ORDER BY")
+	case "RELEASE":
+		p.acceptKeyword("SAVEPOINT")
+		stmt.Kind = "RELEASE"
 	}
 
-	columnCount := 0
-	expectingColumn := true
+	if stmt.Kind == "SAVEPOINT" || stmt.Kind == "RELEASE" || stmt.Kind == "ROLLBACK TO" {
+		name, err := p.parseIdentifier("nombre de savepoint después de " + stmt.Kind)
+		if err != nil {
+			return nil, err
+		}
+		stmt.Savepoint = name
+	}
 
-	for i < len(tokens) &&
-		strings.ToUpper(tokens[i].Value) != "LIMIT" &&
-		tokens[i].Value != ";" {
+	stmt.Span = p.spanFrom(start)
+	return stmt, p.expectEnd(stmt.Kind)
+}
 
-		if tokens[i].Value == "," {
-			if !expectingColumn {
-				expectingColumn = true
-			} else {
-				return nil, i, fmt.Errorf("se esperaba columna antes de ','")
+// parseTransactionModes lee los modos de BEGIN, separados opcionalmente por
+// comas. Un modo no puede repetirse.
+func (p *parser) parseTransactionModes() ([]string, error) {
+	var modes []string
+	seen := map[string]bool{}
+	for {
+		var mode, group string
+		switch {
+		case p.acceptKeyword("ISOLATION", "LEVEL"):
+			for _, level := range isolationLevels {
+				if p.acceptKeyword(level...) {
+					mode = "ISOLATION LEVEL " + strings.Join(level, " ")
+					break
+				}
 			}
-		} else if expectingColumn && tokens[i].Type == "IDENTIFICADOR" {
-			orderItem := &SyntaxNode{Type: "ORDER_ITEM", Value: tokens[i].Value}
-			i++
-
-			// Dirección (opcional)
-			if i < len(tokens) &&
-				(strings.ToUpper(tokens[i].Value) == "ASC" ||
-					strings.ToUpper(tokens[i].Value) == "DESC") {
-				orderItem.Children = append(orderItem.Children,
-					SyntaxNode{Type: "DIRECTION", Value: tokens[i].Value})
-				i++
+			if mode == "" {
+				return nil, p.errorf("se esperaba SERIALIZABLE, REPEATABLE READ, READ COMMITTED o READ UNCOMMITTED después de ISOLATION LEVEL, se encontró '%s'", p.current())
 			}
-
-			orderNode.Children = append(orderNode.Children, *orderItem)
-			columnCount++
-			expectingColumn = false
-		} else if expectingColumn {
-			return nil, i, fmt.Errorf("se esperaba nombre de columna en ORDER BY")
-		} else {
-			i++
+			group = "ISOLATION LEVEL"
+		case p.acceptKeyword("READ", "ONLY"):
+			mode, group = "READ ONLY", "READ ONLY / READ WRITE"
+		case p.acceptKeyword("READ", "WRITE"):
+			mode, group = "READ WRITE", "READ ONLY / READ WRITE"
+		case p.acceptKeyword("NOT", "DEFERRABLE"):
+			mode, group = "NOT DEFERRABLE", "DEFERRABLE / NOT DEFERRABLE"
+		case p.acceptKeyword("DEFERRABLE"):
+			mode, group = "DEFERRABLE", "DEFERRABLE / NOT DEFERRABLE"
+		default:
+			if len(modes) > 0 {
+				return nil, p.errorf("se esperaba un modo de transacción después de ',', se encontró '%s'", p.current())
+			}
+			return nil, nil
+		}
+		if seen[group] {
+			return nil, p.errorf("el modo %s se indicó más de una vez", group)
+		}
+		seen[group] = true
+		modes = append(modes, mode)
+		if !p.accept(",") && !p.isKeyword("ISOLATION") && !p.isKeyword("READ") &&
+			!p.isKeyword("DEFERRABLE") && !p.isKeyword("NOT") {
+			return modes, nil
 		}
 	}
-
-	if columnCount == 0 {
-		return nil, i, fmt.Errorf("ORDER BY requiere al menos una columna")
-	}
-
-	if expectingColumn {
-		return nil, i, fmt.Errorf("se esperaba columna después de ','")
-	}
-
-	return orderNode, i, nil
 }
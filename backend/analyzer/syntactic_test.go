@@ -0,0 +1,251 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStatementTypes(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1", "*analyzer.SelectStmt"},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "*analyzer.SelectStmt"},
+		{"SELECT 1 UNION SELECT 2", "*analyzer.SetOperation"},
+		{"INSERT INTO t (a) VALUES (1)", "*analyzer.InsertStmt"},
+		{"UPDATE t SET a = 1", "*analyzer.UpdateStmt"},
+		{"DELETE FROM t", "*analyzer.DeleteStmt"},
+		{"CREATE TABLE t (id INTEGER)", "*analyzer.CreateTableStmt"},
+		{"CREATE UNIQUE INDEX i ON t (a)", "*analyzer.CreateIndexStmt"},
+		{"ALTER TABLE t ADD COLUMN b TEXT", "*analyzer.AlterTableStmt"},
+		{"DROP TABLE IF EXISTS t CASCADE", "*analyzer.DropStmt"},
+		{"BEGIN", "*analyzer.TransactionStmt"},
+		{"ROLLBACK TO SAVEPOINT s", "*analyzer.TransactionStmt"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			stmt, err := SyntacticAnalysis(test.query)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := fmt.Sprintf("%T", stmt); got != test.want {
+				t.Errorf("se obtuvo %s, se esperaba %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSelectAST(t *testing.T) {
+	stmt, err := SyntacticAnalysis(`SELECT DISTINCT u.nombre AS n, count(*) total
+		FROM usuarios u LEFT JOIN pedidos p ON p.usuario_id = u.id
+		WHERE u.activo GROUP BY u.nombre HAVING count(*) > 1
+		ORDER BY total DESC LIMIT 10 OFFSET 5`)
+	if err != nil {
+		t.Fatalf("error inesperado: %v", err)
+	}
+	s := stmt.(*SelectStmt)
+
+	if !s.Distinct || len(s.Columns) != 2 {
+		t.Fatalf("Distinct = %v, %d columnas", s.Distinct, len(s.Columns))
+	}
+	if s.Columns[0].Alias.Name != "n" || s.Columns[1].Alias.Name != "total" {
+		t.Errorf("alias %q y %q, se esperaba n y total", s.Columns[0].Alias.Name, s.Columns[1].Alias.Name)
+	}
+	if call, ok := s.Columns[1].Expr.(*FuncCall); !ok || !call.Star {
+		t.Errorf("la segunda columna es %T, se esperaba count(*)", s.Columns[1].Expr)
+	}
+
+	if len(s.From) != 1 {
+		t.Fatalf("%d elementos en FROM, se esperaba 1", len(s.From))
+	}
+	join, ok := s.From[0].(*JoinExpr)
+	if !ok {
+		t.Fatalf("FROM es %T, se esperaba un JOIN", s.From[0])
+	}
+	if join.Kind != "LEFT" || join.On == nil {
+		t.Errorf("JOIN %s con ON %v", join.Kind, join.On)
+	}
+	if left := join.Left.(*TableRef); left.Name.String() != "usuarios" || left.VisibleName() != "u" {
+		t.Errorf("tabla izquierda %s (%s)", left.Name, left.VisibleName())
+	}
+
+	if render(s.Where) != "u.activo" || render(s.Having) != "(count() > 1)" {
+		t.Errorf("WHERE %s, HAVING %s", render(s.Where), render(s.Having))
+	}
+	if len(s.GroupBy) != 1 || len(s.OrderBy) != 1 || s.OrderBy[0].Direction != "DESC" {
+		t.Errorf("GROUP BY %d, ORDER BY %v", len(s.GroupBy), s.OrderBy)
+	}
+	if render(s.Limit) != "10" || render(s.Offset) != "5" {
+		t.Errorf("LIMIT %s OFFSET %s", render(s.Limit), render(s.Offset))
+	}
+}
+
+func TestJoinAST(t *testing.T) {
+	tests := []struct {
+		from    string
+		kind    string
+		natural bool
+		using   string
+	}{
+		{"a JOIN b ON a.id = b.id", "INNER", false, ""},
+		{"a INNER JOIN b ON true", "INNER", false, ""},
+		{"a LEFT OUTER JOIN b USING (id)", "LEFT", false, "id"},
+		{"a RIGHT JOIN b USING (id, nombre)", "RIGHT", false, "id,nombre"},
+		{"a FULL JOIN b ON a.x = b.x", "FULL", false, ""},
+		{"a CROSS JOIN b", "CROSS", false, ""},
+		{"a NATURAL JOIN b", "INNER", true, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.from, func(t *testing.T) {
+			stmt, err := SyntacticAnalysis("SELECT * FROM " + test.from)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			join := stmt.(*SelectStmt).From[0].(*JoinExpr)
+			var using []string
+			for _, column := range join.Using {
+				using = append(using, column.Name)
+			}
+			if join.Kind != test.kind || join.Natural != test.natural || strings.Join(using, ",") != test.using {
+				t.Errorf("JOIN %s natural=%v USING (%s)", join.Kind, join.Natural, strings.Join(using, ","))
+			}
+		})
+	}
+}
+
+func TestSetOperationAST(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT 1 UNION SELECT 2 UNION ALL SELECT 3", "((S UNION S) UNION ALL S)"},
+		{"SELECT 1 UNION SELECT 2 INTERSECT SELECT 3", "(S UNION (S INTERSECT S))"},
+		{"SELECT 1 EXCEPT SELECT 2 EXCEPT SELECT 3", "((S EXCEPT S) EXCEPT S)"},
+		{"(SELECT 1 UNION SELECT 2) INTERSECT SELECT 3", "((S UNION S) INTERSECT S)"},
+	}
+	var shape func(q Query) string
+	shape = func(q Query) string {
+		set, ok := q.(*SetOperation)
+		if !ok {
+			return "S"
+		}
+		op := set.Op
+		if set.All {
+			op += " ALL"
+		}
+		return fmt.Sprintf("(%s %s %s)", shape(set.Left), op, shape(set.Right))
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			stmt, err := SyntacticAnalysis(test.query)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if got := shape(stmt.(Query)); got != test.want {
+				t.Errorf("se agrupó como %s, se esperaba %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSetOperationResultClauses(t *testing.T) {
+	tests := []struct {
+		query  string
+		limit  string
+		offset string
+	}{
+		{"SELECT a FROM t UNION SELECT a FROM u ORDER BY 1 LIMIT 5", "5", ""},
+		{"SELECT a FROM t UNION SELECT a FROM u LIMIT ALL OFFSET 2", "ALL", "2"},
+		{"SELECT a FROM t UNION SELECT a FROM u LIMIT 10 + 5", "(10 + 5)", ""},
+		{"SELECT a FROM t UNION SELECT a FROM u LIMIT (SELECT 3)", "<*analyzer.SubqueryExpr>", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			stmt, err := SyntacticAnalysis(test.query)
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			set, ok := stmt.(*SetOperation)
+			if !ok {
+				t.Fatalf("se obtuvo %T, se esperaba una operación de conjuntos", stmt)
+			}
+			var offset string
+			if set.Offset != nil {
+				offset = render(set.Offset)
+			}
+			if render(set.Limit) != test.limit || offset != test.offset {
+				t.Errorf("LIMIT %s OFFSET %s", render(set.Limit), offset)
+			}
+		})
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		error string
+	}{
+		{"SELECT", "al menos una columna"},
+		{"SELECT * FROM", "se esperaba"},
+		{"SELECT * FROM t WHERE", "WHERE requiere al menos una condición"},
+		{"SELECT a FROM t ORDER BY a ORDER BY b", "cláusula no reconocida: 'ORDER'"},
+		{"SELECT a FROM t LIMIT", "se esperaba una cantidad después de LIMIT"},
+		{"SELECT a FROM t OFFSET ALL", "ALL"},
+		{"SELECT 1 UNION", "se esperaba"},
+		{"SELECT * FROM a JOIN b", "ON"},
+		{"INSERT INTO t VALUES", "se esperaba"},
+		{"CREATE TABLE t (id FOO)", "tipo de dato inválido: 'FOO'"},
+		{"UPDATE t SET", "se esperaba"},
+		{"SELECT (1", ")"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			_, err := SyntacticAnalysis(test.query)
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if !strings.Contains(err.Error(), test.error) {
+				t.Errorf("error %q, se esperaba que contenga %q", err, test.error)
+			}
+			if _, ok := ErrorSpan(err); !ok {
+				t.Errorf("el error %q no tiene ubicación", err)
+			}
+		})
+	}
+}
+
+func TestColumnDataTypes(t *testing.T) {
+	tests := []struct {
+		column string
+		want   string
+		length int
+	}{
+		{"a VARCHAR(20)", "VARCHAR", 20},
+		{"a varchar", "VARCHAR", 0},
+		{"a character varying", "VARCHAR", 0},
+		{"a CHARACTER VARYING(8)", "VARCHAR", 8},
+		{"a char", "CHAR", 1},
+		{"a character(4)", "CHAR", 4},
+		{"a int4", "INTEGER", 0},
+		{"a double precision", "DOUBLE PRECISION", 0},
+		{"a timestamp with time zone", "TIMESTAMPTZ", 0},
+		{"a timestamp(3) without time zone", "TIMESTAMP", 0},
+		{"a timestamptz", "TIMESTAMPTZ", 0},
+		{"a integer[]", "INTEGER", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.column, func(t *testing.T) {
+			stmt, err := SyntacticAnalysis("CREATE TABLE t (" + test.column + ")")
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			dataType := stmt.(*CreateTableStmt).Columns[0].Type
+			if dataType.Name != test.want || typeLength(dataType) != test.length {
+				t.Errorf("tipo %s de largo %d, se esperaba %s de largo %d",
+					dataType.Name, typeLength(dataType), test.want, test.length)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package analyzer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// checkTypes verifica los tipos de las expresiones de la sentencia con los
+// de las columnas del catálogo: que los valores comparados sean de tipos
+// compatibles, que la aritmética se haga con números (o fechas e
+// intervalos), que las cadenas literales tengan el formato del tipo al que
+// se convierten, que las condiciones (WHERE, HAVING, ON y los operandos de
+// AND, OR y NOT) sean booleanas y que los valores de INSERT y SET puedan
+// guardarse en sus columnas.
+func (info *SemanticInfo) checkTypes(stmt Statement) {
+	Walk(stmt, func(node Node) bool {
+		switch e := node.(type) {
+		case *SelectStmt:
+			info.checkCondition(e.Where, "WHERE")
+			info.checkCondition(e.Having, "HAVING")
+			info.checkCount(e.Limit, "LIMIT")
+			info.checkCount(e.Offset, "OFFSET")
+		case *SetOperation:
+			info.checkCount(e.Limit, "LIMIT")
+			info.checkCount(e.Offset, "OFFSET")
+		case *UpdateStmt:
+			info.checkCondition(e.Where, "WHERE")
+		case *DeleteStmt:
+			info.checkCondition(e.Where, "WHERE")
+		case *OnConflict:
+			info.checkCondition(e.TargetWhere, "WHERE")
+			info.checkCondition(e.Where, "WHERE")
+		case *JoinExpr:
+			info.checkCondition(e.On, "JOIN/ON")
+		case *FuncCall:
+			info.checkCondition(e.Filter, "FILTER")
+		case *BinaryExpr:
+			switch e.Op {
+			case "=", "<>", "!=", "<", ">", "<=", ">=":
+				info.checkComparison(e.Left, e.Right, e.Span)
+			case "+", "-", "*", "/", "%", "^":
+				info.checkArithmetic(e)
+			case "AND", "OR":
+				info.checkCondition(e.Left, e.Op)
+				info.checkCondition(e.Right, e.Op)
+			}
+		case *UnaryExpr:
+			if e.Op == "NOT" {
+				info.checkCondition(e.Operand, "NOT")
+				break
+			}
+			operand := info.typeOf(e.Operand)
+			if e.Op == "-" && operand.literal == nil && !compatibleCategories(operand.category, typeNumeric) && operand.category != typeInterval {
+				info.addError(e.Span, "el operador '-' requiere un valor numérico, no uno de tipo %s", operand.describe())
+			}
+		case *BetweenExpr:
+			info.checkComparison(e.Expr, e.Low, e.Span)
+			info.checkComparison(e.Expr, e.High, e.Span)
+		case *InExpr:
+			for _, item := range e.List {
+				info.checkComparison(e.Expr, item, item.Location())
+			}
+		case *LikeExpr:
+			if t := info.typeOf(e.Expr); !compatibleCategories(t.category, typeText) {
+				info.addError(e.Span, "%s compara texto, no un valor de tipo %s", e.Op, t.describe())
+			}
+		case *CaseExpr:
+			if e.Operand != nil {
+				for _, when := range e.Whens {
+					info.checkComparison(e.Operand, when.Cond, when.Cond.Location())
+				}
+			}
+		case *CastExpr:
+			if t := info.typeOf(e.Expr); t.literal != nil {
+				info.checkLiteral(t.literal, dataType(e.Type))
+			}
+		}
+		return true
+	})
+
+	switch s := stmt.(type) {
+	case *InsertStmt:
+		info.checkInsertTypes(s)
+	case *UpdateStmt:
+		info.checkAssignmentTypes(s.Set, s.Table.Name)
+	}
+}
+
+// checkComparison verifica que dos valores comparados sean de tipos
+// compatibles. Una cadena literal se convierte al tipo del otro valor.
+func (info *SemanticInfo) checkComparison(left, right Expr, span Span) {
+	l, r := info.typeOf(left), info.typeOf(right)
+	if info.coerceLiteral(l, r) || compatibleCategories(l.category, r.category) {
+		return
+	}
+	info.addError(span, "no se puede comparar un valor de tipo %s con uno de tipo %s", l.describe(), r.describe())
+}
+
+// checkCondition verifica que una condición sea booleana, como exige
+// PostgreSQL: "argument of WHERE must be type boolean". Se aceptan NULL, una
+// cadena que pueda convertirse a boolean ('t', 'yes') y los valores cuyo
+// tipo no se conoce.
+func (info *SemanticInfo) checkCondition(cond Expr, clause string) {
+	if cond == nil {
+		return
+	}
+	t := info.typeOf(cond)
+	if info.coerceLiteral(t, booleanType) || compatibleCategories(t.category, typeBoolean) {
+		return
+	}
+	info.addError(cond.Location(), "el argumento de %s debe ser de tipo boolean, no de tipo %s", clause, t.describe())
+}
+
+// checkCount verifica la cantidad de LIMIT u OFFSET: PostgreSQL la
+// convierte a bigint y no admite que dependa de las columnas de la
+// consulta.
+func (info *SemanticInfo) checkCount(count Expr, clause string) {
+	if count == nil {
+		return
+	}
+	Walk(count, func(node Node) bool {
+		switch n := node.(type) {
+		case Query:
+			return false
+		case *ColumnRef:
+			info.addError(n.Span, "el argumento de %s no puede contener columnas como '%s'", clause, n.Name.String())
+		}
+		return true
+	})
+	t := info.typeOf(count)
+	if t.literal != nil {
+		info.checkLiteral(t.literal, valueType{category: typeNumeric, name: "bigint"})
+		return
+	}
+	if !compatibleCategories(t.category, typeNumeric) {
+		info.addError(count.Location(), "el argumento de %s debe ser de tipo bigint, no de tipo %s", clause, t.describe())
+	}
+}
+
+// checkArithmetic verifica que una operación aritmética se aplique a tipos
+// que la admiten.
+func (info *SemanticInfo) checkArithmetic(expr *BinaryExpr) {
+	left, right := info.typeOf(expr.Left), info.typeOf(expr.Right)
+	if left.literal != nil && right.literal == nil {
+		info.checkLiteral(left.literal, literalOperand(right))
+	}
+	if right.literal != nil && left.literal == nil {
+		info.checkLiteral(right.literal, literalOperand(left))
+	}
+	if _, ok := arithmeticType(expr.Op, left, right); !ok {
+		info.addError(expr.Span, "el operador '%s' no se puede aplicar a valores de tipo %s y %s",
+			expr.Op, left.describe(), right.describe())
+	}
+}
+
+// coerceLiteral verifica, si uno de los dos valores es una cadena literal y
+// el otro no, que la cadena pueda convertirse al tipo del otro. Devuelve
+// true si alguno es una cadena literal: entonces los tipos no se comparan.
+func (info *SemanticInfo) coerceLiteral(a, b valueType) bool {
+	switch {
+	case a.literal != nil && b.literal == nil:
+		info.checkLiteral(a.literal, b)
+	case b.literal != nil && a.literal == nil:
+		info.checkLiteral(b.literal, a)
+	}
+	return a.literal != nil || b.literal != nil
+}
+
+// checkInsertTypes verifica los valores de un INSERT contra los tipos de
+// las columnas de destino: las de la lista o, sin lista, las de la tabla
+// en orden.
+func (info *SemanticInfo) checkInsertTypes(stmt *InsertStmt) {
+	cached, known := info.tableOf(stmt.Table.Name)
+	if !known {
+		return
+	}
+	var targets []ColumnSchema
+	if len(stmt.Columns) == 0 {
+		targets = cached.schema.Columns
+	}
+	for _, column := range stmt.Columns {
+		schema, ok := cached.columns[column.Name]
+		if !ok {
+			// Ya informada; los valores no se pueden emparejar
+			return
+		}
+		targets = append(targets, schema)
+	}
+
+	check := func(values []Expr) {
+		for i, value := range values {
+			if i < len(targets) {
+				info.checkAssignedValue(value, targets[i])
+			}
+		}
+	}
+	for _, row := range stmt.Values {
+		check(row)
+	}
+	if stmt.Query != nil {
+		if items, ok := outputItems(stmt.Query); ok {
+			values := make([]Expr, len(items))
+			for i, item := range items {
+				values[i] = item.Expr
+			}
+			check(values)
+		}
+	}
+	if stmt.OnConflict != nil {
+		info.checkAssignmentTypes(stmt.OnConflict.Set, stmt.Table.Name)
+	}
+}
+
+// checkAssignmentTypes verifica los valores de un SET contra los tipos de
+// las columnas que asigna.
+func (info *SemanticInfo) checkAssignmentTypes(set []*Assignment, table QualifiedName) {
+	columns, known := info.columnsOf(table)
+	if !known {
+		return
+	}
+	for _, assignment := range set {
+		values := assignment.Values
+		if len(assignment.Columns) == 0 {
+			values = []Expr{assignment.Value}
+		}
+		for i, column := range assignment.Targets() {
+			schema, ok := columns[column.Name]
+			if ok && i < len(values) {
+				info.checkAssignedValue(values[i], schema)
+			}
+		}
+	}
+}
+
+// checkAssignedValue verifica que un valor pueda guardarse en una columna.
+// PostgreSQL convierte al asignar cualquier valor a texto y los de una
+// categoría entre sí, pero no, por ejemplo, un texto a un número. Una
+// cadena literal debe tener el formato del tipo de la columna y no superar
+// el largo de un VARCHAR(n).
+func (info *SemanticInfo) checkAssignedValue(value Expr, column ColumnSchema) {
+	if literal, ok := value.(*Literal); ok && (literal.Kind == "NULL" || literal.Kind == "DEFAULT") {
+		return
+	}
+	target, t := info.columnType(column), info.typeOf(value)
+	if t.literal != nil {
+		if info.checkLiteral(t.literal, target) && target.length > 0 {
+			text := t.literal.Text
+			if target.name == "character" {
+				// CHAR(n) descarta los espacios finales que sobran
+				text = strings.TrimRight(text, " ")
+			}
+			if length := utf8.RuneCountInString(text); length > target.length {
+				info.addError(t.literal.Span, "el valor tiene %d caracteres y la columna '%s' es de tipo %s(%d)",
+					length, column.Name, target.name, target.length)
+			}
+		}
+		return
+	}
+	if target.category == typeText || compatibleCategories(target.category, t.category) {
+		return
+	}
+	info.addError(value.Location(), "la columna '%s' es de tipo %s pero el valor es de tipo %s",
+		column.Name, target.describe(), t.describe())
+}
+
+// Formatos de las cadenas que se convierten a números, fechas y uuid.
+var (
+	integerFormat   = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	numericFormat   = regexp.MustCompile(`(?i)^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)(e[+-]?[0-9]+)?$|^(nan|[+-]?infinity)$`)
+	uuidFormat      = regexp.MustCompile(`^\{?[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}\}?$`)
+	isoDateTime     = regexp.MustCompile(`^([0-9]{4})-([0-9]{1,2})-([0-9]{1,2})([ T]([0-9]{1,2}):([0-9]{2})(:([0-9]{2})(\.[0-9]+)?)?)?\s*(Z|[+-][0-9]{1,2}(:?[0-9]{2})?|[A-Za-z][A-Za-z0-9/_+-]*)?$`)
+	numericDateTime = regexp.MustCompile(`^[0-9]{1,2}[/.-][0-9]{1,2}[/.-][0-9]{2,4}(\s+[0-9]{1,2}:[0-9]{2}(:[0-9]{2}(\.[0-9]+)?)?)?$|^[0-9]{8}$`)
+	isoDatePrefix   = regexp.MustCompile(`^[0-9]{4}-[0-9]`)
+	namedDateTime   = regexp.MustCompile(`^[A-Za-z0-9_/ ,.:+-]*[0-9][A-Za-z0-9_/ ,.:+-]*$`)
+	dateTimeWord    = regexp.MustCompile(`[A-Za-z][A-Za-z_]*(/[A-Za-z_]+)*`)
+)
+
+// dateTimeWords son las palabras que PostgreSQL acepta dentro de una fecha
+// u hora: meses, días de la semana, AM/PM, BC/AD, la 'T' de ISO 8601 y las
+// abreviaturas de zonas horarias más usadas.
+var dateTimeWords = map[string]bool{
+	"january": true, "february": true, "march": true, "april": true, "may": true, "june": true,
+	"july": true, "august": true, "september": true, "october": true, "november": true, "december": true,
+	"jan": true, "feb": true, "mar": true, "apr": true, "jun": true, "jul": true, "aug": true,
+	"sep": true, "sept": true, "oct": true, "nov": true, "dec": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+	"mon": true, "tue": true, "tues": true, "wed": true, "thu": true, "thur": true, "thurs": true,
+	"fri": true, "sat": true, "sun": true,
+	"am": true, "pm": true, "bc": true, "ad": true, "t": true, "allballs": true,
+	"z": true, "zulu": true, "utc": true, "ut": true, "gmt": true,
+	"est": true, "edt": true, "cst": true, "cdt": true, "mst": true, "mdt": true, "pst": true, "pdt": true,
+	"akst": true, "akdt": true, "hst": true, "ast": true, "adt": true, "nst": true, "ndt": true,
+	"art": true, "brt": true, "clt": true, "clst": true, "uyt": true, "pyt": true, "bot": true, "pet": true, "cot": true, "vet": true,
+	"wet": true, "west": true, "bst": true, "cet": true, "cest": true, "met": true, "mest": true, "eet": true, "eest": true, "msk": true,
+	"ist": true, "pkt": true, "ict": true, "wib": true, "sgt": true, "hkt": true, "jst": true, "kst": true,
+	"awst": true, "acst": true, "acdt": true, "aest": true, "aedt": true, "nzst": true, "nzdt": true,
+}
+
+// timeZoneAreas son las regiones con que empiezan los nombres de zona
+// horaria de la base IANA, como America/Argentina/Buenos_Aires.
+var timeZoneAreas = map[string]bool{
+	"africa": true, "america": true, "antarctica": true, "arctic": true, "asia": true,
+	"atlantic": true, "australia": true, "europe": true, "indian": true, "pacific": true, "etc": true,
+}
+
+// knownDateTimeWord indica si una palabra de una fecha es una de
+// dateTimeWords o un nombre de zona horaria IANA.
+func knownDateTimeWord(word string) bool {
+	word = strings.ToLower(word)
+	if area, _, found := strings.Cut(word, "/"); found {
+		return timeZoneAreas[area]
+	}
+	return dateTimeWords[word]
+}
+
+// booleanValues son las cadenas que PostgreSQL acepta como booleanos.
+var booleanValues = map[string]bool{
+	"t": true, "true": true, "y": true, "yes": true, "on": true, "1": true,
+	"f": true, "false": true, "n": true, "no": true, "off": true, "0": true,
+}
+
+// specialDateTimes son las cadenas especiales que aceptan date y timestamp.
+var specialDateTimes = map[string]bool{
+	"today": true, "tomorrow": true, "yesterday": true, "now": true,
+	"epoch": true, "infinity": true, "-infinity": true,
+}
+
+// formatHints indican el formato esperado por tipo.
+var formatHints = map[string]string{
+	"date":                        "use el formato AAAA-MM-DD",
+	"timestamp without time zone": "use el formato AAAA-MM-DD HH:MM:SS",
+	"timestamp with time zone":    "use el formato AAAA-MM-DD HH:MM:SS",
+	"uuid":                        "use el formato xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx",
+	"boolean":                     "use true o false",
+}
+
+// checkLiteral verifica que una cadena literal pueda convertirse al tipo
+// indicado, como lo haría PostgreSQL al ejecutar la sentencia, y devuelve
+// si puede.
+func (info *SemanticInfo) checkLiteral(literal *Literal, target valueType) bool {
+	if validLiteral(strings.TrimSpace(literal.Text), target) {
+		return true
+	}
+	if hint := formatHints[target.name]; hint != "" {
+		info.addError(literal.Span, "%s no es un valor válido de tipo %s: %s", literal.Value, target.name, hint)
+	} else {
+		info.addError(literal.Span, "%s no es un valor válido de tipo %s", literal.Value, target.describe())
+	}
+	return false
+}
+
+// validLiteral indica si el texto de una cadena es un valor del tipo. Los
+// tipos cuyo formato no se verifica aceptan cualquier texto.
+func validLiteral(text string, target valueType) bool {
+	switch {
+	case target.category == typeNumeric:
+		switch target.name {
+		case "integer", "bigint", "smallint":
+			return integerFormat.MatchString(text)
+		case "money":
+			return true
+		}
+		return numericFormat.MatchString(text)
+	case target.category == typeBoolean:
+		return booleanValues[strings.ToLower(text)]
+	case target.category == typeUUID:
+		return uuidFormat.MatchString(text)
+	case target.name == "date" || strings.HasPrefix(target.name, "timestamp"):
+		return validDateTime(text)
+	}
+	return true
+}
+
+// validDateTime indica si el texto es una fecha, con hora opcional, que
+// PostgreSQL acepta: en formato ISO con valores posibles, en formato
+// numérico (01/02/2024), con palabras conocidas (nombres de meses y días,
+// AM/PM, zonas horarias) o una cadena especial como 'today'.
+func validDateTime(text string) bool {
+	if specialDateTimes[strings.ToLower(text)] {
+		return true
+	}
+	if parts := isoDateTime.FindStringSubmatch(text); parts != nil {
+		year, _ := strconv.Atoi(parts[1])
+		month, _ := strconv.Atoi(parts[2])
+		day, _ := strconv.Atoi(parts[3])
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if date.Month() != time.Month(month) || date.Day() != day {
+			return false
+		}
+		hour, _ := strconv.Atoi(parts[5])
+		minute, _ := strconv.Atoi(parts[6])
+		second, _ := strconv.Atoi(parts[8])
+		for _, word := range dateTimeWord.FindAllString(parts[10], -1) {
+			if !knownDateTimeWord(word) {
+				return false
+			}
+		}
+		return hour <= 24 && minute < 60 && second <= 60
+	}
+	if isoDatePrefix.MatchString(text) {
+		// Empieza como una fecha ISO pero no lo es
+		return false
+	}
+	if numericDateTime.MatchString(text) {
+		return true
+	}
+	// Con nombres, como 'January 8, 1999' o '1999-Jan-08 10:00 PM': todas
+	// las palabras deben ser conocidas
+	words := dateTimeWord.FindAllString(text, -1)
+	if len(words) == 0 || !namedDateTime.MatchString(text) {
+		return false
+	}
+	for _, word := range words {
+		if !knownDateTimeWord(word) {
+			return false
+		}
+	}
+	return true
+}
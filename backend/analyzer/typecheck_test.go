@@ -0,0 +1,126 @@
+package analyzer
+
+import "testing"
+
+func TestComparisonTypes(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT * FROM usuarios WHERE edad > 18", valid: true},
+		{query: "SELECT * FROM usuarios WHERE edad = '18'", valid: true},
+		{query: "SELECT * FROM pedidos WHERE total BETWEEN 1 AND 2.5", valid: true},
+		{query: "SELECT * FROM usuarios WHERE edad IN (1, 2, 3)", valid: true},
+		{query: "SELECT * FROM usuarios WHERE nombre = edad", error: "no se puede comparar un valor de tipo character varying con uno de tipo integer"},
+		{query: "SELECT * FROM usuarios WHERE edad = 'dieciocho'", error: "'dieciocho' no es un valor válido de tipo integer"},
+		{query: "SELECT * FROM usuarios WHERE activo = 'talvez'", error: "no es un valor válido de tipo boolean"},
+		{query: "SELECT * FROM usuarios WHERE edad LIKE '1%'", error: "LIKE compara texto"},
+		{query: "SELECT * FROM pedidos WHERE codigo = 'abc'", error: "no es un valor válido de tipo uuid"},
+		{query: "SELECT * FROM pedidos WHERE codigo = 'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'", valid: true},
+	})
+}
+
+func TestArithmeticTypes(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT edad + 1, total * 2 FROM usuarios, pedidos", valid: true},
+		{query: "SELECT fecha + 1, fecha - interval '1 day' FROM pedidos", valid: true},
+		{query: "SELECT nombre + 1 FROM usuarios", error: "el operador '+' no se puede aplicar"},
+		{query: "SELECT -nombre FROM usuarios", error: "el operador '-' requiere un valor numérico"},
+		{query: "SELECT edad + 'x' FROM usuarios", error: "'x' no es un valor válido"},
+	})
+}
+
+func TestDateTimeLiterals(t *testing.T) {
+	tests := []struct {
+		text  string
+		valid bool
+	}{
+		{"2024-01-31", true},
+		{"2024-02-29 23:59:59.5", true},
+		{"2024-01-01T10:00:00Z", true},
+		{"2024-01-01 10:00+02", true},
+		{"2024-01-01 10:00 UTC", true},
+		{"2024-01-01 10:00 America/Argentina/Buenos_Aires", true},
+		{"01/02/2024", true},
+		{"20240131", true},
+		{"January 8, 1999", true},
+		{"1999-Jan-08", true},
+		{"Mon Jan 8 1999 10:00 PM", true},
+		{"today", true},
+		{"infinity", true},
+		{"2023-02-29", false},
+		{"2024-13-01", false},
+		{"2024-01-01 25:00", false},
+		{"2024-01-01 foo", false},
+		{"abc1", false},
+		{"x 12", false},
+		{"Janvier 8 1999", false},
+		{"hoy", false},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			if got := validDateTime(test.text); got != test.valid {
+				t.Errorf("validDateTime(%q) = %v, se esperaba %v", test.text, got, test.valid)
+			}
+		})
+	}
+}
+
+func TestLiteralFormats(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT * FROM pedidos WHERE fecha = '2024-02-29'", valid: true},
+		{query: "SELECT * FROM pedidos WHERE fecha = '2023-02-29'", error: "'2023-02-29' no es un valor válido de tipo date"},
+		{query: "SELECT * FROM pedidos WHERE fecha = 'abc1'", error: "'abc1' no es un valor válido de tipo date"},
+		{query: "SELECT * FROM usuarios WHERE creado < '2024-01-01 10:00'", valid: true},
+		{query: "SELECT DATE '2020-13-01'", error: "no es un valor válido de tipo date"},
+		{query: "SELECT TIMESTAMP '2024-01-01 10:00'", valid: true},
+		{query: "SELECT '2024-01-32'::date", error: "no es un valor válido de tipo date"},
+		{query: "SELECT CAST('12' AS integer)", valid: true},
+		{query: "SELECT CAST('1.5' AS integer)", error: "no es un valor válido de tipo integer"},
+		{query: "SELECT uuid 'abc'", error: "no es un valor válido de tipo uuid"},
+	})
+}
+
+func TestAssignedValues(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "INSERT INTO usuarios (id, nombre, edad) VALUES (1, 'Ana', 30)", valid: true},
+		{query: "INSERT INTO usuarios (id, nombre, edad) VALUES (1, 'Ana', NULL)", valid: true},
+		{query: "INSERT INTO productos VALUES (1, 'mesa', 10.5, 'MES')", valid: true},
+		{query: "INSERT INTO usuarios (id, nombre, edad) VALUES (1, 'Ana', 'treinta')", error: "no es un valor válido de tipo integer"},
+		{query: "INSERT INTO productos (id, nombre) VALUES (1, 'un nombre demasiado largo')", error: "la columna 'nombre' es de tipo character varying(10)"},
+		{query: "INSERT INTO productos (id, sigla) VALUES (1, 'ABCD')", error: "la columna 'sigla' es de tipo character(3)"},
+		{query: "INSERT INTO usuarios (id, nombre, activo) VALUES (1, 'Ana', 5)", error: "la columna 'activo' es de tipo boolean pero el valor es de tipo integer"},
+		{query: "UPDATE usuarios SET edad = edad + 1", valid: true},
+		{query: "UPDATE usuarios SET edad = nombre", error: "la columna 'edad' es de tipo integer"},
+		{query: "INSERT INTO usuarios (id, nombre, edad) SELECT id, nombre, nombre FROM productos", error: "la columna 'edad' es de tipo integer"},
+	})
+}
+
+func TestBooleanConditions(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT * FROM usuarios WHERE activo", valid: true},
+		{query: "SELECT * FROM usuarios WHERE activo AND edad > 1 OR NOT activo", valid: true},
+		{query: "SELECT * FROM usuarios WHERE 't'", valid: true},
+		{query: "SELECT * FROM usuarios WHERE NULL", valid: true},
+		{query: "SELECT count(*) FILTER (WHERE activo) FROM usuarios", valid: true},
+		{query: "SELECT * FROM usuarios WHERE edad", error: "el argumento de WHERE debe ser de tipo boolean, no de tipo integer"},
+		{query: "SELECT * FROM usuarios WHERE 1", error: "el argumento de WHERE debe ser de tipo boolean"},
+		{query: "SELECT * FROM usuarios WHERE 'x'", error: "'x' no es un valor válido de tipo boolean"},
+		{query: "SELECT edad FROM usuarios GROUP BY edad HAVING count(*)", error: "el argumento de HAVING debe ser de tipo boolean, no de tipo bigint"},
+		{query: "SELECT * FROM usuarios u JOIN pedidos p ON p.total", error: "el argumento de JOIN/ON debe ser de tipo boolean"},
+		{query: "SELECT * FROM usuarios WHERE activo AND edad", error: "el argumento de AND debe ser de tipo boolean"},
+		{query: "SELECT * FROM usuarios WHERE NOT nombre", error: "el argumento de NOT debe ser de tipo boolean"},
+		{query: "SELECT CASE WHEN edad THEN 1 END FROM usuarios", error: "el argumento de CASE/WHEN debe ser de tipo boolean"},
+		{query: "UPDATE usuarios SET edad = 1 WHERE id", error: "el argumento de WHERE debe ser de tipo boolean"},
+		{query: "DELETE FROM usuarios WHERE nombre", error: "el argumento de WHERE debe ser de tipo boolean"},
+	})
+}
+
+func TestLimitTypes(t *testing.T) {
+	runSemanticTests(t, []semanticTest{
+		{query: "SELECT * FROM usuarios LIMIT 10 OFFSET 5", valid: true},
+		{query: "SELECT * FROM usuarios LIMIT '10'", valid: true},
+		{query: "SELECT * FROM usuarios LIMIT ALL", valid: true},
+		{query: "SELECT * FROM usuarios LIMIT 'diez'", error: "'diez' no es un valor válido de tipo bigint"},
+		{query: "SELECT * FROM usuarios LIMIT true", error: "el argumento de LIMIT debe ser de tipo bigint, no de tipo boolean"},
+		{query: "SELECT * FROM usuarios LIMIT edad", error: "el argumento de LIMIT no puede contener columnas"},
+		{query: "SELECT id FROM usuarios UNION SELECT id FROM pedidos OFFSET 'x'", error: "'x' no es un valor válido de tipo bigint"},
+	})
+}
@@ -0,0 +1,369 @@
+package analyzer
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Categorías de tipos. Dos valores de la misma categoría son compatibles
+// (PostgreSQL convierte entre ellos implícitamente); typeUnknown indica que
+// el tipo no se puede deducir sin consultar la base.
+const (
+	typeUnknown  = ""
+	typeNumeric  = "numérico"
+	typeText     = "texto"
+	typeBoolean  = "booleano"
+	typeDateTime = "fecha/hora"
+	typeInterval = "intervalo"
+	typeUUID     = "uuid"
+	typeJSON     = "json"
+	typeBinary   = "binario"
+	typeArray    = "arreglo"
+)
+
+// typeCategories asigna una categoría a cada tipo de validTypes.
+var typeCategories = map[string]string{
+	"INT": typeNumeric, "INTEGER": typeNumeric, "BIGINT": typeNumeric, "SMALLINT": typeNumeric,
+	"SERIAL": typeNumeric, "BIGSERIAL": typeNumeric,
+	"DECIMAL": typeNumeric, "NUMERIC": typeNumeric, "FLOAT": typeNumeric, "REAL": typeNumeric,
+	"DOUBLE PRECISION": typeNumeric, "MONEY": typeNumeric,
+	"VARCHAR": typeText, "TEXT": typeText, "CHAR": typeText,
+	"DATE": typeDateTime, "TIME": typeDateTime, "TIMESTAMP": typeDateTime,
+	"TIMETZ": typeDateTime, "TIMESTAMPTZ": typeDateTime,
+	"INTERVAL": typeInterval,
+	"BOOLEAN":  typeBoolean, "BOOL": typeBoolean,
+	"UUID": typeUUID, "JSON": typeJSON, "JSONB": typeJSON,
+	"BYTEA": typeBinary, "ARRAY": typeArray,
+}
+
+// typeCategory devuelve la categoría de un tipo de dato por su nombre.
+func typeCategory(typeName string) string {
+	return typeCategories[strings.ToUpper(typeName)]
+}
+
+// catalogTypeNames traduce los nombres de tipo de information_schema que no
+// coinciden con los de validTypes.
+var catalogTypeNames = map[string]string{
+	"CHARACTER VARYING": "VARCHAR", "CHARACTER": "CHAR",
+	"TIMESTAMP WITHOUT TIME ZONE": "TIMESTAMP", "TIMESTAMP WITH TIME ZONE": "TIMESTAMP",
+	"TIME WITHOUT TIME ZONE": "TIME", "TIME WITH TIME ZONE": "TIME",
+}
+
+// catalogTypeCategory devuelve la categoría de un tipo tal como lo nombra
+// information_schema.columns (integer, character varying...).
+func catalogTypeCategory(dataType string) string {
+	name := strings.ToUpper(dataType)
+	if alias, ok := catalogTypeNames[name]; ok {
+		name = alias
+	}
+	return typeCategory(name)
+}
+
+// dataTypeNames traduce los tipos de validTypes al nombre con el que los
+// informa information_schema.columns.
+var dataTypeNames = map[string]string{
+	"INT": "integer", "INTEGER": "integer", "SERIAL": "integer",
+	"BIGINT": "bigint", "BIGSERIAL": "bigint", "SMALLINT": "smallint",
+	"DECIMAL": "numeric", "NUMERIC": "numeric", "FLOAT": "double precision",
+	"DOUBLE PRECISION": "double precision", "REAL": "real", "MONEY": "money",
+	"VARCHAR": "character varying", "CHAR": "character", "TEXT": "text",
+	"DATE": "date", "TIME": "time without time zone", "TIMESTAMP": "timestamp without time zone",
+	"TIMETZ": "time with time zone", "TIMESTAMPTZ": "timestamp with time zone",
+	"INTERVAL": "interval", "BOOLEAN": "boolean", "BOOL": "boolean",
+	"UUID": "uuid", "JSON": "json", "JSONB": "jsonb", "BYTEA": "bytea", "ARRAY": "ARRAY",
+}
+
+// catalogTypeName devuelve el nombre que information_schema daría al tipo
+// de una columna definida con ese tipo.
+func catalogTypeName(dataType *DataType) string {
+	if dataType.ArrayDims > 0 {
+		return "ARRAY"
+	}
+	if name, ok := dataTypeNames[dataType.Name]; ok {
+		return name
+	}
+	return strings.ToLower(dataType.Name)
+}
+
+// typeLength devuelve el largo máximo de un VARCHAR(n) o CHAR(n), o 0 si
+// el tipo no lo limita. Como en PostgreSQL, VARCHAR sin tamaño no tiene
+// límite y CHAR sin tamaño es CHAR(1).
+func typeLength(dataType *DataType) int {
+	if dataType.ArrayDims > 0 || dataType.Name != "VARCHAR" && dataType.Name != "CHAR" {
+		return 0
+	}
+	if len(dataType.Params) == 0 {
+		if dataType.Name == "CHAR" {
+			return 1
+		}
+		return 0
+	}
+	length, _ := strconv.Atoi(dataType.Params[0])
+	return length
+}
+
+// valueType es el tipo deducido del valor de una expresión: su categoría y,
+// si se conoce, el nombre del tipo como lo informa el catálogo y el largo
+// máximo de un character varying(n). Una cadena literal no tiene tipo
+// propio: PostgreSQL la convierte al tipo con el que se combina, y literal
+// la conserva para verificar que la conversión sea posible.
+type valueType struct {
+	category string
+	name     string
+	length   int
+	literal  *Literal
+}
+
+// booleanType es el tipo de las condiciones.
+var booleanType = valueType{category: typeBoolean, name: "boolean"}
+
+// describe nombra el tipo en los mensajes: por su nombre o, si no se
+// conoce, por su categoría.
+func (t valueType) describe() string {
+	if t.name != "" {
+		return t.name
+	}
+	return t.category
+}
+
+// columnType es el tipo de una columna del catálogo.
+func (info *SemanticInfo) columnType(column ColumnSchema) valueType {
+	if column.Type == "" {
+		return valueType{}
+	}
+	return valueType{category: info.catalog.TypeCategory(column.Type), name: column.Type, length: column.Length}
+}
+
+// dataType es el tipo escrito en una conversión o una definición.
+func dataType(t *DataType) valueType {
+	if t.ArrayDims > 0 {
+		return valueType{category: typeArray}
+	}
+	return valueType{category: typeCategory(t.Name), name: catalogTypeName(t), length: typeLength(t)}
+}
+
+// typeOf deduce el tipo del valor de una expresión a partir de su forma y
+// de los tipos de las columnas que usa.
+func (info *SemanticInfo) typeOf(expr Expr) valueType {
+	switch e := expr.(type) {
+	case *Literal:
+		switch e.Kind {
+		case "NUMERO":
+			if strings.ContainsAny(e.Value, ".eE") {
+				return valueType{category: typeNumeric, name: "numeric"}
+			}
+			return valueType{category: typeNumeric, name: "integer"}
+		case "CADENA":
+			return valueType{category: typeText, literal: e}
+		case "BOOLEANO":
+			return booleanType
+		}
+	case *ColumnRef:
+		if column, ok := info.columnTypes[e]; ok {
+			return info.columnType(column)
+		}
+	case *CastExpr:
+		return dataType(e.Type)
+	case *CaseExpr:
+		return info.commonType(caseResults(e))
+	case *FuncCall:
+		fn, ok := lookupFunction(e.Name)
+		switch {
+		case !ok:
+			return valueType{}
+		case fn.Returns != "":
+			return dataType(&DataType{Name: fn.Returns})
+		}
+		// Funciones como COALESCE o MAX devuelven el tipo de sus argumentos
+		result := info.commonType(e.Args)
+		result.literal = nil
+		return result
+	case *SubqueryExpr:
+		if items, known := outputItems(e.Query); known && len(items) == 1 {
+			return info.typeOf(items[0].Expr)
+		}
+	case *UnaryExpr:
+		if e.Op == "NOT" {
+			return booleanType
+		}
+		return info.typeOf(e.Operand)
+	case *BinaryExpr:
+		switch e.Op {
+		case "||":
+			return valueType{category: typeText, name: "text"}
+		case "+", "-", "*", "/", "%", "^":
+			result, _ := arithmeticType(e.Op, info.typeOf(e.Left), info.typeOf(e.Right))
+			return result
+		}
+		return booleanType
+	case *InExpr, *BetweenExpr, *LikeExpr, *IsExpr, *ExistsExpr, *QuantifiedExpr:
+		return booleanType
+	}
+	return valueType{}
+}
+
+// commonType es el tipo que toma un conjunto de valores combinados, como
+// los resultados de un CASE: el del primero con tipo propio, al que se
+// convierten las cadenas literales. Si todos son cadenas, es texto.
+func (info *SemanticInfo) commonType(exprs []Expr) valueType {
+	var result valueType
+	for _, expr := range exprs {
+		t := info.typeOf(expr)
+		if t.literal == nil && t.category != typeUnknown {
+			return t
+		}
+		if result.category == typeUnknown {
+			result = t
+		}
+	}
+	return result
+}
+
+// arithmeticType devuelve el tipo del resultado de una operación aritmética
+// y si la operación es válida para esos tipos. Además de los números, se
+// admiten las operaciones de fechas e intervalos de PostgreSQL: fecha ±
+// intervalo, fecha - fecha, intervalo ± intervalo, intervalo * número y
+// date ± entero. Una cadena literal toma el tipo del otro operando, salvo
+// junto a una fecha, donde es un intervalo ('1 day').
+func arithmeticType(op string, left, right valueType) (valueType, bool) {
+	if left.literal != nil && right.literal == nil {
+		left = literalOperand(right)
+	}
+	if right.literal != nil && left.literal == nil {
+		right = literalOperand(left)
+	}
+	if left.category == typeUnknown || right.category == typeUnknown {
+		return valueType{}, true
+	}
+
+	numeric := valueType{category: typeNumeric}
+	interval := valueType{category: typeInterval, name: "interval"}
+	switch pair := left.category + " " + op + " " + right.category; pair {
+	case typeNumeric + " " + op + " " + typeNumeric:
+		if left.name == right.name {
+			numeric.name = left.name
+		}
+		return numeric, true
+	case typeDateTime + " + " + typeInterval, typeDateTime + " - " + typeInterval:
+		return left, true
+	case typeInterval + " + " + typeDateTime:
+		return right, true
+	case typeDateTime + " - " + typeDateTime:
+		if left.name == "date" && right.name == "date" {
+			return valueType{category: typeNumeric, name: "integer"}, true
+		}
+		return interval, true
+	case typeInterval + " + " + typeInterval, typeInterval + " - " + typeInterval,
+		typeInterval + " * " + typeNumeric, typeInterval + " / " + typeNumeric, typeNumeric + " * " + typeInterval:
+		return interval, true
+	case typeDateTime + " + " + typeNumeric, typeDateTime + " - " + typeNumeric:
+		return left, left.name == "date"
+	case typeNumeric + " + " + typeDateTime:
+		return right, right.name == "date"
+	}
+	return valueType{}, false
+}
+
+// literalOperand es el tipo que toma una cadena literal operada con un
+// valor de tipo other.
+func literalOperand(other valueType) valueType {
+	if other.category == typeDateTime {
+		return valueType{category: typeInterval, name: "interval"}
+	}
+	return other
+}
+
+// compatibleCategories indica si dos categorías pueden combinarse; una
+// categoría desconocida es compatible con cualquiera.
+func compatibleCategories(a, b string) bool {
+	return a == typeUnknown || b == typeUnknown || a == b
+}
+
+// setBranch es una de las consultas combinadas por una operación de
+// conjuntos y el operador que la une a las anteriores.
+type setBranch struct {
+	query Query
+	op    string
+}
+
+// setBranches aplana una operación de conjuntos en sus ramas, en orden.
+func setBranches(query Query, op string) []setBranch {
+	if set, ok := query.(*SetOperation); ok {
+		return append(setBranches(set.Left, op), setBranches(set.Right, set.Op)...)
+	}
+	return []setBranch{{query: query, op: op}}
+}
+
+// checkSetOperation verifica que todas las ramas de una operación de
+// conjuntos devuelvan la misma cantidad de columnas y que los tipos de cada
+// columna sean compatibles con los de la primera rama.
+func (info *SemanticInfo) checkSetOperation(set *SetOperation) {
+	branches := setBranches(set, "")
+	first, known := outputItems(branches[0].query)
+	if !known {
+		return
+	}
+
+	for i, branch := range branches[1:] {
+		items, known := outputItems(branch.query)
+		if !known {
+			continue
+		}
+		number := i + 2
+		if len(items) != len(first) {
+			info.addError(branch.query.Location(), "%s: la rama %d devuelve %d columnas pero la primera devuelve %d",
+				branch.op, number, len(items), len(first))
+			continue
+		}
+		for col, item := range items {
+			expected, actual := info.typeOf(first[col].Expr), info.typeOf(item.Expr)
+			if !info.coerceLiteral(expected, actual) && !compatibleCategories(expected.category, actual.category) {
+				info.addError(item.Span, "%s: la columna %d de la rama %d es de tipo %s pero en la primera rama es de tipo %s",
+					branch.op, col+1, number, actual.category, expected.category)
+			}
+		}
+	}
+}
+
+// caseResults devuelve los resultados posibles de un CASE: el de cada THEN
+// y el de ELSE.
+func caseResults(expr *CaseExpr) []Expr {
+	results := make([]Expr, 0, len(expr.Whens)+1)
+	for _, when := range expr.Whens {
+		results = append(results, when.Result)
+	}
+	if expr.Else != nil {
+		results = append(results, expr.Else)
+	}
+	return results
+}
+
+// checkCases verifica que los resultados de cada CASE sean de tipos
+// compatibles y que las condiciones de un CASE con búsqueda sean booleanas.
+// Las cadenas literales deben poder convertirse al tipo de los demás
+// resultados.
+func (info *SemanticInfo) checkCases(stmt Statement) {
+	Walk(stmt, func(node Node) bool {
+		expr, ok := node.(*CaseExpr)
+		if !ok {
+			return true
+		}
+		if expr.Operand == nil {
+			for _, when := range expr.Whens {
+				info.checkCondition(when.Cond, "CASE/WHEN")
+			}
+		}
+
+		results := caseResults(expr)
+		common := info.commonType(results)
+		for _, result := range results {
+			t := info.typeOf(result)
+			if !info.coerceLiteral(t, common) && !compatibleCategories(common.category, t.category) {
+				info.addError(result.Location(), "los resultados de CASE no son compatibles: uno es de tipo %s y otro de tipo %s",
+					common.category, t.category)
+				break
+			}
+		}
+		return true
+	})
+}
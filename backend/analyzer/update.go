@@ -0,0 +1,36 @@
+package analyzer
+
+// checkUpdate verifica las asignaciones de un UPDATE contra las columnas de
+// la tabla que modifica.
+func (info *SemanticInfo) checkUpdate(stmt *UpdateStmt) {
+	info.checkAssignments(stmt.Set, stmt.Table.Name)
+}
+
+// checkAssignments verifica las asignaciones de un SET: que cada columna
+// exista (si se conocen las de la tabla), que no se asigne dos veces y que
+// una subconsulta asignada a una lista de columnas devuelva tantas columnas
+// como la lista.
+func (info *SemanticInfo) checkAssignments(set []*Assignment, table QualifiedName) {
+	seen := map[string]bool{}
+	for _, assignment := range set {
+		for _, column := range assignment.Targets() {
+			if seen[column.Name] {
+				info.addError(column.Span, "la columna '%s' se asigna más de una vez en SET", column.String())
+				continue
+			}
+			seen[column.Name] = true
+			if !info.addTableColumn(column, table) {
+				info.addError(column.Span, "la columna '%s' no existe en la tabla '%s'", column.String(), table.String())
+			}
+		}
+
+		subquery, ok := assignment.Value.(*SubqueryExpr)
+		if !ok || len(assignment.Columns) == 0 {
+			continue
+		}
+		if output, known := outputColumns(subquery.Query); known && len(output) != len(assignment.Columns) {
+			info.addError(subquery.Span, "la subconsulta devuelve %d columnas pero se asignan %d",
+				len(output), len(assignment.Columns))
+		}
+	}
+}
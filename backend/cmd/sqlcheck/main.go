@@ -0,0 +1,77 @@
+// sqlcheck verifica scripts SQL contra un esquema armado con archivos DDL,
+// sin conectarse a una base de datos:
+//
+//	go run ./cmd/sqlcheck -schema migraciones consultas/*.sql
+//
+// Termina con código 1 si alguna sentencia tiene errores.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sql-analyzer/analyzer"
+)
+
+func main() {
+	schema := flag.String("schema", "", "directorio con los scripts .sql que definen el esquema")
+	flag.Parse()
+	if *schema == "" || flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "uso: sqlcheck -schema <directorio> <archivo.sql>...")
+		os.Exit(2)
+	}
+
+	catalog, warnings, err := analyzer.LoadCatalogDir(*schema)
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, "aviso:", warning)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error cargando el esquema:", err)
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, file := range flag.Args() {
+		script, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if !checkScript(file, string(script), catalog) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// checkScript analiza cada sentencia del script, con los cambios de esquema
+// de las anteriores, e informa sus errores y avisos. Devuelve false si hubo
+// errores.
+func checkScript(file, script string, catalog analyzer.Catalog) bool {
+	valid := true
+	analysis := analyzer.NewScriptAnalyzer(catalog)
+	for _, stmt := range analyzer.SplitScript(script) {
+		info, err := analysis.Semantic(stmt)
+		if err != nil {
+			span, _ := analyzer.ErrorSpan(err)
+			fmt.Printf("%s:%d:%d: %v\n", file, span.Start.Line, span.Start.Column, err)
+			valid = false
+			continue
+		}
+
+		errors := map[string]bool{}
+		for _, e := range info.Errors {
+			fmt.Printf("%s:%d:%d: %s\n", file, e.Start.Line, e.Start.Column, e.Message)
+			errors[e.Message] = true
+		}
+		for _, warning := range info.Warnings {
+			if !errors[warning] {
+				fmt.Printf("%s:%d:%d: aviso: %s\n", file, stmt.Start.Line, stmt.Start.Column, warning)
+			}
+		}
+		valid = valid && info.Valid
+	}
+	return valid
+}
@@ -54,31 +54,61 @@ type QueryResult struct {
 	Columns      []string                 `json:"columns,omitempty"`
 	Message      string                   `json:"message"`
 	TableName    string                   `json:"tableName,omitempty"`
+	// Before es la estructura de la tabla antes de un ALTER TABLE; Data
+	// contiene la estructura resultante.
+	Before []map[string]interface{} `json:"before,omitempty"`
 }
 
+// querier ejecuta sentencias. Lo cumplen el pool (*sql.DB) y la conexión
+// fija de una sesión (ver Session).
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// ExecuteQuery ejecuta una sentencia en cualquier conexión del pool. Las
+// sentencias de transacción necesitan que las siguientes usen la misma
+// conexión, por lo que solo se admiten dentro de una sesión.
 func ExecuteQuery(query string) (*QueryResult, error) {
-	queryUpper := strings.ToUpper(strings.TrimSpace(query))
+	if transactionKind(query) != "" {
+		return nil, fmt.Errorf("las sentencias de transacción requieren una sesión: sin ella cada sentencia puede ejecutarse en una conexión distinta")
+	}
+	return executeOn(db, query)
+}
+
+// executeOn ejecuta una sentencia con q y arma su resultado según el tipo
+// de sentencia.
+func executeOn(q querier, query string) (*QueryResult, error) {
+	queryUpper := strings.ToUpper(stripLeadingComments(query))
 
 	switch {
-	case strings.HasPrefix(queryUpper, "SELECT"):
-		return executeSelect(query)
+	case transactionKind(query) != "":
+		return executeTransaction(q, query)
+	case strings.HasPrefix(queryUpper, "SELECT"), strings.HasPrefix(queryUpper, "WITH"),
+		strings.HasPrefix(queryUpper, "("):
+		// Las consultas con CTE y las operaciones de conjuntos entre
+		// paréntesis devuelven filas como un SELECT
+		return executeSelect(q, query)
 	case strings.HasPrefix(queryUpper, "INSERT"):
-		return executeInsert(query)
+		return executeInsert(q, query)
 	case strings.HasPrefix(queryUpper, "UPDATE"):
-		return executeUpdate(query)
+		return executeUpdate(q, query)
 	case strings.HasPrefix(queryUpper, "DELETE"):
-		return executeDelete(query)
+		return executeDelete(q, query)
 	case strings.HasPrefix(queryUpper, "CREATE"):
-		return executeCreate(query)
+		return executeCreate(q, query)
 	case strings.HasPrefix(queryUpper, "DROP"):
-		return executeDrop(query)
+		return executeDrop(q, query)
+	case strings.HasPrefix(queryUpper, "ALTER"):
+		return executeAlter(q, query)
 	default:
-		return executeGeneric(query)
+		return executeGeneric(q, query)
 	}
 }
 
-func executeSelect(query string) (*QueryResult, error) {
-	rows, err := db.Query(query)
+func executeSelect(q querier, query string) (*QueryResult, error) {
+	rows, err := q.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -124,11 +154,26 @@ func executeSelect(query string) (*QueryResult, error) {
 	}, nil
 }
 
-func executeInsert(query string) (*QueryResult, error) {
+func executeInsert(q querier, query string) (*QueryResult, error) {
 	// Extraer nombre de tabla
 	tableName := extractTableName(query, "INTO")
 
-	result, err := db.Exec(query)
+	if hasReturning(query) {
+		data, columns, err := executeReturning(q, query)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryResult{
+			Type:         "INSERT",
+			RowsAffected: int64(len(data)),
+			Data:         data,
+			Columns:      columns,
+			Message:      fmt.Sprintf("INSERT exitoso. %d fila(s) insertada(s) en %s.", len(data), tableName),
+			TableName:    tableName,
+		}, nil
+	}
+
+	result, err := q.Exec(query)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +184,7 @@ func executeInsert(query string) (*QueryResult, error) {
 	var selectQuery string
 	if tableName != "" {
 		selectQuery = fmt.Sprintf("SELECT * FROM %s ORDER BY id DESC LIMIT %d", tableName, rowsAffected)
-		rows, err := db.Query(selectQuery)
+		rows, err := q.Query(selectQuery)
 		if err == nil {
 			defer rows.Close()
 			data, columns := rowsToData(rows)
@@ -163,11 +208,26 @@ func executeInsert(query string) (*QueryResult, error) {
 	}, nil
 }
 
-func executeUpdate(query string) (*QueryResult, error) {
+func executeUpdate(q querier, query string) (*QueryResult, error) {
 	tableName := extractTableName(query, "UPDATE")
 
+	if hasReturning(query) {
+		data, columns, err := executeReturning(q, query)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryResult{
+			Type:         "UPDATE",
+			RowsAffected: int64(len(data)),
+			Data:         data,
+			Columns:      columns,
+			Message:      fmt.Sprintf("UPDATE exitoso. %d fila(s) actualizada(s) en %s.", len(data), tableName),
+			TableName:    tableName,
+		}, nil
+	}
+
 	// Ejecutar el UPDATE directamente
-	result, err := db.Exec(query)
+	result, err := q.Exec(query)
 	if err != nil {
 		return nil, err
 	}
@@ -178,7 +238,7 @@ func executeUpdate(query string) (*QueryResult, error) {
 	if tableName != "" && rowsAffected > 0 {
 		whereClause := extractWhereClause(query)
 		selectQuery := fmt.Sprintf("SELECT * FROM %s %s", tableName, whereClause)
-		rows, err := db.Query(selectQuery)
+		rows, err := q.Query(selectQuery)
 		if err == nil {
 			defer rows.Close()
 			data, columns := rowsToData(rows)
@@ -202,23 +262,38 @@ func executeUpdate(query string) (*QueryResult, error) {
 	}, nil
 }
 
-func executeDelete(query string) (*QueryResult, error) {
+func executeDelete(q querier, query string) (*QueryResult, error) {
 	tableName := extractTableName(query, "FROM")
 
+	if hasReturning(query) {
+		data, columns, err := executeReturning(q, query)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryResult{
+			Type:         "DELETE",
+			RowsAffected: int64(len(data)),
+			Data:         data,
+			Columns:      columns,
+			Message:      fmt.Sprintf("DELETE exitoso. %d fila(s) eliminada(s) de %s.", len(data), tableName),
+			TableName:    tableName,
+		}, nil
+	}
+
 	// Obtener datos antes de eliminar
 	var deletedData []map[string]interface{}
 	var columns []string
 	if tableName != "" {
 		whereClause := extractWhereClause(query)
 		selectQuery := fmt.Sprintf("SELECT * FROM %s %s", tableName, whereClause)
-		rows, err := db.Query(selectQuery)
+		rows, err := q.Query(selectQuery)
 		if err == nil {
 			deletedData, columns = rowsToData(rows)
 			rows.Close()
 		}
 	}
 
-	result, err := db.Exec(query)
+	result, err := q.Exec(query)
 	if err != nil {
 		return nil, err
 	}
@@ -235,8 +310,8 @@ func executeDelete(query string) (*QueryResult, error) {
 	}, nil
 }
 
-func executeCreate(query string) (*QueryResult, error) {
-	_, err := db.Exec(query)
+func executeCreate(q querier, query string) (*QueryResult, error) {
+	_, err := q.Exec(query)
 	if err != nil {
 		return nil, err
 	}
@@ -257,17 +332,8 @@ func executeCreate(query string) (*QueryResult, error) {
 
 	// Si es una tabla, obtener su estructura
 	if objectType == "TABLA" && objectName != "" {
-		structQuery := `
-            SELECT column_name, data_type, is_nullable, column_default
-            FROM information_schema.columns
-            WHERE table_name = $1
-            ORDER BY ordinal_position;
-        `
-		rows, err := db.Query(structQuery, strings.ToLower(objectName))
+		data, columns, err := tableStructure(q, objectName)
 		if err == nil {
-			defer rows.Close()
-			data, columns := rowsToData(rows)
-
 			return &QueryResult{
 				Type:      "CREATE",
 				Data:      data,
@@ -284,32 +350,132 @@ func executeCreate(query string) (*QueryResult, error) {
 	}, nil
 }
 
-func executeDrop(query string) (*QueryResult, error) {
-	queryUpper := strings.ToUpper(query)
-	var objectType, objectName string
+// dropObjectNames nombra en los mensajes cada tipo de objeto de DROP: en
+// singular, en plural y el participio que concuerda con él.
+var dropObjectNames = map[string][3]string{
+	"TABLE":             {"TABLA", "TABLAS", "eliminada"},
+	"VIEW":              {"VISTA", "VISTAS", "eliminada"},
+	"MATERIALIZED VIEW": {"VISTA MATERIALIZADA", "VISTAS MATERIALIZADAS", "eliminada"},
+	"INDEX":             {"ÍNDICE", "ÍNDICES", "eliminado"},
+	"SEQUENCE":          {"SECUENCIA", "SECUENCIAS", "eliminada"},
+	"SCHEMA":            {"ESQUEMA", "ESQUEMAS", "eliminado"},
+	"DATABASE":          {"BASE DE DATOS", "BASES DE DATOS", "eliminada"},
+}
 
-	if strings.Contains(queryUpper, "TABLE") {
-		objectType = "TABLA"
-		objectName = extractTableName(query, "TABLE")
-	} else if strings.Contains(queryUpper, "DATABASE") {
-		objectType = "BASE DE DATOS"
-		objectName = extractTableName(query, "DATABASE")
-	}
+func executeDrop(q querier, query string) (*QueryResult, error) {
+	objectType, names := extractDropTarget(query)
 
-	_, err := db.Exec(query)
+	_, err := q.Exec(query)
 	if err != nil {
 		return nil, err
 	}
 
+	labels, ok := dropObjectNames[objectType]
+	if !ok {
+		labels = [3]string{"OBJETO", "OBJETOS", "eliminado"}
+	}
+	message := fmt.Sprintf("%s '%s' %s exitosamente.", labels[0], strings.Join(names, ", "), labels[2])
+	if len(names) > 1 {
+		message = fmt.Sprintf("%s %ss exitosamente: %s.", labels[1], labels[2], strings.Join(names, ", "))
+	}
+
 	return &QueryResult{
 		Type:      "DROP",
-		Message:   fmt.Sprintf("%s '%s' eliminada exitosamente.", objectType, objectName),
-		TableName: objectName,
+		Message:   message,
+		TableName: strings.Join(names, ", "),
+	}, nil
+}
+
+// executeAlter ejecuta un ALTER TABLE y devuelve la estructura de la tabla
+// antes (Before) y después (Data) del cambio.
+func executeAlter(q querier, query string) (*QueryResult, error) {
+	tableName, newName := extractAlterTarget(query)
+	before, _, _ := tableStructure(q, tableName)
+
+	_, err := q.Exec(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if newName != "" {
+		tableName = newName
+	}
+	message := fmt.Sprintf("TABLA '%s' modificada exitosamente.", tableName)
+	after, columns, err := tableStructure(q, tableName)
+	if err != nil {
+		return &QueryResult{Type: "ALTER", Message: message, TableName: tableName}, nil
+	}
+
+	return &QueryResult{
+		Type:      "ALTER",
+		Data:      after,
+		Columns:   columns,
+		Before:    before,
+		Message:   message,
+		TableName: tableName,
+	}, nil
+}
+
+// transactionKind devuelve el tipo de una sentencia de control de
+// transacciones ("BEGIN", "COMMIT", "ROLLBACK", "ROLLBACK TO", "SAVEPOINT"
+// o "RELEASE"), o "" si la sentencia es de otro tipo.
+func transactionKind(query string) string {
+	words := strings.Fields(strings.ToUpper(strings.TrimSuffix(strings.TrimSpace(stripLeadingComments(query)), ";")))
+	if len(words) == 0 {
+		return ""
+	}
+	switch words[0] {
+	case "BEGIN", "START":
+		return "BEGIN"
+	case "COMMIT", "END":
+		return "COMMIT"
+	case "ROLLBACK", "ABORT":
+		for _, word := range words[1:] {
+			if word == "TO" {
+				return "ROLLBACK TO"
+			}
+		}
+		return "ROLLBACK"
+	case "SAVEPOINT", "RELEASE":
+		return words[0]
+	}
+	return ""
+}
+
+func executeTransaction(q querier, query string) (*QueryResult, error) {
+	_, err := q.Exec(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// El savepoint es siempre la última palabra de la sentencia
+	words := strings.Fields(strings.TrimSuffix(strings.TrimSpace(stripLeadingComments(query)), ";"))
+	savepoint := strings.ToLower(words[len(words)-1])
+
+	var message string
+	switch transactionKind(query) {
+	case "BEGIN":
+		message = "Transacción iniciada. Sus cambios no serán visibles para otras sesiones hasta el COMMIT."
+	case "COMMIT":
+		message = "Transacción confirmada (COMMIT)."
+	case "ROLLBACK":
+		message = "Transacción revertida (ROLLBACK): se descartaron sus cambios."
+	case "ROLLBACK TO":
+		message = fmt.Sprintf("Cambios revertidos hasta el savepoint '%s'.", savepoint)
+	case "SAVEPOINT":
+		message = fmt.Sprintf("Savepoint '%s' creado.", savepoint)
+	case "RELEASE":
+		message = fmt.Sprintf("Savepoint '%s' liberado.", savepoint)
+	}
+
+	return &QueryResult{
+		Type:    "TRANSACTION",
+		Message: message,
 	}, nil
 }
 
-func executeGeneric(query string) (*QueryResult, error) {
-	result, err := db.Exec(query)
+func executeGeneric(q querier, query string) (*QueryResult, error) {
+	result, err := q.Exec(query)
 	if err != nil {
 		return nil, err
 	}
@@ -324,6 +490,89 @@ func executeGeneric(query string) (*QueryResult, error) {
 }
 
 // Funciones auxiliares
+
+// stripLeadingComments quita los espacios y comentarios (-- y /* */) que
+// preceden a la sentencia, para poder identificarla por su primera palabra.
+func stripLeadingComments(query string) string {
+	for {
+		query = strings.TrimSpace(query)
+		switch {
+		case strings.HasPrefix(query, "--"):
+			end := strings.IndexByte(query, '\n')
+			if end == -1 {
+				return ""
+			}
+			query = query[end+1:]
+		case strings.HasPrefix(query, "/*"):
+			depth := 0
+			i := 0
+			for i+1 < len(query) {
+				if query[i:i+2] == "/*" {
+					depth++
+					i += 2
+				} else if query[i:i+2] == "*/" {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			if depth != 0 {
+				return ""
+			}
+			query = query[i:]
+		default:
+			return query
+		}
+	}
+}
+
+// hasReturning indica si la sentencia tiene una cláusula RETURNING, sin
+// tener en cuenta el texto de las cadenas y de los identificadores entre
+// comillas.
+func hasReturning(query string) bool {
+	var unquoted strings.Builder
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			unquoted.WriteByte(' ')
+		case c == '\'' || c == '"':
+			quote = c
+			unquoted.WriteByte(' ')
+		default:
+			unquoted.WriteByte(c)
+		}
+	}
+	for _, word := range strings.FieldsFunc(strings.ToUpper(unquoted.String()), func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_')
+	}) {
+		if word == "RETURNING" {
+			return true
+		}
+	}
+	return false
+}
+
+// executeReturning ejecuta una sentencia con RETURNING y devuelve las filas
+// que produce.
+func executeReturning(q querier, query string) ([]map[string]interface{}, []string, error) {
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	data, columns := rowsToData(rows)
+	return data, columns, rows.Err()
+}
+
 func extractTableName(query string, afterKeyword string) string {
 	parts := strings.Fields(strings.ToUpper(query))
 	for i, part := range parts {
@@ -338,6 +587,77 @@ func extractTableName(query string, afterKeyword string) string {
 	return ""
 }
 
+// extractDropTarget devuelve el tipo de objeto de un DROP (TABLE, VIEW...)
+// y los nombres que elimina, sin IF EXISTS ni CASCADE/RESTRICT.
+func extractDropTarget(query string) (objectType string, names []string) {
+	parts := strings.Fields(strings.ReplaceAll(strings.TrimSuffix(strings.TrimSpace(stripLeadingComments(query)), ";"), ",", " "))
+	i := 1
+	if i < len(parts) {
+		objectType = strings.ToUpper(parts[i])
+		i++
+		if objectType == "MATERIALIZED" && i < len(parts) {
+			objectType += " " + strings.ToUpper(parts[i])
+			i++
+		}
+	}
+	for ; i < len(parts); i++ {
+		switch strings.ToUpper(parts[i]) {
+		case "IF", "EXISTS", "CONCURRENTLY", "CASCADE", "RESTRICT":
+		default:
+			names = append(names, strings.ToLower(parts[i]))
+		}
+	}
+	return objectType, names
+}
+
+// extractAlterTarget devuelve la tabla de un ALTER TABLE y, si la sentencia
+// la renombra con RENAME TO, su nuevo nombre.
+func extractAlterTarget(query string) (tableName, newName string) {
+	parts := strings.Fields(strings.TrimSuffix(strings.TrimSpace(stripLeadingComments(query)), ";"))
+	for i := 0; i < len(parts); i++ {
+		switch part := strings.ToUpper(parts[i]); {
+		case part == "TABLE" && tableName == "":
+			j := i + 1
+			for j < len(parts) && (strings.EqualFold(parts[j], "IF") || strings.EqualFold(parts[j], "EXISTS") ||
+				strings.EqualFold(parts[j], "ONLY")) {
+				j++
+			}
+			if j < len(parts) {
+				tableName = unqualified(parts[j])
+				i = j
+			}
+		case part == "RENAME" && i+2 < len(parts) && strings.EqualFold(parts[i+1], "TO"):
+			newName = unqualified(parts[i+2])
+		}
+	}
+	return tableName, newName
+}
+
+// unqualified quita el esquema de un nombre de tabla y lo pasa a minúsculas,
+// como lo guarda information_schema.
+func unqualified(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, ","))
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// tableStructure devuelve las columnas de una tabla según
+// information_schema: nombre, tipo, si admite NULL y valor por defecto.
+func tableStructure(q querier, tableName string) ([]map[string]interface{}, []string, error) {
+	structQuery := `
+            SELECT column_name, data_type, is_nullable, column_default
+            FROM information_schema.columns
+            WHERE table_name = $1
+            ORDER BY ordinal_position;
+        `
+	rows, err := q.Query(structQuery, strings.ToLower(tableName))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	data, columns := rowsToData(rows)
+	return data, columns, nil
+}
+
 func extractWhereClause(query string) string {
 	upperQuery := strings.ToUpper(query)
 	whereIndex := strings.Index(upperQuery, "WHERE")
@@ -382,6 +702,12 @@ func rowsToData(rows *sql.Rows) ([]map[string]interface{}, []string) {
 
 // Función para obtener el estado actual de todas las tablas
 func GetDatabaseState() (map[string]interface{}, error) {
+	return databaseState(db)
+}
+
+// databaseState devuelve las tablas del esquema public y sus filas según
+// las ve q; en una sesión con una transacción abierta incluye sus cambios.
+func databaseState(q querier) (map[string]interface{}, error) {
 	tablesQuery := `
         SELECT table_name 
         FROM information_schema.tables 
@@ -389,25 +715,31 @@ func GetDatabaseState() (map[string]interface{}, error) {
         ORDER BY table_name;
     `
 
-	rows, err := db.Query(tablesQuery)
+	rows, err := q.Query(tablesQuery)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	state := make(map[string]interface{})
-	var tables []map[string]interface{}
 
+	// Los nombres se leen antes de contar las filas: una conexión fija no
+	// admite otra consulta mientras rows está abierto
+	var names []string
 	for rows.Next() {
 		var tableName string
 		if err := rows.Scan(&tableName); err != nil {
 			continue
 		}
+		names = append(names, tableName)
+	}
+	rows.Close()
+
+	state := make(map[string]interface{})
+	var tables []map[string]interface{}
 
+	for _, tableName := range names {
 		// Contar filas en cada tabla
 		var count int
 		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName)
-		db.QueryRow(countQuery).Scan(&count)
+		q.QueryRow(countQuery).Scan(&count)
 
 		tableInfo := map[string]interface{}{
 			"name":     tableName,
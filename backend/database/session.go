@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Una sesión reserva una conexión del pool para un cliente, de modo que
+// todas sus sentencias se ejecutan en la misma conexión y una transacción
+// (BEGIN ... COMMIT/ROLLBACK) puede abarcar varias solicitudes. Las
+// sesiones inactivas por más de SessionIdleTimeout se cierran revirtiendo
+// la transacción que hubieran dejado abierta.
+
+// SessionIdleTimeout es el tiempo sin uso tras el cual se cierra una
+// sesión. Se configura con SESSION_IDLE_TIMEOUT (por ejemplo "15m").
+var SessionIdleTimeout = 10 * time.Minute
+
+// Session es una conexión reservada para un cliente.
+type Session struct {
+	ID string
+
+	mu            sync.Mutex
+	conn          *sql.Conn
+	lastUsed      time.Time
+	inTransaction bool
+	closed        bool
+}
+
+// SessionInfo es el estado de una sesión que se informa al cliente.
+type SessionInfo struct {
+	ID            string    `json:"sessionId"`
+	InTransaction bool      `json:"inTransaction"`
+	LastUsed      time.Time `json:"lastUsed"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+}
+
+var (
+	sessions   = map[string]*Session{}
+	sessionsMu sync.Mutex
+	reaperOnce sync.Once
+	// ErrNoSession indica que la sesión pedida no existe: nunca se creó, se
+	// cerró o expiró por inactividad.
+	ErrNoSession = fmt.Errorf("la sesión no existe o expiró por inactividad")
+)
+
+// connQuerier adapta *sql.Conn, que solo tiene los métodos con contexto, a
+// querier.
+type connQuerier struct {
+	conn *sql.Conn
+}
+
+func (c connQuerier) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.ExecContext(context.Background(), query, args...)
+}
+
+func (c connQuerier) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.QueryContext(context.Background(), query, args...)
+}
+
+func (c connQuerier) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.conn.QueryRowContext(context.Background(), query, args...)
+}
+
+// NewSession reserva una conexión y la registra con un identificador nuevo.
+func NewSession() (*Session, error) {
+	reaperOnce.Do(startReaper)
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	id, err := newSessionID()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	session := &Session{ID: id, conn: conn, lastUsed: time.Now()}
+	sessionsMu.Lock()
+	sessions[id] = session
+	sessionsMu.Unlock()
+	return session, nil
+}
+
+// GetSession devuelve la sesión con ese identificador.
+func GetSession(id string) (*Session, error) {
+	sessionsMu.Lock()
+	session, ok := sessions[id]
+	sessionsMu.Unlock()
+	if !ok {
+		return nil, ErrNoSession
+	}
+	return session, nil
+}
+
+// CloseSession cierra la sesión con ese identificador. Devuelve true si
+// tenía una transacción abierta, que se revierte.
+func CloseSession(id string) (bool, error) {
+	session, err := GetSession(id)
+	if err != nil {
+		return false, err
+	}
+	return session.Close()
+}
+
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Execute ejecuta una sentencia en la conexión de la sesión.
+func (s *Session) Execute(query string) (*QueryResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrNoSession
+	}
+	s.lastUsed = time.Now()
+
+	result, err := executeOn(connQuerier{s.conn}, query)
+	if err != nil {
+		return nil, err
+	}
+	switch transactionKind(query) {
+	case "BEGIN":
+		s.inTransaction = true
+	case "COMMIT", "ROLLBACK":
+		s.inTransaction = false
+	}
+	return result, nil
+}
+
+// DatabaseState devuelve el estado de la base tal como lo ve la sesión,
+// incluidos los cambios de su transacción.
+func (s *Session) DatabaseState() (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrNoSession
+	}
+	return databaseState(connQuerier{s.conn})
+}
+
+// Querier devuelve un objeto que consulta en la conexión de la sesión, para
+// leer el catálogo tal como lo ve su transacción. Cada consulta espera a
+// que termine la sentencia que la sesión esté ejecutando.
+func (s *Session) Querier() SessionQuerier {
+	return SessionQuerier{session: s}
+}
+
+// SessionQuerier consulta en la conexión de una sesión (ver
+// Session.Querier).
+type SessionQuerier struct {
+	session *Session
+}
+
+func (q SessionQuerier) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	q.session.mu.Lock()
+	defer q.session.mu.Unlock()
+	return q.session.conn.QueryContext(context.Background(), query, args...)
+}
+
+func (q SessionQuerier) QueryRow(query string, args ...interface{}) *sql.Row {
+	q.session.mu.Lock()
+	defer q.session.mu.Unlock()
+	return q.session.conn.QueryRowContext(context.Background(), query, args...)
+}
+
+// Info devuelve el estado de la sesión.
+func (s *Session) Info() SessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SessionInfo{
+		ID:            s.ID,
+		InTransaction: s.inTransaction,
+		LastUsed:      s.lastUsed,
+		ExpiresAt:     s.lastUsed.Add(SessionIdleTimeout),
+	}
+}
+
+// Close revierte la transacción abierta, si la hay, y devuelve la conexión
+// al pool. La conexión nunca vuelve al pool dentro de una transacción.
+func (s *Session) Close() (rolledBack bool, err error) {
+	sessionsMu.Lock()
+	delete(sessions, s.ID)
+	sessionsMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false, nil
+	}
+	s.closed = true
+
+	// El ROLLBACK se envía siempre, por si la transacción se abrió de una
+	// forma que Execute no detectó; fuera de una transacción no tiene efecto
+	if _, err := s.conn.ExecContext(context.Background(), "ROLLBACK"); err != nil {
+		// Si no se pudo revertir, la conexión se descarta en vez de volver
+		// al pool
+		s.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		s.conn.Close()
+		return s.inTransaction, err
+	}
+	return s.inTransaction, s.conn.Close()
+}
+
+// startReaper cierra periódicamente las sesiones inactivas.
+func startReaper() {
+	if value := os.Getenv("SESSION_IDLE_TIMEOUT"); value != "" {
+		if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+			SessionIdleTimeout = timeout
+		} else {
+			log.Printf("SESSION_IDLE_TIMEOUT inválido (%q), se usa %s", value, SessionIdleTimeout)
+		}
+	}
+
+	go func() {
+		for range time.Tick(time.Minute) {
+			var open []*Session
+			sessionsMu.Lock()
+			for _, session := range sessions {
+				open = append(open, session)
+			}
+			sessionsMu.Unlock()
+
+			for _, session := range open {
+				if session.Info().ExpiresAt.After(time.Now()) {
+					continue
+				}
+				if rolledBack, _ := session.Close(); rolledBack {
+					log.Printf("sesión %s cerrada por inactividad: se revirtió su transacción", session.ID)
+				}
+			}
+		}
+	}()
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"sql-analyzer/analyzer"
 	"sql-analyzer/database"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -13,17 +16,64 @@ import (
 
 type AnalyzeRequest struct {
 	Query string `json:"query"`
+	// Script indica que Query puede tener varias sentencias separadas por
+	// ';', que se analizan (o ejecutan) una por una.
+	Script bool `json:"script,omitempty"`
+	// SessionID indica la sesión en cuya conexión se ejecuta la query (ver
+	// /api/sessions); vacío usa cualquier conexión del pool.
+	SessionID string `json:"sessionId,omitempty"`
 }
 
 type AnalyzeResponse struct {
-	Valid    bool             `json:"valid"`
-	Tokens   []analyzer.Token `json:"tokens,omitempty"`
-	Syntax   interface{}      `json:"syntax,omitempty"`
-	Semantic interface{}      `json:"semantic,omitempty"`
-	Error    string           `json:"error,omitempty"`
+	Valid     bool             `json:"valid"`
+	Tokens    []analyzer.Token `json:"tokens,omitempty"`
+	Syntax    interface{}      `json:"syntax,omitempty"`
+	Semantic  interface{}      `json:"semantic,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	ErrorSpan *analyzer.Span   `json:"errorSpan,omitempty"`
+	// Statements tiene el resultado de cada sentencia en el modo script.
+	Statements []StatementResponse `json:"statements,omitempty"`
+}
+
+// StatementResponse es el análisis de una sentencia de un script.
+type StatementResponse struct {
+	analyzer.ScriptStatement
+	AnalyzeResponse
+}
+
+// analyzeScript aplica analyze a cada sentencia del script. El script es
+// válido si lo son todas sus sentencias.
+func analyzeScript(script string, analyze func(analyzer.ScriptStatement) AnalyzeResponse) AnalyzeResponse {
+	statements := analyzer.SplitScript(script)
+	if len(statements) == 0 {
+		return AnalyzeResponse{Valid: false, Error: "script vacío"}
+	}
+	resp := AnalyzeResponse{Valid: true}
+	for _, stmt := range statements {
+		result := analyze(stmt)
+		resp.Valid = resp.Valid && result.Valid
+		resp.Statements = append(resp.Statements, StatementResponse{ScriptStatement: stmt, AnalyzeResponse: result})
+	}
+	return resp
+}
+
+// errorResponse arma la respuesta de un análisis fallido incluyendo, si
+// existe, la ubicación del error para que el frontend pueda subrayarla.
+func errorResponse(err error) AnalyzeResponse {
+	resp := AnalyzeResponse{
+		Valid: false,
+		Error: err.Error(),
+	}
+	if span, ok := analyzer.ErrorSpan(err); ok {
+		resp.ErrorSpan = &span
+	}
+	return resp
 }
 
 func main() {
+	// El análisis semántico verifica las sentencias contra la base conectada
+	analyzer.DefaultCatalog = analyzer.NewPostgresCatalog(database.GetDB())
+
 	r := mux.NewRouter()
 
 	// Rutas
@@ -32,6 +82,11 @@ func main() {
 	r.HandleFunc("/api/analyze/semantic", handleSemanticAnalysis).Methods("POST")
 	r.HandleFunc("/api/execute", handleExecuteQuery).Methods("POST")
 
+	// Sesiones: una conexión fija para poder usar transacciones
+	r.HandleFunc("/api/sessions", handleCreateSession).Methods("POST")
+	r.HandleFunc("/api/sessions/{id}", handleGetSession).Methods("GET")
+	r.HandleFunc("/api/sessions/{id}", handleCloseSession).Methods("DELETE")
+
 	// Nueva ruta para obtener el estado de la base de datos
 	r.HandleFunc("/api/database/state", handleDatabaseState).Methods("GET")
 
@@ -54,12 +109,20 @@ func handleLexicalAnalysis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Script {
+		json.NewEncoder(w).Encode(analyzeScript(req.Query, func(stmt analyzer.ScriptStatement) AnalyzeResponse {
+			tokens, err := stmt.Lexical()
+			if err != nil {
+				return errorResponse(err)
+			}
+			return AnalyzeResponse{Valid: true, Tokens: tokens}
+		}))
+		return
+	}
+
 	tokens, err := analyzer.LexicalAnalysis(req.Query)
 	if err != nil {
-		json.NewEncoder(w).Encode(AnalyzeResponse{
-			Valid: false,
-			Error: err.Error(),
-		})
+		json.NewEncoder(w).Encode(errorResponse(err))
 		return
 	}
 
@@ -76,18 +139,26 @@ func handleSyntacticAnalysis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	syntaxTree, err := analyzer.SyntacticAnalysis(req.Query)
+	if req.Script {
+		json.NewEncoder(w).Encode(analyzeScript(req.Query, func(stmt analyzer.ScriptStatement) AnalyzeResponse {
+			syntax, err := stmt.Syntactic()
+			if err != nil {
+				return errorResponse(err)
+			}
+			return AnalyzeResponse{Valid: true, Syntax: analyzer.SyntaxTree(syntax)}
+		}))
+		return
+	}
+
+	stmt, err := analyzer.SyntacticAnalysis(req.Query)
 	if err != nil {
-		json.NewEncoder(w).Encode(AnalyzeResponse{
-			Valid: false,
-			Error: err.Error(),
-		})
+		json.NewEncoder(w).Encode(errorResponse(err))
 		return
 	}
 
 	json.NewEncoder(w).Encode(AnalyzeResponse{
 		Valid:  true,
-		Syntax: syntaxTree,
+		Syntax: analyzer.SyntaxTree(stmt),
 	})
 }
 
@@ -98,12 +169,22 @@ func handleSemanticAnalysis(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Script {
+		// Cada sentencia se verifica con los cambios de esquema de las anteriores
+		script := analyzer.NewScriptAnalyzer(analyzer.DefaultCatalog)
+		json.NewEncoder(w).Encode(analyzeScript(req.Query, func(stmt analyzer.ScriptStatement) AnalyzeResponse {
+			info, err := script.Semantic(stmt)
+			if err != nil {
+				return errorResponse(err)
+			}
+			return AnalyzeResponse{Valid: true, Semantic: info}
+		}))
+		return
+	}
+
 	semanticInfo, err := analyzer.SemanticAnalysis(req.Query)
 	if err != nil {
-		json.NewEncoder(w).Encode(AnalyzeResponse{
-			Valid: false,
-			Error: err.Error(),
-		})
+		json.NewEncoder(w).Encode(errorResponse(err))
 		return
 	}
 
@@ -120,36 +201,151 @@ func handleExecuteQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var session *database.Session
+	if req.SessionID != "" {
+		var err error
+		if session, err = database.GetSession(req.SessionID); err != nil {
+			resp := map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+			}
+			if errors.Is(err, database.ErrNoSession) {
+				resp["code"] = codeSessionNotFound
+			}
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+
+	if req.Script {
+		json.NewEncoder(w).Encode(executeScript(req.Query, session))
+		return
+	}
+
 	// Validar con los tres análisis
 	_, lexErr := analyzer.LexicalAnalysis(req.Query)
 	if lexErr != nil {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "Error léxico: " + lexErr.Error(),
-		})
+		json.NewEncoder(w).Encode(withSession(executeErrorResponse("Error léxico: ", lexErr), session))
 		return
 	}
 
 	_, synErr := analyzer.SyntacticAnalysis(req.Query)
 	if synErr != nil {
+		json.NewEncoder(w).Encode(withSession(executeErrorResponse("Error sintáctico: ", synErr), session))
+		return
+	}
+
+	info, semErr := analyzer.SemanticAnalysisWithCatalog(req.Query, catalogFor(session))
+	if semErr != nil {
+		json.NewEncoder(w).Encode(withSession(executeErrorResponse("Error semántico: ", semErr), session))
+		return
+	}
+	if !info.Valid {
+		json.NewEncoder(w).Encode(withSession(semanticErrorResponse(info), session))
+		return
+	}
+
+	// Ejecutar en PostgreSQL
+	result, err := run(session, req.Query)
+	if err != nil {
+		json.NewEncoder(w).Encode(withSession(map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}, session))
+		return
+	}
+
+	// Obtener el estado actualizado de la base de datos
+	resp := map[string]interface{}{
+		"success": true,
+		"result":  result,
+	}
+	addSessionState(resp, session)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// catalogFor devuelve el catálogo contra el que se validan las sentencias
+// antes de ejecutarlas: el de la conexión de la sesión, que ve los cambios
+// de su transacción sin confirmar, o el de la base.
+func catalogFor(session *database.Session) analyzer.Catalog {
+	if session == nil {
+		return analyzer.DefaultCatalog
+	}
+	return analyzer.NewPostgresCatalog(session.Querier())
+}
+
+// run ejecuta la query en la sesión o, si no hay, en el pool.
+func run(session *database.Session, query string) (*database.QueryResult, error) {
+	if session != nil {
+		return session.Execute(query)
+	}
+	return database.ExecuteQuery(query)
+}
+
+// addSessionState agrega a la respuesta el estado de la base (visto desde
+// la sesión, si la hay) y el de la sesión.
+func addSessionState(resp map[string]interface{}, session *database.Session) {
+	if session == nil {
+		resp["dbState"], _ = database.GetDatabaseState()
+		return
+	}
+	resp["dbState"], _ = session.DatabaseState()
+	resp["session"] = session.Info()
+}
+
+// codeSessionNotFound es el código de error que indica al cliente que la
+// sesión ya no existe y debe olvidarla.
+const codeSessionNotFound = "SESSION_NOT_FOUND"
+
+// withSession agrega a una respuesta de error el estado de la sesión de la
+// solicitud, que sigue abierta (con su transacción, aunque haya quedado
+// abortada) para que el cliente pueda continuarla o revertirla. Si la
+// sesión expiró mientras tanto, agrega codeSessionNotFound.
+func withSession(resp map[string]interface{}, session *database.Session) map[string]interface{} {
+	if session == nil {
+		return resp
+	}
+	if _, err := database.GetSession(session.ID); errors.Is(err, database.ErrNoSession) {
+		resp["code"] = codeSessionNotFound
+		return resp
+	}
+	resp["session"] = session.Info()
+	return resp
+}
+
+func handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	session, err := database.NewSession()
+	if err != nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
-			"error":   "Error sintáctico: " + synErr.Error(),
+			"error":   err.Error(),
 		})
 		return
 	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"session":     session.Info(),
+		"idleTimeout": database.SessionIdleTimeout.String(),
+	})
+}
 
-	_, semErr := analyzer.SemanticAnalysis(req.Query)
-	if semErr != nil {
+func handleGetSession(w http.ResponseWriter, r *http.Request) {
+	session, err := database.GetSession(mux.Vars(r)["id"])
+	if err != nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
-			"error":   "Error semántico: " + semErr.Error(),
+			"error":   err.Error(),
 		})
 		return
 	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"session": session.Info(),
+	})
+}
 
-	// Ejecutar en PostgreSQL
-	result, err := database.ExecuteQuery(req.Query)
+func handleCloseSession(w http.ResponseWriter, r *http.Request) {
+	rolledBack, err := database.CloseSession(mux.Vars(r)["id"])
 	if err != nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": false,
@@ -157,15 +353,133 @@ func handleExecuteQuery(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	message := "Sesión cerrada."
+	if rolledBack {
+		message = "Sesión cerrada: se revirtió la transacción que estaba abierta."
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"rolledBack": rolledBack,
+		"message":    message,
+	})
+}
 
-	// Obtener el estado actualizado de la base de datos
-	dbState, _ := database.GetDatabaseState()
+// executeScript ejecuta las sentencias del script en orden y devuelve el
+// resultado de cada una. Cada sentencia se analiza justo antes de
+// ejecutarla, de modo que ve los cambios de las anteriores; la ejecución se
+// detiene en la primera sentencia que falla. Sin sesión, el script usa una
+// conexión propia durante su ejecución, de modo que puede contener una
+// transacción completa; si la deja abierta, se revierte al terminar.
+func executeScript(script string, session *database.Session) map[string]interface{} {
+	statements := analyzer.SplitScript(script)
+	if len(statements) == 0 {
+		return map[string]interface{}{
+			"success": false,
+			"error":   "script vacío",
+		}
+	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	resp := map[string]interface{}{
+		"success": true,
+		"total":   len(statements),
+	}
+	conn := session
+	if conn == nil {
+		var err error
+		if conn, err = database.NewSession(); err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+			}
+		}
+	}
+
+	// Cada sentencia se verifica con los cambios de esquema de las anteriores
+	analysis := analyzer.NewScriptAnalyzer(catalogFor(conn))
+	var results []map[string]interface{}
+	for _, stmt := range statements {
+		result := executeStatement(stmt, analysis, conn)
+		result["index"] = stmt.Index
+		result["text"] = stmt.Text
+		result["span"] = stmt.Span
+		results = append(results, result)
+		if result["success"] != true {
+			resp["success"] = false
+			resp["error"] = fmt.Sprintf("la sentencia %d falló: %s", stmt.Index+1, result["error"])
+			if span, ok := result["errorSpan"]; ok {
+				resp["errorSpan"] = span
+			}
+			break
+		}
+	}
+	resp["results"] = results
+
+	if session == nil {
+		if rolledBack, _ := conn.Close(); rolledBack {
+			resp["warning"] = "el script dejó una transacción abierta que se revirtió al terminar: use una sesión para continuarla en otra solicitud"
+		}
+	}
+	addSessionState(resp, session)
+	return resp
+}
+
+// executeStatement valida una sentencia de un script con los tres análisis
+// y, si no hay errores, la ejecuta.
+func executeStatement(stmt analyzer.ScriptStatement, script *analyzer.ScriptAnalyzer, session *database.Session) map[string]interface{} {
+	if _, err := stmt.Lexical(); err != nil {
+		return executeErrorResponse("Error léxico: ", err)
+	}
+	if _, err := stmt.Syntactic(); err != nil {
+		return executeErrorResponse("Error sintáctico: ", err)
+	}
+	info, err := script.Semantic(stmt)
+	if err != nil {
+		return executeErrorResponse("Error semántico: ", err)
+	}
+	if !info.Valid {
+		return semanticErrorResponse(info)
+	}
+
+	result, err := session.Execute(stmt.Text)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+	}
+	return map[string]interface{}{
 		"success": true,
 		"result":  result,
-		"dbState": dbState,
-	})
+	}
+}
+
+func executeErrorResponse(prefix string, err error) map[string]interface{} {
+	resp := map[string]interface{}{
+		"success": false,
+		"error":   prefix + err.Error(),
+	}
+	if span, ok := analyzer.ErrorSpan(err); ok {
+		resp["errorSpan"] = span
+	}
+	return resp
+}
+
+// semanticErrorResponse arma la respuesta de una sentencia que no pasó el
+// análisis semántico, con todos sus errores y la ubicación del primero.
+func semanticErrorResponse(info *analyzer.SemanticInfo) map[string]interface{} {
+	messages := make([]string, len(info.Errors))
+	for i, err := range info.Errors {
+		messages[i] = err.Error()
+	}
+	resp := map[string]interface{}{
+		"success": false,
+		"error":   "Error semántico: " + strings.Join(messages, "; "),
+		"errors":  info.Errors,
+	}
+	if len(info.Errors) > 0 {
+		resp["errorSpan"] = info.Errors[0].Span
+	}
+	return resp
 }
 
 func handleDatabaseState(w http.ResponseWriter, r *http.Request) {
@@ -4,6 +4,7 @@ import SyntacticAnalyzer from './components/SyntacticAnalyzer';
 import SemanticAnalyzer from './components/SemanticAnalyzer';
 import QueryResults from './components/QueryResults';
 import DatabaseState from './components/DatabaseState';
+import ScriptResults from './components/ScriptResults';
 import axios from 'axios';
 
 const API_URL = 'http://localhost:8080/api';
@@ -19,12 +20,42 @@ function App() {
   const [error, setError] = useState('');
   const [successMessage, setSuccessMessage] = useState('');
   const [loading, setLoading] = useState(false);
+  const [scriptMode, setScriptMode] = useState(false);
+  const [session, setSession] = useState(null);
 
   // Cargar estado inicial de la base de datos
   useEffect(() => {
     loadDatabaseState();
   }, []);
 
+  // La sesión fija una conexión en el servidor para que BEGIN, COMMIT y
+  // ROLLBACK afecten a las consultas siguientes
+  const startSession = async () => {
+    try {
+      const response = await axios.post(`${API_URL}/sessions`);
+      if (response.data.success) {
+        setSession(response.data.session);
+        return response.data.session;
+      }
+      setError(response.data.error);
+    } catch (err) {
+      setError('Error creando la sesión: ' + err.message);
+    }
+    return null;
+  };
+
+  const closeSession = async () => {
+    if (!session) return;
+    try {
+      const response = await axios.delete(`${API_URL}/sessions/${session.sessionId}`);
+      setSuccessMessage(response.data.message || '');
+      setSession(null);
+      loadDatabaseState();
+    } catch (err) {
+      setError('Error cerrando la sesión: ' + err.message);
+    }
+  };
+
   const loadDatabaseState = async () => {
     try {
       const response = await axios.get(`${API_URL}/database/state`);
@@ -40,7 +71,7 @@ function App() {
     try {
       setError('');
       setLoading(true);
-      const response = await axios.post(`${API_URL}/analyze/lexical`, { query });
+      const response = await axios.post(`${API_URL}/analyze/lexical`, { query, script: scriptMode });
       setLexicalResult(response.data);
       setActiveTab('lexical');
     } catch (err) {
@@ -54,7 +85,7 @@ function App() {
     try {
       setError('');
       setLoading(true);
-      const response = await axios.post(`${API_URL}/analyze/syntactic`, { query });
+      const response = await axios.post(`${API_URL}/analyze/syntactic`, { query, script: scriptMode });
       setSyntacticResult(response.data);
       setActiveTab('syntactic');
     } catch (err) {
@@ -68,7 +99,7 @@ function App() {
     try {
       setError('');
       setLoading(true);
-      const response = await axios.post(`${API_URL}/analyze/semantic`, { query });
+      const response = await axios.post(`${API_URL}/analyze/semantic`, { query, script: scriptMode });
       setSemanticResult(response.data);
       setActiveTab('semantic');
     } catch (err) {
@@ -83,13 +114,32 @@ function App() {
       setError('');
       setSuccessMessage('');
       setLoading(true);
-      const response = await axios.post(`${API_URL}/execute`, { query });
+      const response = await axios.post(`${API_URL}/execute`, {
+        query,
+        script: scriptMode,
+        sessionId: session?.sessionId,
+      });
+      if (response.data.session) {
+        setSession(response.data.session);
+      } else if (response.data.code === 'SESSION_NOT_FOUND') {
+        // La sesión expiró por inactividad: su transacción se revirtió
+        setSession(null);
+      }
       
-      if (response.data.success) {
-        setSuccessMessage('Query ejecutada exitosamente');
-        setQueryResult(response.data.result);
+      if (scriptMode && response.data.results) {
+        // En modo script se muestran también las sentencias ejecutadas
+        // antes de la que falló
+        setQueryResult({ statements: response.data.results });
         setDatabaseState(response.data.dbState);
         setActiveTab('results');
+      }
+      if (response.data.success) {
+        setSuccessMessage(scriptMode ? 'Script ejecutado exitosamente' : 'Query ejecutada exitosamente');
+        if (!scriptMode) {
+          setQueryResult(response.data.result);
+          setDatabaseState(response.data.dbState);
+          setActiveTab('results');
+        }
       } else {
         setError(response.data.error);
       }
@@ -107,6 +157,8 @@ function App() {
     { label: "UPDATE", query: "UPDATE productos SET precio = 999.99 WHERE nombre = 'Laptop Dell';" },
     { label: "DELETE", query: "DELETE FROM usuarios WHERE email = 'ana@email.com';" },
     { label: "CREATE TABLE", query: "CREATE TABLE categorias (id SERIAL PRIMARY KEY, nombre VARCHAR(50) NOT NULL);" },
+    { label: "BEGIN", query: "BEGIN;" },
+    { label: "ROLLBACK", query: "ROLLBACK;" },
   ];
 
   return (
@@ -127,6 +179,31 @@ function App() {
               disabled={loading}
             />
             
+            <label className="script-toggle">
+              <input
+                type="checkbox"
+                checked={scriptMode}
+                onChange={(e) => setScriptMode(e.target.checked)}
+                disabled={loading}
+              />
+              Modo script (varias sentencias separadas por ';')
+            </label>
+
+            <div className="session-bar">
+              {session ? (
+                <>
+                  <span className={session.inTransaction ? 'session-status in-transaction' : 'session-status'}>
+                    {session.inTransaction ? 'Sesión con transacción abierta' : 'Sesión activa'}
+                  </span>
+                  <button onClick={closeSession} disabled={loading}>Cerrar sesión</button>
+                </>
+              ) : (
+                <button onClick={startSession} disabled={loading}>
+                  Iniciar sesión (para usar BEGIN / COMMIT / ROLLBACK)
+                </button>
+              )}
+            </div>
+
             <div className="button-group">
               <button onClick={analyzeLexical} disabled={loading || !query}>
                 Analizar Léxico
@@ -189,10 +266,23 @@ function App() {
           </div>
 
           <div className="tab-content">
-            {activeTab === 'lexical' && <LexicalAnalyzer result={lexicalResult} />}
-            {activeTab === 'syntactic' && <SyntacticAnalyzer result={syntacticResult} />}
-            {activeTab === 'semantic' && <SemanticAnalyzer result={semanticResult} />}
-            {activeTab === 'results' && <QueryResults result={queryResult} dbState={databaseState} />}
+            {activeTab === 'lexical' && (lexicalResult?.statements
+              ? <ScriptResults statements={lexicalResult.statements} render={(s) => <LexicalAnalyzer result={s} />} />
+              : <LexicalAnalyzer result={lexicalResult} />)}
+            {activeTab === 'syntactic' && (syntacticResult?.statements
+              ? <ScriptResults statements={syntacticResult.statements} render={(s) => <SyntacticAnalyzer result={s} />} />
+              : <SyntacticAnalyzer result={syntacticResult} />)}
+            {activeTab === 'semantic' && (semanticResult?.statements
+              ? <ScriptResults statements={semanticResult.statements} render={(s) => <SemanticAnalyzer result={s} />} />
+              : <SemanticAnalyzer result={semanticResult} />)}
+            {activeTab === 'results' && (queryResult?.statements
+              ? <ScriptResults
+                  statements={queryResult.statements}
+                  render={(s) => s.success
+                    ? <QueryResults result={s.result} dbState={databaseState} />
+                    : <div className="error">{s.error}</div>}
+                />
+              : <QueryResults result={queryResult} dbState={databaseState} />)}
           </div>
         </div>
 
@@ -32,6 +32,7 @@ const LexicalAnalyzer = ({ result }) => {
           <tr>
             <th>Token</th>
             <th>Tipo</th>
+            <th>Posición</th>
           </tr>
         </thead>
         <tbody>
@@ -39,6 +40,7 @@ const LexicalAnalyzer = ({ result }) => {
             <tr key={index}>
               <td>{token.token}</td>
               <td>{token.tipo}</td>
+              <td>{token.start ? `${token.start.line}:${token.start.column}` : ''}</td>
             </tr>
           ))}
         </tbody>
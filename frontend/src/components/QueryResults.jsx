@@ -4,7 +4,7 @@ const QueryResults = ({ result, dbState }) => {
   if (!result) return null;
 
   const renderResultTable = () => {
-    if (!result.data || result.data.length === 0) {
+    if (!result.data || result.data.length === 0 || result.type === 'ALTER') {
       return null;
     }
 
@@ -49,11 +49,39 @@ const QueryResults = ({ result, dbState }) => {
         return '#f85149';
       case 'SELECT':
         return '#a371f7';
+      case 'ALTER':
+        return '#d29922';
       default:
         return '#8b949e';
     }
   };
 
+  const renderSchemaTable = (title, columns) => (
+    <div className="schema-info">
+      <h4>{title}</h4>
+      <table className="schema-table">
+        <thead>
+          <tr>
+            <th>Columna</th>
+            <th>Tipo</th>
+            <th>Nullable</th>
+            <th>Default</th>
+          </tr>
+        </thead>
+        <tbody>
+          {columns && columns.map((col, index) => (
+            <tr key={index}>
+              <td>{col.column_name}</td>
+              <td>{col.data_type}</td>
+              <td>{col.is_nullable}</td>
+              <td>{col.column_default || '-'}</td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+    </div>
+  );
+
   return (
     <div className="query-results">
       <div className="result-header" style={{ borderLeftColor: getResultColor() }}>
@@ -70,30 +98,14 @@ const QueryResults = ({ result, dbState }) => {
 
       {renderResultTable()}
 
-      {result.type === 'CREATE' && result.tableName && (
-        <div className="schema-info">
-          <h4>Estructura de la tabla '{result.tableName}':</h4>
-          <table className="schema-table">
-            <thead>
-              <tr>
-                <th>Columna</th>
-                <th>Tipo</th>
-                <th>Nullable</th>
-                <th>Default</th>
-              </tr>
-            </thead>
-            <tbody>
-              {result.data && result.data.map((col, index) => (
-                <tr key={index}>
-                  <td>{col.column_name}</td>
-                  <td>{col.data_type}</td>
-                  <td>{col.is_nullable}</td>
-                  <td>{col.column_default || '-'}</td>
-                </tr>
-              ))}
-            </tbody>
-          </table>
-        </div>
+      {result.type === 'CREATE' && result.tableName &&
+        renderSchemaTable(`Estructura de la tabla '${result.tableName}':`, result.data)}
+
+      {result.type === 'ALTER' && result.tableName && (
+        <>
+          {result.before && renderSchemaTable('Estructura anterior:', result.before)}
+          {renderSchemaTable(`Estructura de la tabla '${result.tableName}' después del cambio:`, result.data)}
+        </>
       )}
     </div>
   );
@@ -0,0 +1,20 @@
+import React from 'react';
+
+// Muestra el resultado de cada sentencia de un script usando el componente
+// de la pestaña correspondiente.
+const ScriptResults = ({ statements, render }) => (
+  <div>
+    {statements.map((statement) => (
+      <div key={statement.index} className="script-statement">
+        <h3>
+          Sentencia {statement.index + 1}
+          {statement.start && ` (línea ${statement.start.line})`}
+        </h3>
+        <pre className="script-statement-text">{statement.text}</pre>
+        {render(statement)}
+      </div>
+    ))}
+  </div>
+);
+
+export default ScriptResults;
@@ -42,7 +42,10 @@ const SemanticAnalyzer = ({ result }) => {
           <h3>Columnas Referenciadas</h3>
           {semanticInfo.columns.map((column, index) => (
             <div key={index} className="info-card">
-              <span>{column.column}</span>
+              <span>
+                {column.table ? `${column.table}.${column.column}` : column.column}
+                {column.type && ` (${column.type}${column.nullable ? '' : ', NOT NULL'})`}
+              </span>
               <span className={column.exists ? 'exists-true' : 'exists-false'}>
                 {column.exists ? 'Válida' : 'No encontrada'}
               </span>